@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"gorm.io/gorm"
+)
+
+// runAdmin 管理员账号命令
+func runAdmin(args []string) {
+	if len(args) == 0 {
+		fatalf("Usage: QA admin create -username <name> [-password <password>] [-super]\n" +
+			"       QA admin reset -username <name> [-reset-2fa]")
+	}
+	switch args[0] {
+	case "create":
+		runAdminCreate(args[1:])
+	case "reset":
+		runAdminReset(args[1:])
+	default:
+		fatalf("未知的 admin 子命令 %s", args[0])
+	}
+}
+
+// runAdminCreate 创建管理员, 未指定密码时生成一次性密码, 首次登录后需修改
+func runAdminCreate(args []string) {
+	fs := flag.NewFlagSet("admin create", flag.ExitOnError)
+	username := fs.String("username", "", "用户名")
+	password := fs.String("password", "", "密码, 为空时生成一次性密码")
+	super := fs.Bool("super", false, "创建超级管理员")
+	_ = fs.Parse(args)
+	if *username == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	bootstrap(true)
+	err := service.IsAdminExist(*username)
+	if err == nil {
+		fatalf("用户 %s 已存在", *username)
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		fatalf("查询用户失败: %v", err)
+	}
+	user := model.User{Username: *username, Password: *password, AdminType: 1}
+	if *super {
+		user.AdminType = 2
+	}
+	if user.Password == "" {
+		user.Password, err = utils.RandomPassword(16)
+		if err != nil {
+			fatalf("生成密码失败: %v", err)
+		}
+		user.MustChangePassword = true
+	}
+	if err := service.CreateAdmin(user); err != nil {
+		fatalf("创建用户失败: %v", err)
+	}
+	created, err := service.GetAdminByUsername(*username)
+	if err != nil {
+		fatalf("查询用户失败: %v", err)
+	}
+	service.RecordSystemAudit(service.AuditEntry{
+		Action:     service.AuditUserCreate,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(created.ID),
+		After:      map[string]any{"username": created.Username, "admin_type": created.AdminType},
+	})
+	fmt.Printf("created %s (id=%d, admin_type=%d)\n", created.Username, created.ID, created.AdminType)
+	if *password == "" {
+		fmt.Printf("password: %s\n", user.Password)
+	}
+}
+
+// runAdminReset 重置管理员密码为一次性密码, 并使其全部会话失效、解除登录锁定
+func runAdminReset(args []string) {
+	fs := flag.NewFlagSet("admin reset", flag.ExitOnError)
+	username := fs.String("username", "", "用户名")
+	reset2FA := fs.Bool("reset-2fa", false, "同时关闭两步验证, 用于丢失认证器的情况")
+	_ = fs.Parse(args)
+	if *username == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	bootstrap(true)
+	user, err := service.GetAdminByUsername(*username)
+	if err != nil {
+		fatalf("查询用户失败: %v", err)
+	}
+	password, err := service.ResetAdminPassword(user.ID)
+	if err != nil {
+		fatalf("重置密码失败: %v", err)
+	}
+	if err := service.RevokeAdminSessions(user.ID, ""); err != nil {
+		fatalf("注销会话失败: %v", err)
+	}
+	if err := service.UnlockLogin(service.LoginScopeAdmin, user.Username); err != nil {
+		fatalf("解除登录锁定失败: %v", err)
+	}
+	service.RecordSystemAudit(service.AuditEntry{
+		Action:     service.AuditUserPasswordReset,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     map[string]any{"username": user.Username},
+	})
+	if *reset2FA {
+		if err := service.DisableTOTP(user.ID); err != nil {
+			fatalf("关闭两步验证失败: %v", err)
+		}
+		service.RecordSystemAudit(service.AuditEntry{
+			Action:     service.AuditUserTOTPReset,
+			TargetType: service.AuditTargetUser,
+			TargetID:   strconv.Itoa(user.ID),
+			Before:     map[string]any{"username": user.Username},
+		})
+	}
+	fmt.Printf("password: %s\n", password)
+}
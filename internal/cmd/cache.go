@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"fmt"
+
+	"QA-System/internal/service"
+)
+
+// runCache 缓存命令
+func runCache(args []string) {
+	if len(args) == 0 || args[0] != "rebuild" {
+		fatalf("Usage: QA cache rebuild")
+	}
+	bootstrap(true)
+	num, err := service.RebuildCache()
+	if err != nil {
+		fatalf("重建缓存失败: %v", err)
+	}
+	fmt.Printf("cache rebuilt, %d published surveys warmed\n", num)
+}
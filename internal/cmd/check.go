@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"QA-System/internal/service"
+	"go.uber.org/zap"
+)
+
+// runCheck 检查并按需修复孤立数据, 结果以 JSON 输出
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	repair := fs.Bool("repair", false, "删除孤立数据并重试未执行成功的发件箱事件")
+	_ = fs.Parse(args)
+	bootstrap(true)
+	report, err := service.CheckConsistency(*repair)
+	if err != nil {
+		zap.L().Fatal("Failed to check consistency:" + err.Error())
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		zap.L().Fatal(err.Error())
+	}
+	fmt.Println(string(data))
+	if !*repair && (len(report.OrphanQuestions) > 0 || len(report.OrphanOptions) > 0 ||
+		len(report.OrphanManages) > 0 || len(report.OrphanAnswerSheets) > 0 ||
+		len(report.OrphanRecordSheets) > 0 || report.PendingOutbox > 0) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"QA-System/internal/pkg/database/mongodb"
+	"QA-System/internal/pkg/database/mysql"
+	"QA-System/internal/pkg/log"
+	"QA-System/internal/pkg/mail"
+	"QA-System/internal/pkg/redis"
+	"QA-System/internal/pkg/scanner"
+	"QA-System/internal/pkg/storage"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(args []string)
+}
+
+var commands = []command{
+	{"serve", "serve [-worker] [-scheduler]    启动 HTTP 服务, 默认同时处理异步任务", runServe},
+	{"worker", "worker [-scheduler=true]        只处理异步任务与周期任务", runWorker},
+	{"migrate", "migrate up|down|status          执行、回滚或查看数据库迁移", runMigrate},
+	{"admin", "admin create|reset              创建管理员或重置密码", runAdmin},
+	{"survey", "survey export <id> -format      导出问卷答卷", runSurvey},
+	{"cache", "cache rebuild                   重建问题与选项缓存", runCache},
+	{"files", "files gc [-dry-run] | thumbs    清理未关联的上传文件, 补全缩略图", runFiles},
+	{"check", "check [-repair]                 检查并修复孤立数据", runCheck},
+	{"mail", "mail test -to <address>         发送测试邮件, 检查 SMTP 配置", runMail},
+}
+
+// Run 根据参数执行子命令, 未指定子命令时启动 HTTP 服务
+func Run(args []string) {
+	if len(args) == 0 {
+		runServe(nil)
+		return
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			c.run(args[1:])
+			return
+		}
+	}
+	usage()
+	if args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: QA <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintln(os.Stderr, "  "+c.usage)
+	}
+}
+
+// bootstrap 初始化日志、数据库、Redis、dao、文件存储、安全扫描与邮件服务, migrate 为 true 时按配置自动执行迁移
+func bootstrap(migrate bool) (*gorm.DB, *mongo.Database) {
+	log.ZapInit()
+	redis.Init()
+	db := mysql.Init()
+	mdb := mongodb.Init()
+	if migrate {
+		mysql.AutoMigrate(db)
+		mongodb.AutoMigrate(mdb)
+	}
+	service.Init(db, mdb)
+	if err := utils.Init(); err != nil {
+		zap.L().Fatal(err.Error())
+	}
+	if err := service.CheckImageConfig(); err != nil {
+		zap.L().Fatal("Invalid image config:" + err.Error())
+	}
+	if err := storage.Init(); err != nil {
+		zap.L().Fatal("Failed to init storage:" + err.Error())
+	}
+	if err := scanner.Init(); err != nil {
+		zap.L().Fatal("Failed to init scanner:" + err.Error())
+	}
+	if err := mail.Init(); err != nil {
+		zap.L().Fatal("Failed to init mail:" + err.Error())
+	}
+	return db, mdb
+}
+
+// fatalf 输出错误并退出, 用于命令行参数错误等无需记录日志的情况
+func fatalf(format string, a ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
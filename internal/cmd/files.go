@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"QA-System/internal/service"
+)
+
+// runFiles 上传文件命令
+func runFiles(args []string) {
+	if len(args) == 0 {
+		fatalf("Usage: QA files gc [-dry-run] [-min-age 24h] [-scan] | QA files thumbs")
+	}
+	switch args[0] {
+	case "gc":
+		runFilesGC(args[1:])
+	case "thumbs":
+		bootstrap(true)
+		count, err := service.GenerateMissingThumbs()
+		if err != nil {
+			fatalf("生成缩略图失败: %v", err)
+		}
+		fmt.Printf("generated %d thumbnails\n", count)
+	default:
+		fatalf("Usage: QA files gc [-dry-run] [-min-age 24h] [-scan] | QA files thumbs")
+	}
+}
+
+// runFilesGC 清理超时未关联的上传文件, -scan 时额外清理升级前上传且未被引用的文件
+func runFilesGC(args []string) {
+	fs := flag.NewFlagSet("files gc", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "只列出待清理的文件, 不删除")
+	minAge := fs.Duration("min-age", 0, "只处理上传时间早于该时长的文件, 默认为 upload.pending-ttl")
+	scan := fs.Bool("scan", false, "扫描存储中未登记且未被引用的文件, 用于清理升级前上传的文件")
+	_ = fs.Parse(args)
+	if *minAge <= 0 {
+		*minAge = service.GetUploadPendingTTL()
+	}
+
+	bootstrap(true)
+	action := "removed"
+	if *dryRun {
+		action = "found"
+	}
+	uploads, err := service.CollectPendingUploads(*minAge, *dryRun)
+	var size int64
+	for _, upload := range uploads {
+		size += upload.Size
+		fmt.Printf("%s\t%d\t%s\t%s\n", upload.Key, upload.Size, upload.Uploader,
+			upload.CreatedAt.Format(time.RFC3339))
+	}
+	if err != nil {
+		fatalf("清理文件失败: %v", err)
+	}
+	fmt.Printf("%s %d pending uploads, %d bytes\n", action, len(uploads), size)
+	if !*scan {
+		return
+	}
+
+	orphans, err := service.CollectOrphanUploads(*minAge, *dryRun)
+	size = 0
+	for _, orphan := range orphans {
+		size += orphan.Size
+		fmt.Printf("%s\t%d\t%s\n", orphan.Path, orphan.Size, orphan.ModTime.Format(time.RFC3339))
+	}
+	if err != nil {
+		fatalf("清理文件失败: %v", err)
+	}
+	fmt.Printf("%s %d orphan files, %d bytes\n", action, len(orphans), size)
+}
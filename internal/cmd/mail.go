@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+
+	"QA-System/internal/service"
+)
+
+// runMail 邮件命令
+func runMail(args []string) {
+	if len(args) == 0 || args[0] != "test" {
+		fatalf("Usage: QA mail test -to <address>")
+	}
+	fs := flag.NewFlagSet("mail test", flag.ExitOnError)
+	to := fs.String("to", "", "收件地址")
+	_ = fs.Parse(args[1:])
+	if !service.CheckEmail(*to) {
+		fatalf("Usage: QA mail test -to <address>")
+	}
+	bootstrap(false)
+	if err := service.SendTestMail(*to); err != nil {
+		fatalf("发送测试邮件失败: %v", err)
+	}
+	fmt.Printf("test mail sent to %s\n", *to)
+}
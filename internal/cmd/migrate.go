@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"QA-System/internal/pkg/database/mongodb"
+	"QA-System/internal/pkg/database/mysql"
+	"go.uber.org/zap"
+)
+
+// runMigrate 执行数据库迁移命令
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := fs.Int("steps", 0, "执行的迁移数, up 默认全部, down 默认 1")
+	target := fs.String("db", "all", "迁移的数据库: mysql, mongo 或 all")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: QA migrate up|down|status [-steps n] [-db mysql|mongo|all]")
+		fs.PrintDefaults()
+	}
+	if len(args) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	action := args[0]
+	_ = fs.Parse(args[1:])
+	withMySQL := *target == "all" || *target == "mysql"
+	withMongo := *target == "all" || *target == "mongo"
+	if !withMySQL && !withMongo {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	// 迁移命令不自动执行迁移, 以便单独回滚
+	db, mdb := bootstrap(false)
+	ctx := context.Background()
+	switch action {
+	case "up":
+		if withMySQL {
+			done, err := mysql.MigrateUp(db, *steps)
+			for _, m := range done {
+				fmt.Printf("mysql: applied %04d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				zap.L().Fatal("Failed to migrate MySQL:" + err.Error())
+			}
+		}
+		if withMongo {
+			done, err := mongodb.MigrateUp(ctx, mdb, *steps)
+			for _, m := range done {
+				fmt.Printf("mongo: applied %04d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				zap.L().Fatal("Failed to migrate MongoDB:" + err.Error())
+			}
+		}
+	case "down":
+		// 两个数据库互不依赖, 同时指定时分别回滚
+		if withMySQL {
+			done, err := mysql.MigrateDown(db, *steps)
+			for _, m := range done {
+				fmt.Printf("mysql: reverted %04d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				zap.L().Fatal("Failed to revert MySQL migration:" + err.Error())
+			}
+		}
+		if withMongo {
+			done, err := mongodb.MigrateDown(ctx, mdb, *steps)
+			for _, m := range done {
+				fmt.Printf("mongo: reverted %04d_%s\n", m.Version, m.Name)
+			}
+			if err != nil {
+				zap.L().Fatal("Failed to revert MongoDB migration:" + err.Error())
+			}
+		}
+	case "status":
+		if withMySQL {
+			status, err := mysql.GetMigrationStatus(db)
+			if err != nil {
+				zap.L().Fatal("Failed to get MySQL migration status:" + err.Error())
+			}
+			for _, s := range status {
+				printMigrationStatus("mysql", s.Version, s.Name, s.AppliedAt != nil)
+			}
+		}
+		if withMongo {
+			status, err := mongodb.GetMigrationStatus(ctx, mdb)
+			if err != nil {
+				zap.L().Fatal("Failed to get MongoDB migration status:" + err.Error())
+			}
+			for _, s := range status {
+				printMigrationStatus("mongo", s.Version, s.Name, s.AppliedAt != nil)
+			}
+		}
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+}
+
+func printMigrationStatus(db string, version int, name string, applied bool) {
+	state := "pending"
+	if applied {
+		state = "applied"
+	}
+	fmt.Printf("%s: %04d_%s\t%s\n", db, version, name, state)
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"flag"
+
+	global "QA-System/internal/global/config"
+	q "QA-System/internal/handler/queue"
+	"QA-System/internal/middleware"
+	"QA-System/internal/pkg/queue"
+	"QA-System/internal/pkg/session"
+	"QA-System/internal/router"
+	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+// runServe 启动 HTTP 服务
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	worker := fs.Bool("worker", true, "同时处理异步任务, 单独部署 worker 时关闭")
+	scheduler := fs.Bool("scheduler", false, "同时启动周期任务调度器, 需开启 -worker, 多实例部署时只应在一个实例开启")
+	_ = fs.Parse(args)
+
+	// 如果配置文件中开启了调试模式
+	if !global.Config.GetBool("server.debug") {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	bootstrap(true)
+	// 初始化异步任务队列
+	queue.Init()
+	if *worker {
+		startWorker(*scheduler)
+	}
+	if !*worker || !*scheduler {
+		zap.L().Info("Scheduler disabled, periodic tasks require a worker or serve with -scheduler")
+	}
+
+	// 初始化gin
+	r := gin.Default()
+	r.Use(middleware.ErrHandler())
+	r.NoMethod(middleware.HandleNotFound)
+	r.NoRoute(middleware.HandleNotFound)
+	session.Init(r)
+	router.Init(r)
+	err := r.Run(":" + global.Config.GetString("server.port"))
+	if err != nil {
+		zap.L().Fatal("Failed to start the server:" + err.Error())
+	}
+}
+
+// startWorker 启动异步任务处理服务, scheduler 为 true 时同时启动周期任务调度器
+// 周期任务调度器在多实例部署时只应启动一个, 否则任务会重复投递
+func startWorker(scheduler bool) (*asynq.Server, *asynq.Scheduler) {
+	srv := queue.NewServer()
+	if err := srv.Start(q.NewServeMux()); err != nil {
+		zap.L().Fatal("Failed to start the queue server:" + err.Error())
+	}
+	if !scheduler {
+		return srv, nil
+	}
+	s := queue.NewScheduler()
+	if err := q.RegisterPeriodicTasks(s); err != nil {
+		zap.L().Fatal("Failed to register periodic tasks:" + err.Error())
+	}
+	if err := s.Start(); err != nil {
+		zap.L().Fatal("Failed to start the scheduler:" + err.Error())
+	}
+	return srv, s
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"QA-System/internal/service"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// runSurvey 问卷命令
+func runSurvey(args []string) {
+	if len(args) == 0 || args[0] != "export" {
+		fatalf("Usage: QA survey export <id> [-format xlsx|csv|zip] [-o file]")
+	}
+	runSurveyExport(args[1:])
+}
+
+// runSurveyExport 导出问卷答卷, zip 格式为答卷中上传的图片与文件
+func runSurveyExport(args []string) {
+	fs := flag.NewFlagSet("survey export", flag.ExitOnError)
+	format := fs.String("format", "xlsx", "导出格式: xlsx, csv 或 zip")
+	output := fs.String("o", "", "输出文件, 默认为 survey_<id>.<format>")
+	// 问卷id既可以写在参数之前也可以写在参数之后
+	var idArg string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		idArg, args = args[0], args[1:]
+	}
+	_ = fs.Parse(args)
+	if idArg == "" {
+		idArg = fs.Arg(0)
+	}
+	id, err := strconv.Atoi(idArg)
+	if err != nil {
+		fatalf("问卷id无效: %q", idArg)
+	}
+	if *format != "xlsx" && *format != "csv" && *format != "zip" {
+		fatalf("不支持的导出格式 %s", *format)
+	}
+	if *output == "" {
+		*output = fmt.Sprintf("survey_%d.%s", id, *format)
+	}
+
+	bootstrap(true)
+	if _, err := service.GetSurveyByID(id); errors.Is(err, gorm.ErrRecordNotFound) {
+		fatalf("问卷 %d 不存在", id)
+	} else if err != nil {
+		fatalf("查询问卷失败: %v", err)
+	}
+	if *format == "xlsx" {
+		answers, err := service.GetAllSurveyAnswers(id)
+		if err != nil {
+			fatalf("查询答卷失败: %v", err)
+		}
+		if err := service.HandleDownloadFile(answers, *output, nil); err != nil {
+			fatalf("导出失败: %v", err)
+		}
+	} else {
+		file, err := os.Create(filepath.Clean(*output))
+		if err != nil {
+			fatalf("创建文件失败: %v", err)
+		}
+		if *format == "csv" {
+			err = service.WriteAnswersCSV(file, id)
+		} else {
+			err = service.WriteSurveyFilesZip(file, id)
+		}
+		if closeErr := file.Close(); closeErr != nil {
+			zap.L().Error("Failed to close file", zap.Error(closeErr))
+		}
+		if err != nil {
+			fatalf("导出失败: %v", err)
+		}
+	}
+	fmt.Printf("exported survey %d to %s\n", id, *output)
+}
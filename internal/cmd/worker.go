@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"QA-System/internal/pkg/queue"
+	"go.uber.org/zap"
+)
+
+// runWorker 只处理异步任务, 收到退出信号后等待进行中的任务完成
+func runWorker(args []string) {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	scheduler := fs.Bool("scheduler", true, "同时启动周期任务调度器, 多实例部署时只应在一个实例开启")
+	_ = fs.Parse(args)
+
+	bootstrap(true)
+	// 任务处理过程中可能投递新的任务
+	queue.Init()
+	srv, s := startWorker(*scheduler)
+	zap.L().Info("Worker started")
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	if s != nil {
+		s.Shutdown()
+	}
+	srv.Shutdown()
+	zap.L().Info("Worker stopped")
+}
@@ -3,6 +3,8 @@ package dao
 import (
 	"context"
 	"errors"
+	"strconv"
+	"time"
 
 	database "QA-System/internal/pkg/database/mongodb"
 	"go.mongodb.org/mongo-driver/bson"
@@ -22,11 +24,14 @@ type Answer struct {
 
 // AnswerSheet mongodb答卷表模型
 type AnswerSheet struct {
-	SurveyID int                `json:"survey_id" bson:"surveyid"` // 问卷ID
-	AnswerID primitive.ObjectID `json:"answer_id" bson:"_id"`      // 答卷ID
-	Time     string             `json:"time" bson:"time"`          // 答卷时间
-	Unique   bool               `json:"unique" bson:"unique"`      // 是否唯一
-	Answers  []Answer           `json:"answers" bson:"answers"`    // 答案列表
+	SurveyID  int                `json:"survey_id" bson:"surveyid"`              // 问卷ID
+	AnswerID  primitive.ObjectID `json:"answer_id" bson:"_id"`                   // 答卷ID
+	Time      string             `json:"time" bson:"time"`                       // 答卷时间
+	Unique    bool               `json:"unique" bson:"unique"`                   // 是否唯一
+	StudentID string             `json:"student_id" bson:"student_id,omitempty"` // 统一验证的学号
+	Imported  bool               `json:"imported" bson:"imported,omitempty"`     // 是否为导入的历史答卷
+	DeletedAt *time.Time         `json:"deleted_at" bson:"deleted_at,omitempty"` // 删除时间, 删除后进入回收站
+	Answers   []Answer           `json:"answers" bson:"answers"`                 // 答案列表
 }
 
 // QuestionAnswers 问题答案模型
@@ -34,8 +39,13 @@ type QuestionAnswers struct {
 	Title        string   `json:"title"`
 	QuestionType int      `json:"question_type"`
 	Answers      []string `json:"answers"`
+	Thumbs       []string `json:"thumbs,omitempty"`      // 图片题答案对应的缩略图, 多张图片同样以 ┋ 分隔
+	ScanStatus   []string `json:"scan_status,omitempty"` // 文件题答案的安全扫描状态, 多个文件同样以 ┋ 分隔
 }
 
+// notDeleted 未被移入回收站的答卷
+var notDeleted = bson.M{"$exists": false}
+
 // AnswersResonse 答案响应模型
 type AnswersResonse struct {
 	QuestionAnswers []QuestionAnswers    `json:"question_answers"`
@@ -70,8 +80,9 @@ func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids
 	}
 
 	filter := bson.M{
-		"unique": true,
-		"$or":    matchConditions,
+		"unique":     true,
+		"deleted_at": notDeleted,
+		"$or":        matchConditions,
 	}
 
 	var result AnswerSheet
@@ -99,10 +110,13 @@ func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids
 
 	// 新增一条记录
 	newAnswerSheet := AnswerSheet{
-		SurveyID: answerSheet.SurveyID,
-		Time:     answerSheet.Time,
-		Unique:   true,
-		Answers:  answerSheet.Answers,
+		SurveyID:  answerSheet.SurveyID,
+		AnswerID:  answerSheet.AnswerID,
+		Time:      answerSheet.Time,
+		Unique:    true,
+		StudentID: answerSheet.StudentID,
+		Imported:  answerSheet.Imported,
+		Answers:   answerSheet.Answers,
 	}
 
 	_, err = d.mongo.Collection(database.QA).InsertOne(ctx, newAnswerSheet)
@@ -113,6 +127,74 @@ func (d *Dao) SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids
 	return nil
 }
 
+// InsertAnswerSheets 批量写入导入的答卷, 返回实际写入的数量
+// 答卷ID由调用方根据导入内容生成, 重试时已写入的答卷因主键重复而跳过, 不会重复导入
+func (d *Dao) InsertAnswerSheets(ctx context.Context, answerSheets []AnswerSheet, qids []int) (int, error) {
+	if len(answerSheets) == 0 {
+		return 0, nil
+	}
+	// 唯一性与逐条保存一致: 同一答案只保留最后一份答卷为唯一, 批次内靠后的答卷视为较新
+	seen := make(map[string]bool)
+	matchConditions := make([]bson.M, 0)
+	ids := make([]primitive.ObjectID, 0, len(answerSheets))
+	docs := make([]any, len(answerSheets))
+	for i := len(answerSheets) - 1; i >= 0; i-- {
+		sheet := answerSheets[i]
+		sheet.Unique = true
+		for _, answer := range sheet.Answers {
+			if !contains(qids, answer.QuestionID) {
+				continue
+			}
+			key := strconv.Itoa(answer.QuestionID) + "\x00" + answer.Content
+			if seen[key] {
+				sheet.Unique = false
+				continue
+			}
+			seen[key] = true
+			matchConditions = append(matchConditions, bson.M{
+				"answers": bson.M{
+					"$elemMatch": bson.M{
+						"questionid": answer.QuestionID,
+						"content":    answer.Content,
+					},
+				},
+			})
+		}
+		ids = append(ids, sheet.AnswerID)
+		docs[i] = sheet
+	}
+
+	if len(matchConditions) > 0 {
+		filter := bson.M{
+			"unique":     true,
+			"deleted_at": notDeleted,
+			"_id":        bson.M{"$nin": ids},
+			"$or":        matchConditions,
+		}
+		update := bson.M{
+			"$set": bson.M{"unique": false},
+		}
+		if _, err := d.mongo.Collection(database.QA).UpdateMany(ctx, filter, update); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err := d.mongo.Collection(database.QA).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if !mongo.IsDuplicateKeyError(writeErr.WriteError) {
+				return 0, err
+			}
+		}
+		return len(docs) - len(bulkErr.WriteErrors), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(docs), nil
+}
+
 func contains(arr []int, item int) bool {
 	for _, a := range arr {
 		if a == item {
@@ -127,7 +209,7 @@ func (d *Dao) GetAnswerSheetBySurveyID(
 	ctx context.Context, surveyID int, pageNum int, pageSize int, text string, unique bool) (
 	[]AnswerSheet, *int64, error) {
 	answerSheets := make([]AnswerSheet, 0)
-	filter := bson.M{"surveyid": surveyID}
+	filter := bson.M{"surveyid": surveyID, "deleted_at": notDeleted}
 
 	// 如果 text 不为空，添加 text 的查询条件
 	if text != "" {
@@ -206,9 +288,61 @@ func (d *Dao) DeleteAnswerSheetByAnswerID(ctx context.Context, answerID primitiv
 }
 
 // GetAnswerSheetByAnswerID 根据答卷ID获取答卷
-func (d *Dao) GetAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) error {
+func (d *Dao) GetAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) (AnswerSheet, error) {
 	var answerSheet AnswerSheet
-	filter := bson.M{"_id": answerID}
+	filter := bson.M{"_id": answerID, "deleted_at": notDeleted}
 	err := d.mongo.Collection(database.QA).FindOne(ctx, filter).Decode(&answerSheet)
+	return answerSheet, err
+}
+
+// SoftDeleteAnswerSheet 将答卷移入回收站
+func (d *Dao) SoftDeleteAnswerSheet(ctx context.Context, answerID primitive.ObjectID) error {
+	filter := bson.M{"_id": answerID, "deleted_at": notDeleted}
+	_, err := d.mongo.Collection(database.QA).UpdateOne(ctx, filter, bson.M{"$set": bson.M{"deleted_at": time.Now()}})
 	return err
 }
+
+// RestoreAnswerSheet 从回收站恢复答卷
+func (d *Dao) RestoreAnswerSheet(ctx context.Context, answerID primitive.ObjectID) error {
+	filter := bson.M{"_id": answerID, "deleted_at": bson.M{"$exists": true}}
+	_, err := d.mongo.Collection(database.QA).UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"deleted_at": ""}})
+	return err
+}
+
+// GetDeletedAnswerSheetByAnswerID 根据答卷ID获取回收站中的答卷
+func (d *Dao) GetDeletedAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) (
+	AnswerSheet, error) {
+	var answerSheet AnswerSheet
+	filter := bson.M{"_id": answerID, "deleted_at": bson.M{"$exists": true}}
+	err := d.mongo.Collection(database.QA).FindOne(ctx, filter).Decode(&answerSheet)
+	return answerSheet, err
+}
+
+// GetDeletedAnswerSheets 获取回收站中的答卷, surveyID 为 0 时不按问卷筛选, before 为零值时不按删除时间筛选
+func (d *Dao) GetDeletedAnswerSheets(ctx context.Context, surveyID int, before time.Time) ([]AnswerSheet, error) {
+	deletedAt := bson.M{"$exists": true}
+	if !before.IsZero() {
+		deletedAt["$lt"] = before
+	}
+	filter := bson.M{"deleted_at": deletedAt}
+	if surveyID != 0 {
+		filter["surveyid"] = surveyID
+	}
+	return d.findAnswerSheets(ctx, filter, options.Find().SetSort(bson.M{"deleted_at": -1}))
+}
+
+// GetAllAnswerSheetsBySurveyID 获取问卷的全部答卷, 包括回收站中的答卷
+func (d *Dao) GetAllAnswerSheetsBySurveyID(ctx context.Context, surveyID int) ([]AnswerSheet, error) {
+	return d.findAnswerSheets(ctx, bson.M{"surveyid": surveyID}, options.Find())
+}
+
+func (d *Dao) findAnswerSheets(ctx context.Context, filter bson.M, opts *options.FindOptions) (
+	[]AnswerSheet, error) {
+	cur, err := d.mongo.Collection(database.QA).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	answerSheets := make([]AnswerSheet, 0)
+	err = cur.All(ctx, &answerSheets)
+	return answerSheets, err
+}
@@ -0,0 +1,44 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"QA-System/internal/model"
+)
+
+// CreateAPIToken 创建 API 令牌
+func (d *Dao) CreateAPIToken(ctx context.Context, token *model.APIToken) error {
+	return d.orm.WithContext(ctx).Create(token).Error
+}
+
+// GetAPITokenByHash 根据令牌摘要获取 API 令牌
+func (d *Dao) GetAPITokenByHash(ctx context.Context, tokenHash string) (*model.APIToken, error) {
+	var token model.APIToken
+	err := d.orm.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	return &token, err
+}
+
+// GetAPITokensByUserID 获取用户的全部 API 令牌
+func (d *Dao) GetAPITokensByUserID(ctx context.Context, uid int) ([]model.APIToken, error) {
+	var tokens []model.APIToken
+	err := d.orm.WithContext(ctx).Where("user_id = ?", uid).Order("id DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+// RevokeAPIToken 吊销用户的 API 令牌, 返回是否找到该令牌
+func (d *Dao) RevokeAPIToken(ctx context.Context, uid int, id int) (bool, error) {
+	result := d.orm.WithContext(ctx).Model(&model.APIToken{}).Where("id = ? AND user_id = ?", id, uid).
+		Update("revoked", true)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateAPITokenLastUsed 更新 API 令牌的最近使用时间
+func (d *Dao) UpdateAPITokenLastUsed(ctx context.Context, id int, t time.Time) error {
+	return d.orm.WithContext(ctx).Model(&model.APIToken{}).Where("id = ?", id).Update("last_used_at", t).Error
+}
+
+// DeleteAPITokensByUserID 删除用户的全部 API 令牌
+func (d *Dao) DeleteAPITokensByUserID(ctx context.Context, uid int) error {
+	return d.orm.WithContext(ctx).Where("user_id = ?", uid).Delete(&model.APIToken{}).Error
+}
@@ -0,0 +1,58 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"QA-System/internal/model"
+)
+
+// AuditFilter 审计日志查询条件, 零值表示不筛选
+type AuditFilter struct {
+	Username   string
+	Action     string
+	SurveyID   int
+	TargetType string
+	TargetID   string
+	StartTime  time.Time
+	EndTime    time.Time
+}
+
+// CreateAuditLog 写入审计日志
+func (d *Dao) CreateAuditLog(ctx context.Context, log *model.AuditLog) error {
+	return d.orm.WithContext(ctx).Create(log).Error
+}
+
+// GetAuditLogs 分页查询审计日志, 按时间倒序
+func (d *Dao) GetAuditLogs(ctx context.Context, filter AuditFilter, pageNum, pageSize int) (
+	[]model.AuditLog, *int64, error) {
+	var logs []model.AuditLog
+	var num int64
+	query := d.orm.WithContext(ctx).Model(&model.AuditLog{})
+	if filter.Username != "" {
+		query = query.Where("username = ?", filter.Username)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.SurveyID != 0 {
+		query = query.Where("survey_id = ?", filter.SurveyID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.StartTime.IsZero() {
+		query = query.Where("created_at >= ?", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		query = query.Where("created_at <= ?", filter.EndTime)
+	}
+	if err := query.Count(&num).Error; err != nil {
+		return nil, nil, err
+	}
+	err := query.Order("id DESC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&logs).Error
+	return logs, &num, err
+}
@@ -0,0 +1,104 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+	database "QA-System/internal/pkg/database/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// GetOrphanOptionIDs 获取所属问题已不存在或问题本身为孤立数据的选项
+func (d *Dao) GetOrphanOptionIDs(ctx context.Context) ([]int, error) {
+	var ids []int
+	questions := d.orm.Model(&model.Question{}).Select("id").
+		Where("survey_id IN (?)", d.orm.Unscoped().Model(&model.Survey{}).Select("id"))
+	err := d.orm.WithContext(ctx).Model(&model.Option{}).
+		Where("question_id NOT IN (?)", questions).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetOrphanQuestionIDs 获取所属问卷已被彻底删除的问题
+func (d *Dao) GetOrphanQuestionIDs(ctx context.Context) ([]int, error) {
+	var ids []int
+	err := d.orm.WithContext(ctx).Model(&model.Question{}).
+		Where("survey_id NOT IN (?)", d.orm.Unscoped().Model(&model.Survey{}).Select("id")).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetOrphanManageIDs 获取所属问卷已被彻底删除的权限记录
+func (d *Dao) GetOrphanManageIDs(ctx context.Context) ([]int, error) {
+	var ids []int
+	err := d.orm.WithContext(ctx).Model(&model.Manage{}).
+		Where("survey_id NOT IN (?)", d.orm.Unscoped().Model(&model.Survey{}).Select("id")).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// DeleteOptionsByIDs 批量删除选项
+func (d *Dao) DeleteOptionsByIDs(ctx context.Context, ids []int) error {
+	return d.orm.WithContext(ctx).Where("id IN ?", ids).Delete(&model.Option{}).Error
+}
+
+// DeleteQuestionsByIDs 批量删除问题
+func (d *Dao) DeleteQuestionsByIDs(ctx context.Context, ids []int) error {
+	return d.orm.WithContext(ctx).Where("id IN ?", ids).Delete(&model.Question{}).Error
+}
+
+// DeleteManagesByIDs 批量删除权限记录
+func (d *Dao) DeleteManagesByIDs(ctx context.Context, ids []int) error {
+	return d.orm.WithContext(ctx).Where("id IN ?", ids).Delete(&model.Manage{}).Error
+}
+
+// GetAllSurveyIDs 获取全部问卷id, 包括回收站中的问卷
+func (d *Dao) GetAllSurveyIDs(ctx context.Context) ([]int, error) {
+	var ids []int
+	err := d.orm.WithContext(ctx).Unscoped().Model(&model.Survey{}).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetAnswerSheetSurveyIDs 获取答卷中出现的全部问卷id
+func (d *Dao) GetAnswerSheetSurveyIDs(ctx context.Context) ([]int, error) {
+	values, err := d.mongo.Collection(database.QA).Distinct(ctx, "surveyid", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	return toInts(values), nil
+}
+
+// GetRecordSheetSurveyIDs 获取统一验证记录中出现的全部问卷id
+func (d *Dao) GetRecordSheetSurveyIDs(ctx context.Context) ([]int, error) {
+	values, err := d.mongo.Collection(database.Record).Distinct(ctx, "survey_id", bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	return toInts(values), nil
+}
+
+// toInts 将 MongoDB 返回的数值统一转换为 int
+func toInts(values []any) []int {
+	ids := make([]int, 0, len(values))
+	for _, v := range values {
+		switch id := v.(type) {
+		case int32:
+			ids = append(ids, int(id))
+		case int64:
+			ids = append(ids, int(id))
+		case float64:
+			ids = append(ids, int(id))
+		}
+	}
+	return ids
+}
+
+// GetQuestionAndOptionImgs 获取问题与选项引用的全部图片
+func (d *Dao) GetQuestionAndOptionImgs(ctx context.Context) ([]string, error) {
+	var questionImgs, optionImgs []string
+	err := d.orm.WithContext(ctx).Model(&model.Question{}).Where("img <> ''").Pluck("img", &questionImgs).Error
+	if err != nil {
+		return nil, err
+	}
+	err = d.orm.WithContext(ctx).Model(&model.Option{}).Where("img <> ''").Pluck("img", &optionImgs).Error
+	return append(questionImgs, optionImgs...), err
+}
@@ -24,25 +24,74 @@ func New(orm *gorm.DB, mongodb *mongo.Database) *Dao {
 	}
 }
 
+// Transaction 在 MySQL 事务中执行 fn, fn 返回错误时回滚
+// 事务内只有 MySQL 操作具备原子性, MongoDB、Redis 与文件操作应通过发件箱在提交后执行
+func (d *Dao) Transaction(ctx context.Context, fn func(tx *Dao) error) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Dao{orm: tx, mongo: d.mongo})
+	})
+}
+
 // Daos 数据访问对象接口
 type Daos interface {
 	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
 	GetUserByID(ctx context.Context, id int) (*model.User, error)
 	CreateUser(ctx context.Context, user *model.User) error
+	GetUserList(ctx context.Context, keyword string, adminType int, pageNum, pageSize int) (
+		[]model.User, *int64, error)
+	UpdateUserDisabled(ctx context.Context, uid int, disabled bool) error
+	UpdateUserAdminType(ctx context.Context, uid int, adminType int) error
+	DeleteUser(ctx context.Context, uid int) error
+
+	CreateInvitation(ctx context.Context, invitation *model.Invitation) error
+	GetInvitationByCodeHash(ctx context.Context, codeHash string) (*model.Invitation, error)
+	GetInvitationList(ctx context.Context, pageNum, pageSize int) ([]model.Invitation, *int64, error)
+	RevokeInvitation(ctx context.Context, id int) error
+	CreateUserByInvitation(ctx context.Context, invitationID int, user *model.User, ip string) error
+
+	EnableUserTOTP(ctx context.Context, uid int, secret string, codeHashes []string) error
+	DisableUserTOTP(ctx context.Context, uid int) error
+	ReplaceRecoveryCodes(ctx context.Context, uid int, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, uid int, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, uid int) (int64, error)
+	DeleteRecoveryCodesByUserID(ctx context.Context, uid int) error
+
+	CreateAPIToken(ctx context.Context, token *model.APIToken) error
+	GetAPITokenByHash(ctx context.Context, tokenHash string) (*model.APIToken, error)
+	GetAPITokensByUserID(ctx context.Context, uid int) ([]model.APIToken, error)
+	RevokeAPIToken(ctx context.Context, uid int, id int) (bool, error)
+	UpdateAPITokenLastUsed(ctx context.Context, id int, t time.Time) error
+	DeleteAPITokensByUserID(ctx context.Context, uid int) error
+
+	CreateAuditLog(ctx context.Context, log *model.AuditLog) error
+	GetAuditLogs(ctx context.Context, filter AuditFilter, pageNum, pageSize int) ([]model.AuditLog, *int64, error)
 
-	SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet) error
+	GetSetting(ctx context.Context, key string) (string, error)
+	SetSetting(ctx context.Context, key string, value string) error
+
+	SaveAnswerSheet(ctx context.Context, answerSheet AnswerSheet, qids []int) error
+	InsertAnswerSheets(ctx context.Context, answerSheets []AnswerSheet, qids []int) (int, error)
 	GetAnswerSheetBySurveyID(ctx context.Context, surveyID int, pageNum int, pageSize int) (
 		[]AnswerSheet, *int64, error)
 	DeleteAnswerSheetBySurveyID(ctx context.Context, surveyID int) error
 	DeleteAnswerSheetByAnswerID(ctx context.Context, AnswerID primitive.ObjectID) error
 	GetAnswerSheetByAnswerID(ctx context.Context, AnswerID primitive.ObjectID) (AnswerSheet, error)
+	SoftDeleteAnswerSheet(ctx context.Context, answerID primitive.ObjectID) error
+	RestoreAnswerSheet(ctx context.Context, answerID primitive.ObjectID) error
+	GetDeletedAnswerSheetByAnswerID(ctx context.Context, answerID primitive.ObjectID) (AnswerSheet, error)
+	GetDeletedAnswerSheets(ctx context.Context, surveyID int, before time.Time) ([]AnswerSheet, error)
+	GetAllAnswerSheetsBySurveyID(ctx context.Context, surveyID int) ([]AnswerSheet, error)
 
-	CreateManage(ctx context.Context, id int, surveyID int) error
+	CreateManage(ctx context.Context, id int, surveyID int, role int) error
+	UpdateManageRole(ctx context.Context, id int, surveyID int, role int) error
 	DeleteManage(ctx context.Context, id int, surveyID int) error
 	DeleteManageBySurveyID(ctx context.Context, surveyID int) error
 	CheckManage(ctx context.Context, id int, surveyID int) error
 	GetManageByUIDAndSID(ctx context.Context, uid int, sid int) (*model.Manage, error)
 	GetManageByUserID(ctx context.Context, uid int) ([]model.Manage, error)
+	GetManageBySurveyID(ctx context.Context, sid int) ([]model.Manage, error)
+	DeleteManageByUserID(ctx context.Context, uid int) error
+	DeleteManageOfOwnedSurveys(ctx context.Context, uid int) error
 
 	CreateOption(ctx context.Context, option *model.Option) error
 	GetOptionsByQuestionID(ctx context.Context, questionID int) ([]model.Option, error)
@@ -62,7 +111,63 @@ type Daos interface {
 	UpdateSurvey(ctx context.Context, id int, title, desc, img string, deadline time.Time) error
 	GetAllSurveyByUserID(ctx context.Context, userId int) ([]model.Survey, error)
 	IncreaseSurveyNum(ctx context.Context, sid int) error
+	CountSurveyByUserID(ctx context.Context, userId int) (int64, error)
+	TransferSurveys(ctx context.Context, from int, to int) error
+	PurgeSurvey(ctx context.Context, surveyID int) error
+	RestoreSurvey(ctx context.Context, surveyID int) error
+	GetDeletedSurveyByID(ctx context.Context, surveyID int) (*model.Survey, error)
+	GetDeletedSurveys(ctx context.Context, userID int, before time.Time) ([]model.Survey, error)
 
 	SaveRecordSheet(ctx context.Context, answerSheet RecordSheet, sid int) error
 	DeleteRecordSheets(ctx context.Context, surveyID int) error
+
+	Transaction(ctx context.Context, fn func(tx *Dao) error) error
+	CreateOutboxEvents(ctx context.Context, events []model.OutboxEvent) error
+	GetPendingOutboxEvents(ctx context.Context, before time.Time, maxAttempts int, limit int) (
+		[]model.OutboxEvent, error)
+	MarkOutboxEventDone(ctx context.Context, id int) error
+	MarkOutboxEventFailed(ctx context.Context, id int, reason string) error
+	CountPendingOutboxEvents(ctx context.Context) (int64, error)
+
+	CreateUpload(ctx context.Context, upload *model.Upload) error
+	GetUploadsByKeys(ctx context.Context, keys []string) ([]model.Upload, error)
+	SumUploadSize(ctx context.Context, questionID int, uploader string) (int64, error)
+	AttachUploads(ctx context.Context, keys []string, surveyID int) error
+	ClaimUploads(ctx context.Context, keys []string, surveyID int, uploader string) (int64, error)
+	DetachUploads(ctx context.Context, keys []string) error
+	GetPendingUploads(ctx context.Context, before time.Time) ([]model.Upload, error)
+	DeleteUploadsByKeys(ctx context.Context, keys []string) error
+
+	CreateWebhook(ctx context.Context, webhook *model.Webhook) error
+	GetWebhookByID(ctx context.Context, id int) (*model.Webhook, error)
+	GetWebhooksBySurveyID(ctx context.Context, sid int) ([]model.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook *model.Webhook) error
+	MarkWebhookQuotaNotified(ctx context.Context, id int) (bool, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	DeleteWebhooksBySurveyID(ctx context.Context, sid int) error
+	CreateWebhookDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error
+	GetWebhookDeliveryByID(ctx context.Context, id int) (*model.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, webhookID int, pageNum, pageSize int) (
+		[]model.WebhookDelivery, *int64, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+
+	GetNotification(ctx context.Context, sid int, uid int) (*model.Notification, error)
+	GetNotificationByID(ctx context.Context, id int) (*model.Notification, error)
+	SaveNotification(ctx context.Context, notification *model.Notification) error
+	DeleteNotification(ctx context.Context, sid int, uid int) error
+	DeleteNotificationsBySurveyID(ctx context.Context, sid int) error
+	DeleteNotificationsByUserID(ctx context.Context, uid int) error
+	GetNotificationsByMode(ctx context.Context, sid int, mode int) ([]model.Notification, error)
+	UpdateNotificationDigestAt(ctx context.Context, id int, t time.Time) error
+
+	GetOrphanOptionIDs(ctx context.Context) ([]int, error)
+	GetOrphanQuestionIDs(ctx context.Context) ([]int, error)
+	GetOrphanManageIDs(ctx context.Context) ([]int, error)
+	DeleteOptionsByIDs(ctx context.Context, ids []int) error
+	DeleteQuestionsByIDs(ctx context.Context, ids []int) error
+	DeleteManagesByIDs(ctx context.Context, ids []int) error
+	GetAllSurveyIDs(ctx context.Context) ([]int, error)
+	GetAnswerSheetSurveyIDs(ctx context.Context) ([]int, error)
+	GetRecordSheetSurveyIDs(ctx context.Context) ([]int, error)
+	GetQuestionAndOptionImgs(ctx context.Context) ([]string, error)
 }
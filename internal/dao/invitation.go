@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"QA-System/internal/model"
+	"gorm.io/gorm"
+)
+
+// ErrInvitationUnavailable 邀请码已被使用、作废或过期
+var ErrInvitationUnavailable = errors.New("邀请码不可用")
+
+// CreateInvitation 创建邀请码
+func (d *Dao) CreateInvitation(ctx context.Context, invitation *model.Invitation) error {
+	return d.orm.WithContext(ctx).Create(invitation).Error
+}
+
+// GetInvitationByCodeHash 根据邀请码摘要获取邀请码
+func (d *Dao) GetInvitationByCodeHash(ctx context.Context, codeHash string) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := d.orm.WithContext(ctx).Where("code_hash = ?", codeHash).First(&invitation).Error
+	return &invitation, err
+}
+
+// GetInvitationList 分页获取邀请码, 按创建时间倒序
+func (d *Dao) GetInvitationList(ctx context.Context, pageNum, pageSize int) ([]model.Invitation, *int64, error) {
+	var invitations []model.Invitation
+	var num int64
+	query := d.orm.WithContext(ctx).Model(&model.Invitation{})
+	if err := query.Count(&num).Error; err != nil {
+		return nil, nil, err
+	}
+	err := query.Order("id DESC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&invitations).Error
+	return invitations, &num, err
+}
+
+// RevokeInvitation 作废未使用的邀请码
+func (d *Dao) RevokeInvitation(ctx context.Context, id int) error {
+	result := d.orm.WithContext(ctx).Model(&model.Invitation{}).Where("id = ? AND used_by = 0", id).
+		Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationUnavailable
+	}
+	return nil
+}
+
+// CreateUserByInvitation 使用邀请码创建用户, 邀请码的核销与用户创建在同一事务中完成
+func (d *Dao) CreateUserByInvitation(ctx context.Context, invitationID int, user *model.User, ip string) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		result := tx.Model(&model.Invitation{}).
+			Where("id = ? AND used_by = 0 AND revoked = ? AND expire_at > ?", invitationID, false, now).
+			Updates(map[string]any{"used_by": user.ID, "used_at": now, "used_ip": ip})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationUnavailable
+		}
+		return nil
+	})
+}
@@ -7,8 +7,15 @@ import (
 )
 
 // CreateManage 创建问卷权限
-func (d *Dao) CreateManage(ctx context.Context, id int, surveyID int) error {
-	err := d.orm.WithContext(ctx).Create(&model.Manage{UserID: id, SurveyID: surveyID}).Error
+func (d *Dao) CreateManage(ctx context.Context, id int, surveyID int, role int) error {
+	err := d.orm.WithContext(ctx).Create(&model.Manage{UserID: id, SurveyID: surveyID, Role: role}).Error
+	return err
+}
+
+// UpdateManageRole 更新协作者角色
+func (d *Dao) UpdateManageRole(ctx context.Context, id int, surveyID int, role int) error {
+	err := d.orm.WithContext(ctx).Model(&model.Manage{}).Where("user_id = ? AND survey_id = ?", id, surveyID).
+		Update("role", role).Error
 	return err
 }
 
@@ -44,3 +51,23 @@ func (d *Dao) GetManageByUserID(ctx context.Context, uid int) ([]model.Manage, e
 	err := d.orm.WithContext(ctx).Where("user_id = ?", uid).Find(&manages).Error
 	return manages, err
 }
+
+// GetManageBySurveyID 根据问卷ID获取问卷权限
+func (d *Dao) GetManageBySurveyID(ctx context.Context, sid int) ([]model.Manage, error) {
+	var manages []model.Manage
+	err := d.orm.WithContext(ctx).Where("survey_id = ?", sid).Find(&manages).Error
+	return manages, err
+}
+
+// DeleteManageByUserID 根据用户ID删除问卷权限
+func (d *Dao) DeleteManageByUserID(ctx context.Context, uid int) error {
+	err := d.orm.WithContext(ctx).Where("user_id = ?", uid).Delete(&model.Manage{}).Error
+	return err
+}
+
+// DeleteManageOfOwnedSurveys 删除用户在自己所有的问卷上的多余协作权限
+func (d *Dao) DeleteManageOfOwnedSurveys(ctx context.Context, uid int) error {
+	err := d.orm.WithContext(ctx).Where("user_id = ? AND survey_id IN (?)", uid,
+		d.orm.Model(&model.Survey{}).Select("id").Where("user_id = ?", uid)).Delete(&model.Manage{}).Error
+	return err
+}
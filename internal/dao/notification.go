@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"QA-System/internal/model"
+)
+
+// GetNotification 获取用户对问卷的邮件通知订阅
+func (d *Dao) GetNotification(ctx context.Context, sid int, uid int) (*model.Notification, error) {
+	var notification model.Notification
+	err := d.orm.WithContext(ctx).Where("survey_id = ? AND user_id = ?", sid, uid).First(&notification).Error
+	return &notification, err
+}
+
+// GetNotificationByID 根据id获取邮件通知订阅
+func (d *Dao) GetNotificationByID(ctx context.Context, id int) (*model.Notification, error) {
+	var notification model.Notification
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&notification).Error
+	return &notification, err
+}
+
+// SaveNotification 创建或更新邮件通知订阅
+func (d *Dao) SaveNotification(ctx context.Context, notification *model.Notification) error {
+	return d.orm.WithContext(ctx).Save(notification).Error
+}
+
+// DeleteNotification 取消用户对问卷的邮件通知订阅
+func (d *Dao) DeleteNotification(ctx context.Context, sid int, uid int) error {
+	return d.orm.WithContext(ctx).Where("survey_id = ? AND user_id = ?", sid, uid).
+		Delete(&model.Notification{}).Error
+}
+
+// DeleteNotificationsBySurveyID 删除问卷的全部邮件通知订阅
+func (d *Dao) DeleteNotificationsBySurveyID(ctx context.Context, sid int) error {
+	return d.orm.WithContext(ctx).Where("survey_id = ?", sid).Delete(&model.Notification{}).Error
+}
+
+// DeleteNotificationsByUserID 删除用户的全部邮件通知订阅
+func (d *Dao) DeleteNotificationsByUserID(ctx context.Context, uid int) error {
+	return d.orm.WithContext(ctx).Where("user_id = ?", uid).Delete(&model.Notification{}).Error
+}
+
+// GetNotificationsByMode 获取指定通知方式的订阅, sid 为0时获取全部问卷
+func (d *Dao) GetNotificationsByMode(ctx context.Context, sid int, mode int) ([]model.Notification, error) {
+	var notifications []model.Notification
+	query := d.orm.WithContext(ctx).Where("mode = ?", mode)
+	if sid != 0 {
+		query = query.Where("survey_id = ?", sid)
+	}
+	err := query.Order("id").Find(&notifications).Error
+	return notifications, err
+}
+
+// UpdateNotificationDigestAt 更新最近一次发送汇总的时间
+func (d *Dao) UpdateNotificationDigestAt(ctx context.Context, id int, t time.Time) error {
+	return d.orm.WithContext(ctx).Model(&model.Notification{}).Where("id = ?", id).
+		Update("last_digest_at", t).Error
+}
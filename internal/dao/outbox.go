@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"QA-System/internal/model"
+	"gorm.io/gorm"
+)
+
+// CreateOutboxEvents 写入发件箱事件, 应在业务事务中调用
+func (d *Dao) CreateOutboxEvents(ctx context.Context, events []model.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return d.orm.WithContext(ctx).Create(&events).Error
+}
+
+// GetPendingOutboxEvents 获取 before 之前创建且未执行成功的发件箱事件
+func (d *Dao) GetPendingOutboxEvents(ctx context.Context, before time.Time, maxAttempts int, limit int) (
+	[]model.OutboxEvent, error) {
+	var events []model.OutboxEvent
+	err := d.orm.WithContext(ctx).Where("processed_at IS NULL AND created_at < ? AND attempts < ?",
+		before, maxAttempts).Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// MarkOutboxEventDone 标记发件箱事件执行成功
+func (d *Dao) MarkOutboxEventDone(ctx context.Context, id int) error {
+	return d.orm.WithContext(ctx).Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]any{
+		"attempts":     gorm.Expr("attempts + 1"),
+		"processed_at": time.Now(),
+		"last_error":   "",
+	}).Error
+}
+
+// MarkOutboxEventFailed 记录发件箱事件执行失败
+func (d *Dao) MarkOutboxEventFailed(ctx context.Context, id int, reason string) error {
+	return d.orm.WithContext(ctx).Model(&model.OutboxEvent{}).Where("id = ?", id).Updates(map[string]any{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": reason,
+	}).Error
+}
+
+// CountPendingOutboxEvents 统计未执行成功的发件箱事件
+func (d *Dao) CountPendingOutboxEvents(ctx context.Context) (int64, error) {
+	var num int64
+	err := d.orm.WithContext(ctx).Model(&model.OutboxEvent{}).Where("processed_at IS NULL").Count(&num).Error
+	return num, err
+}
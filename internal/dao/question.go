@@ -14,9 +14,10 @@ import (
 type BaseConfig struct {
 	StartTime  string `json:"start_time"`
 	EndTime    string `json:"end_time"`
-	DailyLimit uint   `json:"day_limit"` // 问卷每日填写限制
-	SumLimit   uint   `json:"sum_limit"` // 问卷总填写次数限制
-	Verify     bool   `json:"verify"`    // 问卷是否需要统一验证
+	DailyLimit uint   `json:"day_limit"`                       // 问卷每日填写限制
+	SumLimit   uint   `json:"sum_limit"`                       // 问卷总填写次数限制
+	Verify     bool   `json:"verify"`                          // 问卷是否需要统一验证
+	Challenge  int    `json:"challenge" binding:"oneof=0 1 2"` // 提交前的人机验证 0:不验证 1:工作量证明 2:图片验证码
 }
 
 // QuestionConfig 问题配置模型
@@ -46,6 +47,11 @@ type QuestionSetting struct {
 	Options       []Option `json:"options"`                                            // 选项
 	MaximumOption uint     `json:"maximum_option"`                                     // 多选最多选项数 0为不限制
 	MinimumOption uint     `json:"minimum_option"`                                     // 多选最少选项数 0为不限制
+	FileTypes     string   `json:"file_types"`                                         // 文件题允许的 MIME 类型, 逗号分隔
+	MaxFileSize   int64    `json:"max_file_size" binding:"min=0"`                      // 单个文件最大字节数 0为使用默认限制
+	MaxFileCount  uint     `json:"max_file_count"`                                     // 最多上传文件数 0为不限制
+	UploadQuota   int64    `json:"upload_quota" binding:"min=0"`                       // 每位答卷人上传的总字节数 0为不限制
+	SendCopy      bool     `json:"send_copy"`                                          // 是否向该题填写的邮箱发送答卷副本
 }
 
 // QuestionsList 问题列表模型
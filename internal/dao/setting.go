@@ -0,0 +1,26 @@
+package dao
+
+import (
+	"context"
+	"errors"
+
+	"QA-System/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSetting 获取系统设置, 不存在时返回空字符串
+func (d *Dao) GetSetting(ctx context.Context, key string) (string, error) {
+	var setting model.Setting
+	err := d.orm.WithContext(ctx).Where("`key` = ?", key).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return setting.Value, err
+}
+
+// SetSetting 保存系统设置
+func (d *Dao) SetSetting(ctx context.Context, key string, value string) error {
+	return d.orm.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&model.Setting{Key: key, Value: value}).Error
+}
@@ -22,7 +22,7 @@ func (d *Dao) UpdateSurveyStatus(ctx context.Context, surveyID int, status int)
 
 // UpdateSurvey 更新问卷
 func (d *Dao) UpdateSurvey(ctx context.Context, id int, surveyType, limit uint,
-	sumLimit uint, verify bool, desc string, title string, deadline, startTime time.Time) error {
+	sumLimit uint, verify bool, challenge int, desc string, title string, deadline, startTime time.Time) error {
 	err := d.orm.WithContext(ctx).Model(&model.Survey{}).Where("id = ?", id).
 		Updates(model.Survey{
 			Deadline:   deadline,
@@ -34,7 +34,11 @@ func (d *Dao) UpdateSurvey(ctx context.Context, id int, surveyType, limit uint,
 			Type:       surveyType,
 			StartTime:  startTime,
 		}).Error
-	return err
+	if err != nil {
+		return err
+	}
+	// Updates 会忽略零值, 关闭人机验证需要单独更新
+	return d.orm.WithContext(ctx).Model(&model.Survey{}).Where("id = ?", id).Update("challenge", challenge).Error
 }
 
 // GetSurveyByUserID 获取用户的所有问卷
@@ -65,8 +69,65 @@ func (d *Dao) IncreaseSurveyNum(ctx context.Context, sid int) error {
 	return err
 }
 
+// IncreaseSurveyNumBy 增加问卷填写人数 n 次
+func (d *Dao) IncreaseSurveyNumBy(ctx context.Context, sid int, n int) error {
+	err := d.orm.WithContext(ctx).Model(&model.Survey{}).Where("id = ?", sid).
+		Update("num", gorm.Expr("num + ?", n)).Error
+	return err
+}
+
 // DeleteSurvey 删除问卷
 func (d *Dao) DeleteSurvey(ctx context.Context, surveyID int) error {
 	err := d.orm.WithContext(ctx).Where("id = ?", surveyID).Delete(&model.Survey{}).Error
 	return err
 }
+
+// CountSurveyByUserID 统计用户创建的问卷数量
+func (d *Dao) CountSurveyByUserID(ctx context.Context, userId int) (int64, error) {
+	var num int64
+	// 回收站中的问卷同样计入
+	err := d.orm.WithContext(ctx).Unscoped().Model(&model.Survey{}).Where("user_id = ?", userId).Count(&num).Error
+	return num, err
+}
+
+// TransferSurveys 将用户的全部问卷转移给另一用户
+func (d *Dao) TransferSurveys(ctx context.Context, from int, to int) error {
+	// 回收站中的问卷一并转移, 以便恢复后仍有所有者
+	err := d.orm.WithContext(ctx).Unscoped().Model(&model.Survey{}).Where("user_id = ?", from).Update("user_id", to).Error
+	return err
+}
+
+// PurgeSurvey 彻底删除问卷
+func (d *Dao) PurgeSurvey(ctx context.Context, surveyID int) error {
+	err := d.orm.WithContext(ctx).Unscoped().Where("id = ?", surveyID).Delete(&model.Survey{}).Error
+	return err
+}
+
+// RestoreSurvey 从回收站恢复问卷
+func (d *Dao) RestoreSurvey(ctx context.Context, surveyID int) error {
+	err := d.orm.WithContext(ctx).Unscoped().Model(&model.Survey{}).Where("id = ?", surveyID).
+		Update("deleted_at", nil).Error
+	return err
+}
+
+// GetDeletedSurveyByID 根据问卷ID获取回收站中的问卷
+func (d *Dao) GetDeletedSurveyByID(ctx context.Context, surveyID int) (*model.Survey, error) {
+	var survey model.Survey
+	err := d.orm.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", surveyID).
+		First(&survey).Error
+	return &survey, err
+}
+
+// GetDeletedSurveys 获取回收站中的问卷, userID 为 0 时获取全部, before 为零值时不按删除时间筛选
+func (d *Dao) GetDeletedSurveys(ctx context.Context, userID int, before time.Time) ([]model.Survey, error) {
+	var surveys []model.Survey
+	query := d.orm.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if !before.IsZero() {
+		query = query.Where("deleted_at < ?", before)
+	}
+	err := query.Order("deleted_at DESC").Find(&surveys).Error
+	return surveys, err
+}
@@ -0,0 +1,72 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"QA-System/internal/model"
+	"gorm.io/gorm"
+)
+
+// EnableUserTOTP 开启两步验证并替换全部恢复码
+func (d *Dao) EnableUserTOTP(ctx context.Context, uid int, secret string, codeHashes []string) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", uid).
+			Updates(map[string]any{"totp_secret": secret, "totp_enabled": true}).Error
+		if err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, uid, codeHashes)
+	})
+}
+
+// DisableUserTOTP 关闭两步验证并删除恢复码
+func (d *Dao) DisableUserTOTP(ctx context.Context, uid int) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.User{}).Where("id = ?", uid).
+			Updates(map[string]any{"totp_secret": "", "totp_enabled": false}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", uid).Delete(&model.RecoveryCode{}).Error
+	})
+}
+
+// ReplaceRecoveryCodes 重新生成恢复码, 旧的恢复码全部失效
+func (d *Dao) ReplaceRecoveryCodes(ctx context.Context, uid int, codeHashes []string) error {
+	return d.orm.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, uid, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, uid int, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", uid).Delete(&model.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]model.RecoveryCode, 0, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes = append(codes, model.RecoveryCode{UserID: uid, CodeHash: hash})
+	}
+	return tx.Create(&codes).Error
+}
+
+// UseRecoveryCode 核销恢复码, 恢复码不存在或已使用时返回 false
+func (d *Dao) UseRecoveryCode(ctx context.Context, uid int, codeHash string) (bool, error) {
+	result := d.orm.WithContext(ctx).Model(&model.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", uid, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// CountRecoveryCodes 统计剩余可用的恢复码数量
+func (d *Dao) CountRecoveryCodes(ctx context.Context, uid int) (int64, error) {
+	var num int64
+	err := d.orm.WithContext(ctx).Model(&model.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", uid).
+		Count(&num).Error
+	return num, err
+}
+
+// DeleteRecoveryCodesByUserID 删除用户的全部恢复码
+func (d *Dao) DeleteRecoveryCodesByUserID(ctx context.Context, uid int) error {
+	return d.orm.WithContext(ctx).Where("user_id = ?", uid).Delete(&model.RecoveryCode{}).Error
+}
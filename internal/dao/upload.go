@@ -0,0 +1,88 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"QA-System/internal/model"
+)
+
+// CreateUpload 登记上传的文件
+func (d *Dao) CreateUpload(ctx context.Context, upload *model.Upload) error {
+	return d.orm.WithContext(ctx).Create(upload).Error
+}
+
+// GetUploadsByKeys 根据 key 获取上传记录
+func (d *Dao) GetUploadsByKeys(ctx context.Context, keys []string) ([]model.Upload, error) {
+	var uploads []model.Upload
+	if len(keys) == 0 {
+		return uploads, nil
+	}
+	err := d.orm.WithContext(ctx).Where("`key` IN ?", keys).Find(&uploads).Error
+	return uploads, err
+}
+
+// SumUploadSize 统计上传者在问题中上传的总字节数
+func (d *Dao) SumUploadSize(ctx context.Context, questionID int, uploader string) (int64, error) {
+	var size int64
+	err := d.orm.WithContext(ctx).Model(&model.Upload{}).Where("question_id = ? AND uploader = ?", questionID, uploader).
+		Select("COALESCE(SUM(size), 0)").Scan(&size).Error
+	return size, err
+}
+
+// AttachUploads 将待关联的文件关联到问卷, 已关联的文件保持不变
+func (d *Dao) AttachUploads(ctx context.Context, keys []string, surveyID int) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return d.orm.WithContext(ctx).Model(&model.Upload{}).
+		Where("`key` IN ? AND status = ?", keys, model.UploadPending).Updates(map[string]any{
+		"status":      model.UploadAttached,
+		"survey_id":   surveyID,
+		"attached_at": time.Now(),
+	}).Error
+}
+
+// ClaimUploads 将上传者待关联的文件关联到问卷, 返回实际关联的数量
+// 以待关联状态为条件更新, 并发提交同一文件时只有一次能成功
+func (d *Dao) ClaimUploads(ctx context.Context, keys []string, surveyID int, uploader string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	result := d.orm.WithContext(ctx).Model(&model.Upload{}).
+		Where("`key` IN ? AND status = ? AND uploader = ?", keys, model.UploadPending, uploader).
+		Updates(map[string]any{
+			"status":      model.UploadAttached,
+			"survey_id":   surveyID,
+			"attached_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// DetachUploads 将已关联的文件恢复为待关联, 用于答卷保存失败时撤销关联
+func (d *Dao) DetachUploads(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return d.orm.WithContext(ctx).Model(&model.Upload{}).
+		Where("`key` IN ? AND status = ?", keys, model.UploadAttached).Updates(map[string]any{
+		"status":      model.UploadPending,
+		"attached_at": nil,
+	}).Error
+}
+
+// GetPendingUploads 获取 before 之前上传且仍未关联的文件
+func (d *Dao) GetPendingUploads(ctx context.Context, before time.Time) ([]model.Upload, error) {
+	var uploads []model.Upload
+	err := d.orm.WithContext(ctx).Where("status = ? AND created_at < ?", model.UploadPending, before).
+		Order("id").Find(&uploads).Error
+	return uploads, err
+}
+
+// DeleteUploadsByKeys 删除上传记录
+func (d *Dao) DeleteUploadsByKeys(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return d.orm.WithContext(ctx).Where("`key` IN ?", keys).Delete(&model.Upload{}).Error
+}
@@ -26,8 +26,46 @@ func (d *Dao) CreateUser(ctx context.Context, user *model.User) error {
 	return result.Error
 }
 
-// UpdateUserPassword 更新用户密码
-func (d *Dao) UpdateUserPassword(ctx context.Context, uid int, password string) error {
-	result := d.orm.WithContext(ctx).Model(&model.User{}).Where("id = ?", uid).Update("password", password)
+// UpdateUserPassword 更新用户密码及是否需要修改密码的标记
+func (d *Dao) UpdateUserPassword(ctx context.Context, uid int, password string, mustChange bool) error {
+	result := d.orm.WithContext(ctx).Model(&model.User{}).Where("id = ?", uid).
+		Updates(map[string]any{"password": password, "must_change_password": mustChange})
+	return result.Error
+}
+
+// GetUserList 分页获取用户列表, keyword 为空时不按用户名筛选, adminType 为 0 时不按类型筛选
+func (d *Dao) GetUserList(ctx context.Context, keyword string, adminType int, pageNum, pageSize int) (
+	[]model.User, *int64, error) {
+	var users []model.User
+	var num int64
+	query := d.orm.WithContext(ctx).Model(&model.User{})
+	if keyword != "" {
+		query = query.Where("username LIKE ?", "%"+keyword+"%")
+	}
+	if adminType != 0 {
+		query = query.Where("admin_type = ?", adminType)
+	}
+	if err := query.Count(&num).Error; err != nil {
+		return nil, nil, err
+	}
+	err := query.Order("id ASC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&users).Error
+	return users, &num, err
+}
+
+// UpdateUserDisabled 更新用户停用状态
+func (d *Dao) UpdateUserDisabled(ctx context.Context, uid int, disabled bool) error {
+	result := d.orm.WithContext(ctx).Model(&model.User{}).Where("id = ?", uid).Update("disabled", disabled)
+	return result.Error
+}
+
+// UpdateUserAdminType 更新用户管理员类型
+func (d *Dao) UpdateUserAdminType(ctx context.Context, uid int, adminType int) error {
+	result := d.orm.WithContext(ctx).Model(&model.User{}).Where("id = ?", uid).Update("admin_type", adminType)
+	return result.Error
+}
+
+// DeleteUser 删除用户
+func (d *Dao) DeleteUser(ctx context.Context, uid int) error {
+	result := d.orm.WithContext(ctx).Where("id = ?", uid).Delete(&model.User{})
 	return result.Error
 }
@@ -0,0 +1,87 @@
+package dao
+
+import (
+	"context"
+
+	"QA-System/internal/model"
+)
+
+// CreateWebhook 创建 Webhook
+func (d *Dao) CreateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	return d.orm.WithContext(ctx).Create(webhook).Error
+}
+
+// GetWebhookByID 根据id获取 Webhook
+func (d *Dao) GetWebhookByID(ctx context.Context, id int) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&webhook).Error
+	return &webhook, err
+}
+
+// GetWebhooksBySurveyID 获取问卷的全部 Webhook
+func (d *Dao) GetWebhooksBySurveyID(ctx context.Context, sid int) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	err := d.orm.WithContext(ctx).Where("survey_id = ?", sid).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// UpdateWebhook 更新 Webhook 的全部字段
+func (d *Dao) UpdateWebhook(ctx context.Context, webhook *model.Webhook) error {
+	return d.orm.WithContext(ctx).Save(webhook).Error
+}
+
+// MarkWebhookQuotaNotified 标记已推送 quota.reached, 返回是否由本次调用标记, 用于避免并发提交时重复推送
+func (d *Dao) MarkWebhookQuotaNotified(ctx context.Context, id int) (bool, error) {
+	result := d.orm.WithContext(ctx).Model(&model.Webhook{}).Where("id = ? AND quota_notified = ?", id, false).
+		Update("quota_notified", true)
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func (d *Dao) DeleteWebhook(ctx context.Context, id int) error {
+	if err := d.orm.WithContext(ctx).Where("webhook_id = ?", id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return d.orm.WithContext(ctx).Where("id = ?", id).Delete(&model.Webhook{}).Error
+}
+
+// DeleteWebhooksBySurveyID 删除问卷的全部 Webhook 及投递记录
+func (d *Dao) DeleteWebhooksBySurveyID(ctx context.Context, sid int) error {
+	if err := d.orm.WithContext(ctx).Where("survey_id = ?", sid).Delete(&model.WebhookDelivery{}).Error; err != nil {
+		return err
+	}
+	return d.orm.WithContext(ctx).Where("survey_id = ?", sid).Delete(&model.Webhook{}).Error
+}
+
+// CreateWebhookDeliveries 批量创建投递记录
+func (d *Dao) CreateWebhookDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return d.orm.WithContext(ctx).Create(&deliveries).Error
+}
+
+// GetWebhookDeliveryByID 根据id获取投递记录
+func (d *Dao) GetWebhookDeliveryByID(ctx context.Context, id int) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := d.orm.WithContext(ctx).Where("id = ?", id).First(&delivery).Error
+	return &delivery, err
+}
+
+// GetWebhookDeliveries 分页获取 Webhook 的投递记录, 按时间倒序
+func (d *Dao) GetWebhookDeliveries(ctx context.Context, webhookID int, pageNum, pageSize int) (
+	[]model.WebhookDelivery, *int64, error) {
+	var deliveries []model.WebhookDelivery
+	var num int64
+	query := d.orm.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if err := query.Count(&num).Error; err != nil {
+		return nil, nil, err
+	}
+	err := query.Order("id DESC").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error
+	return deliveries, &num, err
+}
+
+// UpdateWebhookDelivery 保存投递结果
+func (d *Dao) UpdateWebhookDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return d.orm.WithContext(ctx).Save(delivery).Error
+}
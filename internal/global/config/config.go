@@ -1,22 +1,21 @@
 package config
 
 import (
-	"log"
-
 	"github.com/spf13/viper"
 )
 
 // Config 全局配置变量
 var Config = viper.New()
 
-func init() {
+// Init 读取配置文件, 需在初始化其他模块前调用
+func Init() error {
 	Config.AddConfigPath("conf")
 	Config.SetConfigName("config")
 	Config.SetConfigType("yaml")
 	Config.AddConfigPath(".")
-	Config.WatchConfig() // 自动将配置读入Config变量
-	err := Config.ReadInConfig()
-	if err != nil {
-		log.Fatal("Config not find", err)
+	if err := Config.ReadInConfig(); err != nil {
+		return err
 	}
+	Config.WatchConfig() // 自动将配置读入Config变量
+	return nil
 }
@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// getSuperAdmin 获取当前登录的超级管理员, 非超级管理员时中止请求
+func getSuperAdmin(c *gin.Context) (*model.User, bool) {
+	admin, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return nil, false
+	}
+	if admin.AdminType != 2 {
+		code.AbortWithException(c, code.NoPermission, errors.New(admin.Username+"没有权限"))
+		return nil, false
+	}
+	return admin, true
+}
+
+// getTargetAdmin 获取被操作的管理员, 不允许操作自己
+func getTargetAdmin(c *gin.Context, admin *model.User, username string) (*model.User, bool) {
+	user, err := service.GetAdminByUsername(username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.UserNotFind, err)
+		return nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if user.ID == admin.ID {
+		code.AbortWithException(c, code.OperateSelfError, errors.New(admin.Username+"不能操作自己的账号"))
+		return nil, false
+	}
+	return user, true
+}
+
+type getAdminListData struct {
+	PageNum   int    `form:"page_num" binding:"required,min=1"`
+	PageSize  int    `form:"page_size" binding:"required,min=1,max=100"`
+	Keyword   string `form:"keyword"`
+	AdminType int    `form:"admin_type" binding:"omitempty,oneof=1 2"`
+}
+
+// GetAdminList 获取管理员列表
+func GetAdminList(c *gin.Context) {
+	var data getAdminListData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if _, ok := getSuperAdmin(c); !ok {
+		return
+	}
+	list, total, err := service.GetAdminList(data.Keyword, data.AdminType, data.PageNum, data.PageSize)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"user_list":      list,
+		"total":          *total,
+		"total_page_num": math.Ceil(float64(*total) / float64(data.PageSize)),
+	})
+}
+
+type updateAdminStatusData struct {
+	UserName string `json:"username" binding:"required"`
+	Disabled bool   `json:"disabled"`
+}
+
+// UpdateAdminStatus 停用或启用管理员
+func UpdateAdminStatus(c *gin.Context) {
+	var data updateAdminStatusData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	user, ok := getTargetAdmin(c, admin, data.UserName)
+	if !ok {
+		return
+	}
+	err = service.SetAdminDisabled(user.ID, data.Disabled)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if data.Disabled {
+		err = service.RevokeAdminSessions(user.ID, "")
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditUserStatus,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username, "disabled": user.Disabled},
+		After:      gin.H{"username": user.Username, "disabled": data.Disabled},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type updateAdminTypeData struct {
+	UserName  string `json:"username" binding:"required"`
+	AdminType int    `json:"admin_type" binding:"required,oneof=1 2"`
+}
+
+// UpdateAdminType 提升或降级管理员
+func UpdateAdminType(c *gin.Context) {
+	var data updateAdminTypeData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	user, ok := getTargetAdmin(c, admin, data.UserName)
+	if !ok {
+		return
+	}
+	err = service.SetAdminType(user.ID, data.AdminType)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditUserType,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username, "admin_type": user.AdminType},
+		After:      gin.H{"username": user.Username, "admin_type": data.AdminType},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type deleteAdminData struct {
+	UserName   string `form:"username" binding:"required"`
+	TransferTo string `form:"transfer_to"` // 问卷接收人用户名
+}
+
+// DeleteAdmin 删除管理员, 其名下问卷可转移给其他管理员
+func DeleteAdmin(c *gin.Context) {
+	var data deleteAdminData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	user, ok := getTargetAdmin(c, admin, data.UserName)
+	if !ok {
+		return
+	}
+	var receiver *model.User
+	if data.TransferTo != "" {
+		receiver, err = service.GetAdminByUsername(data.TransferTo)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			code.AbortWithException(c, code.UserNotFind, err)
+			return
+		} else if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		if receiver.ID == user.ID || receiver.Disabled {
+			code.AbortWithException(c, code.ParamError, errors.New("问卷接收人无效"))
+			return
+		}
+	} else {
+		// 未指定接收人时, 仅允许删除没有问卷的管理员
+		num, err := service.CountAdminSurveys(user.ID)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		if num > 0 {
+			code.AbortWithException(c, code.UserHasSurveys, errors.New(user.Username+"仍有问卷"))
+			return
+		}
+	}
+	err = service.DeleteAdmin(user, receiver)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	err = service.RevokeAdminSessions(user.ID, "")
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditUserDelete,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username, "admin_type": user.AdminType},
+		After:      gin.H{"transfer_to": data.TransferTo},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type unlockLoginData struct {
+	Account string `json:"account" binding:"required"`                  // 管理员用户名或学号
+	Scope   string `json:"scope" binding:"omitempty,oneof=admin oauth"` // 默认为管理员登录
+}
+
+// UnlockLogin 解除账号因多次登录失败导致的锁定
+func UnlockLogin(c *gin.Context) {
+	var data unlockLoginData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	if data.Scope == "" {
+		data.Scope = service.LoginScopeAdmin
+	}
+	err = service.UnlockLogin(data.Scope, data.Account)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	zap.L().Info("Login unlocked by admin", zap.String("admin", admin.Username),
+		zap.String("scope", data.Scope), zap.String("account", data.Account))
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditUserUnlock,
+		TargetType: service.AuditTargetUser,
+		TargetID:   data.Account,
+		After:      gin.H{"scope": data.Scope},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// surveySnapshot 获取问卷快照用于审计日志, 失败时只记录日志
+func surveySnapshot(sid int) map[string]any {
+	snapshot, err := service.GetSurveySnapshot(sid)
+	if err != nil {
+		zap.L().Error("Failed to get survey snapshot", zap.Int("survey_id", sid), zap.Error(err))
+		return nil
+	}
+	return snapshot
+}
+
+type getAuditLogsData struct {
+	PageNum    int    `form:"page_num" binding:"required,min=1"`
+	PageSize   int    `form:"page_size" binding:"required,min=1,max=100"`
+	SurveyID   int    `form:"survey_id"`
+	Username   string `form:"username"`
+	Action     string `form:"action"`
+	TargetType string `form:"target_type"`
+	TargetID   string `form:"target_id"`
+	StartTime  string `form:"start_time"`
+	EndTime    string `form:"end_time"`
+}
+
+// GetAuditLogs 查询审计日志, 问卷所有者只能查询自己问卷的日志
+func GetAuditLogs(c *gin.Context) {
+	var data getAuditLogsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if user.AdminType != 2 {
+		if data.SurveyID == 0 {
+			code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限查询全部日志"))
+			return
+		}
+		survey, err := service.GetSurveyByID(data.SurveyID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+			return
+		} else if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		if !checkSurveyPermission(c, user, survey, service.ActionGrant) {
+			return
+		}
+	}
+	filter := dao.AuditFilter{
+		Username:   data.Username,
+		Action:     data.Action,
+		SurveyID:   data.SurveyID,
+		TargetType: data.TargetType,
+		TargetID:   data.TargetID,
+	}
+	if data.StartTime != "" {
+		filter.StartTime, err = time.Parse(time.RFC3339, data.StartTime)
+		if err != nil {
+			code.AbortWithException(c, code.ParamError, err)
+			return
+		}
+	}
+	if data.EndTime != "" {
+		filter.EndTime, err = time.Parse(time.RFC3339, data.EndTime)
+		if err != nil {
+			code.AbortWithException(c, code.ParamError, err)
+			return
+		}
+	}
+	logs, total, err := service.GetAuditLogs(filter, data.PageNum, data.PageSize)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"log_list":       logs,
+		"total_page_num": math.Ceil(float64(*total) / float64(data.PageSize)),
+	})
+}
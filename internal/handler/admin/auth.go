@@ -2,18 +2,48 @@ package admin
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"strconv"
 
+	"QA-System/internal/dao"
 	"QA-System/internal/model"
 	"QA-System/internal/pkg/code"
 	"QA-System/internal/pkg/utils"
 	"QA-System/internal/service"
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type loginData struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
+	Username     string `json:"username" binding:"required"`
+	Password     string `json:"password" binding:"required"`
+	OTP          string `json:"otp"`           // 两步验证码
+	RecoveryCode string `json:"recovery_code"` // 两步验证恢复码
+}
+
+// checkLoginLocked 账号或IP处于锁定中时中止请求
+func checkLoginLocked(c *gin.Context, username string) bool {
+	remain, err := service.CheckLoginLocked(service.LoginScopeAdmin, username, c.ClientIP())
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return true
+	}
+	if remain > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remain.Seconds()))))
+		code.AbortWithException(c, code.LoginLocked, fmt.Errorf("%s登录已锁定, 剩余%s", username, remain))
+		return true
+	}
+	return false
+}
+
+// recordLoginFailure 记录登录失败
+func recordLoginFailure(c *gin.Context, username string) {
+	_, err := service.RecordLoginFailure(service.LoginScopeAdmin, username, c.ClientIP())
+	if err != nil {
+		zap.L().Error("Failed to record login failure", zap.String("username", username), zap.Error(err))
+	}
 }
 
 // Login 登录
@@ -24,20 +54,56 @@ func Login(c *gin.Context) {
 		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
+	if checkLoginLocked(c, data.Username) {
+		return
+	}
 	// 判断密码是否正确
 	user, err := service.GetAdminByUsername(data.Username)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
+			recordLoginFailure(c, data.Username)
 			code.AbortWithException(c, code.UserNotFind, err)
 			return
 		}
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	if user.Password != data.Password {
+	ok, err := service.CheckAdminPassword(user, data.Password)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
+		recordLoginFailure(c, data.Username)
 		code.AbortWithException(c, code.NoThatPasswordOrWrong, errors.New("密码错误"))
 		return
 	}
+	if user.Disabled {
+		code.AbortWithException(c, code.UserDisabled, errors.New(user.Username+"已停用"))
+		return
+	}
+	// 两步验证
+	if user.TOTPEnabled {
+		if data.OTP == "" && data.RecoveryCode == "" {
+			code.AbortWithException(c, code.TwoFactorRequired, errors.New(user.Username+"未提供两步验证码"))
+			return
+		}
+		ok, err := service.VerifySecondFactor(user, data.OTP, data.RecoveryCode)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		if !ok {
+			recordLoginFailure(c, data.Username)
+			code.AbortWithException(c, code.TwoFactorInvalid, errors.New(user.Username+"两步验证码错误"))
+			return
+		}
+	}
+	err = service.ResetLoginFailures(service.LoginScopeAdmin, user.Username)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	// 设置session
 	err = service.SetUserSession(c, user)
 	if err != nil {
@@ -45,13 +111,22 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	utils.JsonSuccessResponse(c, nil)
+	mustEnroll, err := service.IsTwoFactorRequired(user)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"must_change_password": user.MustChangePassword,
+		"must_enroll_2fa":      mustEnroll && !user.TOTPEnabled,
+	})
 }
 
 type registerData struct {
-	Username string `json:"username" binding:"required"`
-	Password string `json:"password" binding:"required"`
-	Key      string `json:"key" binding:"required"`
+	Username   string `json:"username" binding:"required"`
+	Password   string `json:"password" binding:"required"`
+	InviteCode string `json:"invite_code"`
+	Key        string `json:"key"`
 }
 
 // Register 注册
@@ -62,18 +137,30 @@ func Register(c *gin.Context) {
 		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
-	// 判断是否有权限
-	adminKey := service.GetConfigKey()
-	if adminKey != data.Key {
-		code.AbortWithException(c, code.NotSuperAdmin, errors.New(data.Username+"没有权限"))
-		return
-	}
 	// 判断用户是否存在
 	err = service.IsAdminExist(data.Username)
 	if err == nil {
 		code.AbortWithException(c, code.UserExist, errors.New(data.Username+"用户已存在"))
 		return
 	}
+	// 使用邀请码注册
+	if data.InviteCode != "" {
+		err = service.RegisterByInvitation(data.InviteCode, data.Username, data.Password, c.ClientIP())
+		if errors.Is(err, dao.ErrInvitationUnavailable) {
+			code.AbortWithException(c, code.InvitationInvalid, err)
+			return
+		} else if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		utils.JsonSuccessResponse(c, nil)
+		return
+	}
+	// 旧版共享密钥仅用于初始化部署
+	if !service.CheckRegisterKey(data.Key) {
+		code.AbortWithException(c, code.NotSuperAdmin, errors.New(data.Username+"没有权限"))
+		return
+	}
 	// 创建用户
 	err = service.CreateAdmin(model.User{
 		Username:  data.Username,
@@ -108,12 +195,17 @@ func UpdatePassword(c *gin.Context) {
 		return
 	}
 	// 判断旧密码是否正确
-	if user.Password != data.OldPassword {
+	ok, err := service.CheckAdminPassword(user, data.OldPassword)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
 		code.AbortWithException(c, code.NoThatPasswordOrWrong, errors.New("旧密码错误"))
 		return
 	}
 	// 判断新密码是否与旧密码相同
-	if user.Password == data.NewPassword {
+	if data.OldPassword == data.NewPassword {
 		code.AbortWithException(c, code.NewPasswordSame, errors.New("新密码与旧密码相同"))
 		return
 	}
@@ -123,6 +215,18 @@ func UpdatePassword(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	// 修改密码后其他设备需要重新登录
+	err = service.RevokeAdminSessions(user.ID, service.CurrentSessionID(c))
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	user.MustChangePassword = false
+	err = service.SetUserSession(c, user)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	utils.JsonSuccessResponse(c, nil)
 }
 
@@ -154,11 +258,22 @@ func ResetPassword(c *gin.Context) {
 		code.AbortWithException(c, code.UserNotFind, err)
 		return
 	}
-	// 重置密码
-	err = service.UpdateAdminPassword(user.ID, "jhwl")
+	// 重置为一次性密码, 由超级管理员转交给该用户
+	password, err := service.ResetAdminPassword(user.ID)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	utils.JsonSuccessResponse(c, nil)
+	err = service.RevokeAdminSessions(user.ID, "")
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditUserPasswordReset,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username},
+	})
+	utils.JsonSuccessResponse(c, gin.H{"password": password})
 }
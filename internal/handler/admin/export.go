@@ -0,0 +1,150 @@
+package admin
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type exportJobData struct {
+	JobID string `form:"job_id" binding:"required"`
+}
+
+// GetExportStatus 查询导出任务进度
+func GetExportStatus(c *gin.Context) {
+	var data exportJobData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	job, err := service.GetExportJob(data.JobID)
+	if errors.Is(err, redis.Nil) {
+		code.AbortWithException(c, code.ExportJobNotExist, errors.New("导出任务不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 仅允许任务发起者查看
+	if job.UserID != user.ID {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"job_id":    job.ID,
+		"survey_id": job.SurveyID,
+		"status":    job.Status,
+		"progress":  job.Progress,
+		"error":     job.Error,
+		"expire_at": job.ExpireAt,
+	})
+}
+
+// DownloadExportFile 下载导出文件
+func DownloadExportFile(c *gin.Context) {
+	var data exportJobData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	job, err := service.GetExportJob(data.JobID)
+	if errors.Is(err, redis.Nil) {
+		code.AbortWithException(c, code.ExportJobNotExist, errors.New("导出任务不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if job.UserID != user.ID {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return
+	}
+	// 重新校验问卷权限, 防止权限被收回后仍能下载
+	survey, err := service.GetSurveyByID(job.SurveyID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionExport) {
+		return
+	}
+	if job.Status != service.ExportDone {
+		code.AbortWithException(c, code.ExportNotFinished, errors.New("导出任务未完成"))
+		return
+	}
+	if time.Now().After(job.ExpireAt) {
+		code.AbortWithException(c, code.ExportJobNotExist, errors.New("导出文件已过期"))
+		return
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		code.AbortWithException(c, code.ExportJobNotExist, err)
+		return
+	}
+	c.FileAttachment(job.FilePath, job.FileName)
+}
+
+type downloadSurveyFilesData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// DownloadSurveyFiles 打包下载问卷中上传的所有图片和文件
+func DownloadSurveyFiles(c *gin.Context) {
+	var data downloadSurveyFilesData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionExport) {
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditExportFiles,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(survey.ID),
+	})
+	// 边打包边输出, 避免在内存或磁盘中生成完整压缩包
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(survey.Title+"_附件.zip"))
+	err = service.WriteSurveyFilesZip(c.Writer, survey.ID)
+	if err != nil {
+		// 响应已开始输出, 只能记录日志
+		zap.L().Error("Failed to write survey files zip", zap.Int("survey_id", survey.ID), zap.Error(err))
+	}
+}
@@ -0,0 +1,317 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/dustin/go-humanize"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// importSheetData 通过 Excel 题目表导入时, 问卷基本信息由表单字段提供
+type importSheetData struct {
+	Status     int    `form:"status" binding:"omitempty,oneof=1 2"`
+	SurveyType uint   `form:"survey_type"`
+	Title      string `form:"title"`
+	Desc       string `form:"desc"`
+	StartTime  string `form:"start_time"`
+	EndTime    string `form:"end_time"`
+	DailyLimit uint   `form:"day_limit"`
+	SumLimit   uint   `form:"sum_limit"`
+	Verify     bool   `form:"verify"`
+	Challenge  int    `form:"challenge" binding:"oneof=0 1 2"`
+}
+
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// ImportSurvey 从 JSON/YAML 问卷定义文件或 Excel 题目表导入问卷
+func ImportSurvey(c *gin.Context) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if fileHeader.Size > 10*humanize.MiByte {
+		code.AbortWithException(c, code.FileSizeError, errors.New("导入文件过大"))
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	content, err := io.ReadAll(file)
+	if closeErr := file.Close(); closeErr != nil {
+		zap.L().Error("Failed to close file", zap.Error(closeErr))
+	}
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+
+	var data createSurveyData
+	var importErrors []service.ImportError
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	switch ext {
+	case ".json", ".yaml", ".yml":
+		data, importErrors = decodeSurveyDefinition(content, ext)
+	case ".xlsx":
+		var form importSheetData
+		if err := c.ShouldBind(&form); err != nil {
+			code.AbortWithException(c, code.ParamError, err)
+			return
+		}
+		if form.Title == "" {
+			form.Title = strings.TrimSuffix(fileHeader.Filename, filepath.Ext(fileHeader.Filename))
+		}
+		data, importErrors, err = decodeQuestionSheet(content, form)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+	default:
+		code.AbortWithException(c, code.ImportFileTypeError, errors.New("不支持的导入文件类型"+ext))
+		return
+	}
+	if len(importErrors) > 0 {
+		abortWithImportErrors(c, importErrors)
+		return
+	}
+
+	// 与创建问卷使用相同的校验规则
+	if err := binding.Validator.ValidateStruct(&data); err != nil {
+		abortWithImportErrors(c, validationImportErrors(err))
+		return
+	}
+	if _, err := checkCreateSurveyData(data); err != nil {
+		abortWithImportErrors(c, []service.ImportError{{Location: "问卷", Message: err.Error()}})
+		return
+	}
+	ddlTime, startTime, err := parseSurveyTime(data.BaseConfig)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	sid, err := service.CreateSurvey(user.ID, data.QuestionConfig.QuestionList, data.Status, data.SurveyType, data.
+		BaseConfig.DailyLimit, data.BaseConfig.SumLimit, data.BaseConfig.Verify, data.BaseConfig.Challenge, ddlTime,
+		startTime, data.QuestionConfig.Title, data.QuestionConfig.Desc)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditSurveyImport,
+		SurveyID:   sid,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(sid),
+		After:      surveySnapshot(sid),
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// decodeSurveyDefinition 解析 JSON/YAML 问卷定义, 格式与创建问卷的请求体一致
+func decodeSurveyDefinition(content []byte, ext string) (createSurveyData, []service.ImportError) {
+	var data createSurveyData
+	if ext != ".json" {
+		// YAML 先转换为 JSON, 以复用 json 标签
+		var raw any
+		if err := yaml.Unmarshal(content, &raw); err != nil {
+			location := "文件"
+			if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+				location = "第" + m[1] + "行"
+			}
+			return data, []service.ImportError{{Location: location, Message: err.Error()}}
+		}
+		converted, err := json.Marshal(raw)
+		if err != nil {
+			return data, []service.ImportError{{Location: "文件", Message: err.Error()}}
+		}
+		content = converted
+	}
+
+	err := json.Unmarshal(content, &data)
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return data, nil
+	case errors.As(err, &syntaxErr):
+		return data, []service.ImportError{{Location: lineLocation(content, syntaxErr.Offset), Message: err.Error()}}
+	case errors.As(err, &typeErr):
+		location := typeErr.Field
+		if ext == ".json" {
+			location = lineLocation(content, typeErr.Offset) + " " + typeErr.Field
+		}
+		return data, []service.ImportError{{Location: location,
+			Message: fmt.Sprintf("类型应为%s, 实际为%s", typeErr.Type, typeErr.Value)}}
+	default:
+		return data, []service.ImportError{{Location: "文件", Message: err.Error()}}
+	}
+}
+
+// decodeQuestionSheet 解析 Excel 题目表
+func decodeQuestionSheet(content []byte, form importSheetData) (createSurveyData, []service.ImportError, error) {
+	questions, importErrors, err := service.ParseQuestionSheet(bytes.NewReader(content))
+	if err != nil || len(importErrors) > 0 {
+		return createSurveyData{}, importErrors, err
+	}
+	if form.Status == 0 {
+		form.Status = 1
+	}
+	// 未指定时间时默认从现在开始, 持续一周
+	now := time.Now()
+	if form.StartTime == "" {
+		form.StartTime = now.Format(time.RFC3339)
+	}
+	if form.EndTime == "" {
+		form.EndTime = now.Add(7 * 24 * time.Hour).Format(time.RFC3339)
+	}
+	return createSurveyData{
+		Status:     form.Status,
+		SurveyType: form.SurveyType,
+		BaseConfig: dao.BaseConfig{
+			StartTime:  form.StartTime,
+			EndTime:    form.EndTime,
+			DailyLimit: form.DailyLimit,
+			SumLimit:   form.SumLimit,
+			Verify:     form.Verify,
+			Challenge:  form.Challenge,
+		},
+		QuestionConfig: dao.QuestionConfig{
+			Title:        form.Title,
+			Desc:         form.Desc,
+			QuestionList: questions,
+		},
+	}, nil, nil
+}
+
+// lineLocation 将字节偏移量转换为行列位置
+func lineLocation(content []byte, offset int64) string {
+	if offset > int64(len(content)) {
+		offset = int64(len(content))
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return fmt.Sprintf("第%d行第%d列", line, column)
+}
+
+// validationImportErrors 将参数校验错误转换为导入错误
+func validationImportErrors(err error) []service.ImportError {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return []service.ImportError{{Location: "文件", Message: err.Error()}}
+	}
+	importErrors := make([]service.ImportError, 0, len(validationErrs))
+	for _, e := range validationErrs {
+		importErrors = append(importErrors, service.ImportError{
+			Location: strings.TrimPrefix(e.Namespace(), "createSurveyData."),
+			Message:  fmt.Sprintf("不满足校验规则 %s=%s, 实际值为 %v", e.Tag(), e.Param(), e.Value()),
+		})
+	}
+	return importErrors
+}
+
+// abortWithImportErrors 返回导入错误列表
+func abortWithImportErrors(c *gin.Context, importErrors []service.ImportError) {
+	zap.L().Info(code.SurveyImportError.Msg, zap.Any("errors", importErrors), zap.String("ip", c.ClientIP()))
+	utils.JsonResponse(c, http.StatusOK, code.SurveyImportError.Code, code.SurveyImportError.Msg,
+		gin.H{"errors": importErrors})
+	c.Abort()
+}
+
+type importAnswersData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// ImportAnswerSheets 从 Excel/CSV 文件导入历史答卷
+func ImportAnswerSheets(c *gin.Context) {
+	var data importAnswersData
+	err := c.ShouldBind(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if fileHeader.Size > 20*humanize.MiByte {
+		code.AbortWithException(c, code.FileSizeError, errors.New("导入文件过大"))
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if ext != ".csv" && ext != ".xlsx" {
+		code.AbortWithException(c, code.ImportFileTypeError, errors.New("不支持的导入文件类型"+ext))
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionEdit) {
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	defer func(file multipart.File) {
+		err := file.Close()
+		if err != nil {
+			zap.L().Error("Failed to close file", zap.Error(err))
+		}
+	}(file)
+	num, importErrors, err := service.ImportAnswerSheets(survey, file, ext)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if len(importErrors) > 0 {
+		abortWithImportErrors(c, importErrors)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditAnswerSheetImport,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(survey.ID),
+		After:      gin.H{"num": num, "file": fileHeader.Filename},
+	})
+	utils.JsonSuccessResponse(c, gin.H{"num": num})
+}
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type createInvitationData struct {
+	Username  string `json:"username"`                                    // 绑定的用户名, 可选
+	AdminType int    `json:"admin_type" binding:"omitempty,oneof=1 2"`    // 注册后的管理员类型, 默认普通管理员
+	ExpireIn  int    `json:"expire_in" binding:"omitempty,min=1,max=720"` // 有效期 单位: 小时
+}
+
+// CreateInvitation 创建注册邀请码
+func CreateInvitation(c *gin.Context) {
+	var data createInvitationData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	if data.Username != "" && service.IsAdminExist(data.Username) == nil {
+		code.AbortWithException(c, code.UserExist, errors.New(data.Username+"用户已存在"))
+		return
+	}
+	if data.AdminType == 0 {
+		data.AdminType = 1
+	}
+	ttl := service.GetInvitationTTL()
+	if data.ExpireIn != 0 {
+		ttl = time.Duration(data.ExpireIn) * time.Hour
+	}
+	inviteCode, invitation, err := service.CreateInvitation(admin.ID, data.Username, data.AdminType, ttl)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditInvitationCreate,
+		TargetType: service.AuditTargetInvitation,
+		TargetID:   strconv.Itoa(invitation.ID),
+		After:      gin.H{"username": data.Username, "admin_type": data.AdminType, "expire_at": invitation.ExpireAt},
+	})
+	utils.JsonSuccessResponse(c, gin.H{
+		"id":          invitation.ID,
+		"invite_code": inviteCode,
+		"expire_at":   invitation.ExpireAt,
+	})
+}
+
+type getInvitationListData struct {
+	PageNum  int `form:"page_num" binding:"required,min=1"`
+	PageSize int `form:"page_size" binding:"required,min=1,max=100"`
+}
+
+// GetInvitationList 获取邀请码及使用记录
+func GetInvitationList(c *gin.Context) {
+	var data getInvitationListData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	if _, ok := getSuperAdmin(c); !ok {
+		return
+	}
+	list, total, err := service.GetInvitationList(data.PageNum, data.PageSize)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"invitation_list": list,
+		"total_page_num":  math.Ceil(float64(*total) / float64(data.PageSize)),
+	})
+}
+
+type revokeInvitationData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// RevokeInvitation 作废未使用的邀请码
+func RevokeInvitation(c *gin.Context) {
+	var data revokeInvitationData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	err = service.RevokeInvitation(data.ID)
+	if errors.Is(err, dao.ErrInvitationUnavailable) {
+		code.AbortWithException(c, code.InvitationInvalid, err)
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditInvitationRevoke,
+		TargetType: service.AuditTargetInvitation,
+		TargetID:   strconv.Itoa(data.ID),
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
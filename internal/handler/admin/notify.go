@@ -0,0 +1,118 @@
+package admin
+
+import (
+	"errors"
+	"strconv"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// getNotifySurvey 获取问卷并校验查看权限, 能查看答卷的管理员均可订阅邮件通知
+func getNotifySurvey(c *gin.Context, user *model.User, sid int) (*model.Survey, bool) {
+	survey, err := service.GetSurveyByID(sid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionView) {
+		return nil, false
+	}
+	return survey, true
+}
+
+type getNotificationData struct {
+	SurveyID int `form:"survey_id" binding:"required"`
+}
+
+// GetNotification 获取当前用户对问卷的邮件通知设置
+func GetNotification(c *gin.Context) {
+	var data getNotificationData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, ok := getNotifySurvey(c, user, data.SurveyID)
+	if !ok {
+		return
+	}
+	notification, err := service.GetNotification(survey.ID, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		notification = nil
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"notification": notification,
+		"mail_enabled": service.MailEnabled(),
+		"digest_hour":  service.GetDigestHour(),
+	})
+}
+
+type updateNotificationData struct {
+	SurveyID int    `json:"survey_id" binding:"required"`
+	Email    string `json:"email" binding:"required_unless=Mode 0,omitempty,email,max=255"`
+	Mode     int    `json:"mode" binding:"oneof=0 1 2"` // 通知方式 0不通知 1每份答卷 2每日汇总
+}
+
+// UpdateNotification 修改当前用户对问卷的邮件通知设置
+func UpdateNotification(c *gin.Context) {
+	var data updateNotificationData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, ok := getNotifySurvey(c, user, data.SurveyID)
+	if !ok {
+		return
+	}
+	before, err := service.GetNotification(survey.ID, user.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		before = nil
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	notification, err := service.SaveNotification(survey.ID, user.ID, data.Email, data.Mode)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditNotificationUpdate,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetNotify,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     notificationAudit(before),
+		After:      notificationAudit(notification),
+	})
+	utils.JsonSuccessResponse(c, gin.H{"notification": notification})
+}
+
+// notificationAudit 审计日志中记录的订阅设置, 未订阅时为空
+func notificationAudit(notification *model.Notification) any {
+	if notification == nil {
+		return nil
+	}
+	return gin.H{"email": notification.Email, "mode": notification.Mode}
+}
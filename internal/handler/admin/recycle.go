@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"errors"
+	"strconv"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/gorm"
+)
+
+// GetRecycleSurveys 获取回收站中的问卷, 超级管理员可查看全部问卷
+func GetRecycleSurveys(c *gin.Context) {
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	uid := user.ID
+	if user.AdminType == 2 {
+		uid = 0
+	}
+	surveys, err := service.GetDeletedSurveys(uid)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"survey_list": surveys,
+		"retention":   service.GetRecycleRetention().String(),
+	})
+}
+
+type recycleSurveyData struct {
+	ID int `json:"id" form:"id" binding:"required"`
+}
+
+// getDeletedSurvey 获取回收站中的问卷并校验删除权限
+func getDeletedSurvey(c *gin.Context, user *model.User, id int) (*model.Survey, bool) {
+	survey, err := service.GetDeletedSurveyByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("回收站中不存在该问卷"))
+		return nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionDelete) {
+		return nil, false
+	}
+	return survey, true
+}
+
+// RestoreSurvey 从回收站恢复问卷
+func RestoreSurvey(c *gin.Context) {
+	var data recycleSurveyData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if _, ok := getDeletedSurvey(c, user, data.ID); !ok {
+		return
+	}
+	err = service.RestoreSurvey(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditSurveyRestore,
+		SurveyID:   data.ID,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(data.ID),
+		After:      surveySnapshot(data.ID),
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// PurgeSurvey 彻底删除回收站中的问卷, 不可恢复
+func PurgeSurvey(c *gin.Context) {
+	var data recycleSurveyData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, ok := getDeletedSurvey(c, user, data.ID)
+	if !ok {
+		return
+	}
+	err = service.PurgeSurvey(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditSurveyPurge,
+		SurveyID:   data.ID,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(data.ID),
+		Before:     survey,
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type getRecycleAnswerSheetsData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// GetRecycleAnswerSheets 获取问卷在回收站中的答卷
+func GetRecycleAnswerSheets(c *gin.Context) {
+	var data getRecycleAnswerSheetsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionDelete) {
+		return
+	}
+	answerSheets, err := service.GetDeletedAnswerSheets(data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"answer_sheets": answerSheets,
+		"retention":     service.GetRecycleRetention().String(),
+	})
+}
+
+type restoreAnswerSheetData struct {
+	AnswerID string `json:"answer_id" binding:"required"`
+}
+
+// RestoreAnswerSheet 从回收站恢复答卷
+func RestoreAnswerSheet(c *gin.Context) {
+	var data restoreAnswerSheetData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(data.AnswerID)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	answerSheet, err := service.GetDeletedAnswerSheetByAnswerID(objectID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		code.AbortWithException(c, code.AnswerSheetNotExist, errors.New("回收站中不存在该答卷"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 问卷本身在回收站中时需先恢复问卷
+	survey, err := service.GetSurveyByID(answerSheet.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionDelete) {
+		return
+	}
+	err = service.RestoreAnswerSheet(objectID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	answerSheet.DeletedAt = nil
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditAnswerSheetRestore,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetAnswerSheet,
+		TargetID:   objectID.Hex(),
+		After:      answerSheet,
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
@@ -3,7 +3,9 @@ package admin
 import (
 	"errors"
 	"fmt"
+	"strconv"
 
+	"QA-System/internal/model"
 	"QA-System/internal/pkg/code"
 	"QA-System/internal/pkg/utils"
 	"QA-System/internal/service"
@@ -11,9 +13,24 @@ import (
 	"gorm.io/gorm"
 )
 
+// checkSurveyPermission 检查用户能否对问卷执行指定操作, 无权限时中止请求
+func checkSurveyPermission(c *gin.Context, user *model.User, survey *model.Survey, action service.SurveyAction) bool {
+	ok, err := service.HasSurveyPermission(user, survey, action)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return false
+	}
+	if !ok {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+		return false
+	}
+	return true
+}
+
 type createPermissionData struct {
 	UserName string `json:"username" binding:"required"`
 	SurveyID int    `json:"survey_id" binding:"required"`
+	Role     int    `json:"role" binding:"omitempty,oneof=1 2 3 4"`
 }
 
 // CreatePermission 创建权限
@@ -24,16 +41,16 @@ func CreatePermission(c *gin.Context) {
 		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
+	// 未指定角色时与旧版行为一致, 默认为编辑者
+	if data.Role == 0 {
+		data.Role = model.RoleEditor
+	}
 	// 鉴权
 	admin, err := service.GetUserSession(c)
 	if err != nil {
 		code.AbortWithException(c, code.NotLogin, err)
 		return
 	}
-	if admin.AdminType != 2 {
-		code.AbortWithException(c, code.NoPermission, errors.New(admin.Username+"没有权限"))
-		return
-	}
 	user, err := service.GetUserByName(data.UserName)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
@@ -44,6 +61,9 @@ func CreatePermission(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	if !checkSurveyPermission(c, admin, survey, service.ActionGrant) {
+		return
+	}
 	if survey.UserID == user.ID {
 		code.AbortWithException(c, code.PermissionBelong, errors.New("不能给问卷所有者添加权限"))
 		return
@@ -58,14 +78,147 @@ func CreatePermission(c *gin.Context) {
 		return
 	}
 	// 创建权限
-	err = service.CreatePermission(user.ID, data.SurveyID)
+	err = service.CreatePermission(user.ID, data.SurveyID, data.Role)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditPermissionCreate,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		After:      gin.H{"username": user.Username, "role": data.Role},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type updatePermissionData struct {
+	UserName string `json:"username" binding:"required"`
+	SurveyID int    `json:"survey_id" binding:"required"`
+	Role     int    `json:"role" binding:"required,oneof=1 2 3 4"`
+}
+
+// UpdatePermission 修改协作者角色
+func UpdatePermission(c *gin.Context) {
+	var data updatePermissionData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	admin, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	user, err := service.GetUserByName(data.UserName)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.SurveyID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, admin, survey, service.ActionGrant) {
+		return
+	}
+	if survey.UserID == user.ID {
+		code.AbortWithException(c, code.PermissionBelong, errors.New("不能修改问卷所有者的权限"))
+		return
+	}
+	manage, err := service.GetPermission(user.ID, data.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.PermissionNotExist, errors.New(user.Username+"权限不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	err = service.UpdatePermission(user.ID, data.SurveyID, data.Role)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditPermissionUpdate,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username, "role": manage.Role},
+		After:      gin.H{"username": user.Username, "role": data.Role},
+	})
 	utils.JsonSuccessResponse(c, nil)
 }
 
+type getPermissionsData struct {
+	SurveyID int `form:"survey_id" binding:"required"`
+}
+
+type permissionResp struct {
+	UserID   int    `json:"user_id"`
+	Username string `json:"username"`
+	Role     int    `json:"role"`
+}
+
+// GetPermissions 获取问卷的协作者列表
+func GetPermissions(c *gin.Context) {
+	var data getPermissionsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 鉴权
+	admin, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, admin, survey, service.ActionView) {
+		return
+	}
+	manages, err := service.GetSurveyManagers(survey.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	role, err := service.GetSurveyRole(admin, survey)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	list := make([]permissionResp, 0, len(manages))
+	for _, manage := range manages {
+		user, err := service.GetAdminByID(manage.UserID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		} else if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		if manage.Role == 0 {
+			manage.Role = model.RoleEditor
+		}
+		list = append(list, permissionResp{UserID: user.ID, Username: user.Username, Role: manage.Role})
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"role":     role,
+		"managers": list,
+	})
+}
+
 type deletePermissionData struct {
 	UserName string `form:"username" binding:"required"`
 	SurveyID int    `form:"survey_id" binding:"required"`
@@ -85,10 +238,6 @@ func DeletePermission(c *gin.Context) {
 		code.AbortWithException(c, code.NotLogin, err)
 		return
 	}
-	if admin.AdminType != 2 {
-		code.AbortWithException(c, code.NoPermission, errors.New(admin.Username+"没有权限"))
-		return
-	}
 	user, err := service.GetUserByName(data.UserName)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
@@ -99,6 +248,9 @@ func DeletePermission(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	if !checkSurveyPermission(c, admin, survey, service.ActionGrant) {
+		return
+	}
 	if survey.UserID == user.ID {
 		code.AbortWithException(c, code.PermissionBelong, errors.New("不能删除问卷所有者的权限"))
 		return
@@ -115,5 +267,12 @@ func DeletePermission(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditPermissionDelete,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username},
+	})
 	utils.JsonSuccessResponse(c, nil)
 }
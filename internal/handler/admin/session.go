@@ -0,0 +1,119 @@
+package admin
+
+import (
+	"errors"
+	"strconv"
+
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Logout 退出登录
+func Logout(c *gin.Context) {
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		// 会话已失效时视为已退出
+		utils.JsonSuccessResponse(c, nil)
+		return
+	}
+	_, err = service.RevokeAdminSession(user.ID, service.CurrentSessionID(c))
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	err = service.ClearUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// GetSessions 获取当前用户的登录会话
+func GetSessions(c *gin.Context) {
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	list, err := service.ListAdminSessions(user.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	current := service.CurrentSessionID(c)
+	for i := range list {
+		list[i].Current = list[i].ID == current
+	}
+	utils.JsonSuccessResponse(c, gin.H{"session_list": list})
+}
+
+type revokeSessionData struct {
+	ID string `form:"id" binding:"required"`
+}
+
+// RevokeSession 注销当前用户的指定会话
+func RevokeSession(c *gin.Context) {
+	var data revokeSessionData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	ok, err := service.RevokeAdminSession(user.ID, data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
+		code.AbortWithException(c, code.ParamError, errors.New("会话不存在"))
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type revokeUserSessionsData struct {
+	UserName string `form:"username" binding:"required"`
+}
+
+// RevokeUserSessions 超级管理员注销指定用户的全部会话
+func RevokeUserSessions(c *gin.Context) {
+	var data revokeUserSessionsData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	user, err := service.GetAdminByUsername(data.UserName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.UserNotFind, err)
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	err = service.RevokeAdminSessions(user.ID, "")
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditUserSessionsRevoke,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
@@ -9,11 +9,14 @@ import (
 	"time"
 
 	"QA-System/internal/dao"
+	q "QA-System/internal/handler/queue"
 	"QA-System/internal/model"
 	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/queue"
 	"QA-System/internal/pkg/utils"
 	"QA-System/internal/service"
 	"github.com/gin-gonic/gin"
+	"github.com/hibiken/asynq"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"gorm.io/gorm"
@@ -40,41 +43,72 @@ func CreateSurvey(c *gin.Context) {
 		code.AbortWithException(c, code.NotLogin, err)
 		return
 	}
-	// 解析时间转换为中国时间(UTC+8)
-	ddlTime, err := time.Parse(time.RFC3339, data.BaseConfig.EndTime)
+	if apiErr, err := checkCreateSurveyData(data); err != nil {
+		code.AbortWithException(c, apiErr, err)
+		return
+	}
+	ddlTime, startTime, err := parseSurveyTime(data.BaseConfig)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	startTime, err := time.Parse(time.RFC3339, data.BaseConfig.StartTime)
+	// 创建问卷
+	sid, err := service.CreateSurvey(user.ID, data.QuestionConfig.QuestionList, data.Status, data.SurveyType, data.
+		BaseConfig.DailyLimit, data.BaseConfig.SumLimit, data.BaseConfig.Verify, data.BaseConfig.Challenge, ddlTime,
+		startTime, data.QuestionConfig.Title, data.QuestionConfig.Desc)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditSurveyCreate,
+		SurveyID:   sid,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(sid),
+		After:      surveySnapshot(sid),
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+// parseSurveyTime 解析问卷的截止时间和开始时间
+func parseSurveyTime(config dao.BaseConfig) (time.Time, time.Time, error) {
+	// 解析时间转换为中国时间(UTC+8)
+	ddlTime, err := time.Parse(time.RFC3339, config.EndTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	startTime, err := time.Parse(time.RFC3339, config.StartTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return ddlTime, startTime, nil
+}
+
+// checkCreateSurveyData 校验创建问卷的数据
+func checkCreateSurveyData(data createSurveyData) (*code.Error, error) {
+	ddlTime, startTime, err := parseSurveyTime(data.BaseConfig)
+	if err != nil {
+		return code.ServerError, err
+	}
 	if startTime.After(ddlTime) {
-		code.AbortWithException(c, code.SurveyError, errors.New("开始时间晚于截止时间"))
-		return
+		return code.SurveyError, errors.New("开始时间晚于截止时间")
 	}
 	// 检查总投票次数大于日投票数
 	if data.BaseConfig.SumLimit != 0 && data.BaseConfig.DailyLimit != 0 &&
 		data.BaseConfig.SumLimit < data.BaseConfig.DailyLimit {
-		code.AbortWithException(c, code.SurveyError, errors.New("总投票次数小于单日投票次数"))
-		return
+		return code.SurveyError, errors.New("总投票次数小于单日投票次数")
 	}
 	// 检查问卷每个题目的序号没有重复且按照顺序递增
 	questionNumMap := make(map[int]bool)
 	for i, question := range data.QuestionConfig.QuestionList {
 		if data.SurveyType == 2 && (question.QuestionSetting.QuestionType != 2 && !question.QuestionSetting.Required) {
-			code.AbortWithException(c, code.SurveyError, errors.New("投票题目只能为多选必填题"))
-			return
+			return code.SurveyError, errors.New("投票题目只能为多选必填题")
 		}
 		if questionNumMap[question.SerialNum] {
-			code.AbortWithException(c, code.SurveyError, errors.New("题目序号"+strconv.Itoa(question.SerialNum)+"重复"))
-			return
+			return code.SurveyError, errors.New("题目序号" + strconv.Itoa(question.SerialNum) + "重复")
 		}
 		if i > 0 && question.SerialNum != data.QuestionConfig.QuestionList[i-1].SerialNum+1 {
-			code.AbortWithException(c, code.SurveyError, errors.New("题目序号不按顺序递增"))
-			return
+			return code.SurveyError, errors.New("题目序号不按顺序递增")
 		}
 		questionNumMap[question.SerialNum] = true
 		question.SerialNum = i + 1
@@ -83,75 +117,54 @@ func CreateSurvey(c *gin.Context) {
 		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
 			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
 			(question.QuestionSetting.MaximumOption < question.QuestionSetting.MinimumOption) {
-			code.AbortWithException(c, code.OptionNumError, errors.New("多选最多选项数小于最少选项数"))
-			return
+			return code.OptionNumError, errors.New("多选最多选项数小于最少选项数")
 		}
 		// 检查多选选项和最少选项数是否符合要求
 		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
 			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
 			uint(len(question.Options)) < question.QuestionSetting.MinimumOption {
-			code.AbortWithException(c, code.OptionNumError, errors.New("选项数量小于最少选项数"))
-			return
+			return code.OptionNumError, errors.New("选项数量小于最少选项数")
 		}
 		// 检查最多选项数是否符合要求
 		if ((question.QuestionSetting.QuestionType == 2 && data.SurveyType == 0) ||
 			(question.QuestionSetting.QuestionType == 1 && data.SurveyType == 1)) &&
 			question.QuestionSetting.MaximumOption == 0 {
-			code.AbortWithException(c, code.OptionNumError, errors.New("最多选项数小于等于0"))
-			return
+			return code.OptionNumError, errors.New("最多选项数小于等于0")
 		}
 	}
 	// 检测问卷是否填写完整
 	if data.Status == 2 {
 		if data.QuestionConfig.Title == "" || len(data.QuestionConfig.QuestionList) == 0 {
-			code.AbortWithException(c, code.SurveyIncomplete, errors.New("问卷标题为空或问卷没有问题"))
-			return
+			return code.SurveyIncomplete, errors.New("问卷标题为空或问卷没有问题")
 		}
 		questionMap := make(map[string]bool)
 		for _, question := range data.QuestionConfig.QuestionList {
 			if question.Subject == "" {
-				code.AbortWithException(c, code.SurveyIncomplete,
-					errors.New("问题"+strconv.Itoa(question.SerialNum)+"标题为空"))
-				return
+				return code.SurveyIncomplete, errors.New("问题" + strconv.Itoa(question.SerialNum) + "标题为空")
 			}
 			if questionMap[question.Subject] {
-				code.AbortWithException(c, code.SurveyContentRepeat,
-					errors.New("问题"+strconv.Itoa(question.SerialNum)+"题目"+question.Subject+"重复"))
-				return
+				return code.SurveyContentRepeat,
+					errors.New("问题" + strconv.Itoa(question.SerialNum) + "题目" + question.Subject + "重复")
 			}
 			questionMap[question.Subject] = true
 			if question.QuestionSetting.QuestionType == 1 || question.QuestionSetting.QuestionType == 2 {
 				if len(question.Options) < 1 {
-					code.AbortWithException(c, code.SurveyIncomplete,
-						errors.New("问题"+strconv.Itoa(question.SerialNum)+"选项数量太少"))
-					return
+					return code.SurveyIncomplete, errors.New("问题" + strconv.Itoa(question.SerialNum) + "选项数量太少")
 				}
 				optionMap := make(map[string]bool)
 				for _, option := range question.Options {
 					if option.Content == "" {
-						code.AbortWithException(c, code.SurveyIncomplete,
-							errors.New("选项"+strconv.Itoa(option.SerialNum)+"内容为空"))
-						return
+						return code.SurveyIncomplete, errors.New("选项" + strconv.Itoa(option.SerialNum) + "内容为空")
 					}
 					if optionMap[option.Content] {
-						code.AbortWithException(c, code.SurveyContentRepeat,
-							errors.New("选项内容"+option.Content+"重复"))
-						return
+						return code.SurveyContentRepeat, errors.New("选项内容" + option.Content + "重复")
 					}
 					optionMap[option.Content] = true
 				}
 			}
 		}
 	}
-	// 创建问卷
-	err = service.CreateSurvey(user.ID, data.QuestionConfig.QuestionList, data.Status, data.SurveyType, data.BaseConfig.
-		DailyLimit, data.BaseConfig.SumLimit, data.BaseConfig.Verify, ddlTime, startTime, data.QuestionConfig.Title,
-		data.QuestionConfig.Desc)
-	if err != nil {
-		code.AbortWithException(c, code.ServerError, err)
-		return
-	}
-	utils.JsonSuccessResponse(c, nil)
+	return nil, nil
 }
 
 type updateSurveyStatusData struct {
@@ -180,9 +193,7 @@ func UpdateSurveyStatus(c *gin.Context) {
 		return
 	}
 	// 判断权限
-	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
-		!service.UserInManage(user.ID, survey.ID) {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionEdit) {
 		return
 	}
 	// 判断问卷状态
@@ -251,6 +262,20 @@ func UpdateSurveyStatus(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	switch data.Status {
+	case 2:
+		q.DispatchWebhooks(survey.ID, service.EventSurveyPublished, service.SurveyEventData(survey, data.Status))
+	case 3:
+		q.DispatchWebhooks(survey.ID, service.EventSurveyClosed, service.SurveyEventData(survey, data.Status))
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditSurveyStatus,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(survey.ID),
+		Before:     gin.H{"status": survey.Status},
+		After:      gin.H{"status": data.Status},
+	})
 	utils.JsonSuccessResponse(c, nil)
 }
 
@@ -282,9 +307,7 @@ func UpdateSurvey(c *gin.Context) {
 		return
 	}
 	// 判断权限
-	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
-		!service.UserInManage(user.ID, survey.ID) {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionEdit) {
 		return
 	}
 	// 判断问卷状态
@@ -356,13 +379,22 @@ func UpdateSurvey(c *gin.Context) {
 		}
 	}
 	// 修改问卷
+	before := surveySnapshot(data.ID)
 	err = service.UpdateSurvey(data.ID, data.QuestionConfig.QuestionList, data.SurveyType, data.BaseConfig.DailyLimit,
-		data.BaseConfig.SumLimit, data.BaseConfig.Verify, data.QuestionConfig.Desc, data.QuestionConfig.Title, ddlTime,
-		startTime)
+		data.BaseConfig.SumLimit, data.BaseConfig.Verify, data.BaseConfig.Challenge, data.QuestionConfig.Desc,
+		data.QuestionConfig.Title, ddlTime, startTime)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditSurveyUpdate,
+		SurveyID:   data.ID,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(data.ID),
+		Before:     before,
+		After:      surveySnapshot(data.ID),
+	})
 	utils.JsonSuccessResponse(c, nil)
 }
 
@@ -394,22 +426,23 @@ func DeleteSurvey(c *gin.Context) {
 		return
 	}
 	// 判断权限
-	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
-		!service.UserInManage(user.ID, survey.ID) {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionDelete) {
 		return
 	}
-	// 删除问卷
+	// 移入回收站
+	before := surveySnapshot(data.ID)
 	err = service.DeleteSurvey(data.ID)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	err = service.DeleteOauthRecord(data.ID)
-	if err != nil {
-		code.AbortWithException(c, code.ServerError, err)
-		return
-	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditSurveyDelete,
+		SurveyID:   data.ID,
+		TargetType: service.AuditTargetSurvey,
+		TargetID:   strconv.Itoa(data.ID),
+		Before:     before,
+	})
 	utils.JsonSuccessResponse(c, nil)
 }
 
@@ -445,9 +478,7 @@ func GetSurveyAnswers(c *gin.Context) {
 		return
 	}
 	// 判断权限
-	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
-		!service.UserInManage(user.ID, survey.ID) {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionView) {
 		return
 	}
 	// 获取问卷收集数据
@@ -509,7 +540,10 @@ func GetAllSurvey(c *gin.Context) {
 		}
 		for _, manage := range managedSurveys {
 			managedSurvey, err := service.GetSurveyByID(manage.SurveyID)
-			if err != nil {
+			// 已移入回收站的问卷不再展示
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			} else if err != nil {
 				code.AbortWithException(c, code.ServerError, err)
 				return
 			}
@@ -550,9 +584,7 @@ func GetSurvey(c *gin.Context) {
 		return
 	}
 	// 判断权限
-	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
-		!service.UserInManage(user.ID, survey.ID) {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionView) {
 		return
 	}
 	// 获取相应的问题
@@ -576,6 +608,7 @@ func GetSurvey(c *gin.Context) {
 				"serial_num":  option.SerialNum,
 				"content":     option.Content,
 				"img":         option.Img,
+				"thumb":       service.ThumbURL(option.Img),
 				"description": option.Description,
 			}
 			optionsResponse = append(optionsResponse, optionResponse)
@@ -589,6 +622,11 @@ func GetSurvey(c *gin.Context) {
 			"reg":            question.Reg,
 			"maximum_option": question.MaximumOption,
 			"minimum_option": question.MinimumOption,
+			"file_types":     question.FileTypes,
+			"max_file_size":  question.MaxFileSize,
+			"max_file_count": question.MaxFileCount,
+			"upload_quota":   question.UploadQuota,
+			"send_copy":      question.SendCopy,
 		}
 
 		questionListMap := map[string]any{
@@ -614,6 +652,7 @@ func GetSurvey(c *gin.Context) {
 		"day_limit":  survey.DailyLimit,
 		"sum_limit":  survey.SumLimit,
 		"verify":     survey.Verify,
+		"challenge":  survey.Challenge,
 	}
 	response := map[string]any{
 		"id":          survey.ID,
@@ -650,23 +689,32 @@ func DownloadFile(c *gin.Context) {
 		return
 	}
 	// 判断权限
-	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
-		!service.UserInManage(user.ID, survey.ID) {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionExport) {
+		return
+	}
+	// 创建导出任务, 由后台异步生成文件
+	job, err := service.CreateExportJob(user.ID, survey.ID, survey.Title+".xlsx")
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	// 获取数据
-	answers, err := service.GetAllSurveyAnswers(data.ID)
+	task, err := q.NewExportSurveyTask(job.ID)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	url, err := service.HandleDownloadFile(answers, survey)
+	_, err = queue.Client.Enqueue(task, asynq.MaxRetry(2), asynq.Timeout(30*time.Minute))
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-	utils.JsonSuccessResponse(c, url)
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditExportCreate,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetExport,
+		TargetID:   job.ID,
+	})
+	utils.JsonSuccessResponse(c, gin.H{"job_id": job.ID})
 }
 
 type getSurveyStatisticsData struct {
@@ -708,9 +756,7 @@ func GetSurveyStatistics(c *gin.Context) {
 		return
 	}
 
-	if (user.AdminType != 2) && (user.AdminType != 1 || survey.UserID != user.ID) &&
-		!service.UserInManage(user.ID, survey.ID) {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionView) {
 		return
 	}
 
@@ -944,10 +990,11 @@ func DeleteAnswerSheet(c *gin.Context) {
 	// 将 AnswerID 转换为 ObjectID
 	objectID, err := primitive.ObjectIDFromHex(data.AnswerID)
 	if err != nil {
-		code.AbortWithException(c, code.ServerError, err)
+		code.AbortWithException(c, code.ParamError, err)
+		return
 	}
-	// 获取问卷
-	err = service.GetAnswerSheetByAnswerID(objectID)
+	// 获取答卷
+	answerSheet, err := service.GetAnswerSheetByAnswerID(objectID)
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		code.AbortWithException(c, code.AnswerSheetNotExist, errors.New("答卷不存在"))
 		return
@@ -955,16 +1002,31 @@ func DeleteAnswerSheet(c *gin.Context) {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	survey, err := service.GetSurveyByID(answerSheet.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	// 判断权限
-	if user.AdminType != 2 {
-		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"无权限"))
+	if !checkSurveyPermission(c, user, survey, service.ActionDelete) {
 		return
 	}
-	// 删除答卷
+	// 移入回收站
 	err = service.DeleteAnswerSheetByAnswerID(objectID)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	q.DispatchWebhooks(survey.ID, service.EventSheetDeleted, gin.H{"answer_id": objectID.Hex()})
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditAnswerSheetDelete,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetAnswerSheet,
+		TargetID:   objectID.Hex(),
+		Before:     answerSheet,
+	})
 	utils.JsonSuccessResponse(c, nil)
 }
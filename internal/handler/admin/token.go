@@ -0,0 +1,87 @@
+package admin
+
+import (
+	"errors"
+	"time"
+
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type createAPITokenData struct {
+	Name       string   `json:"name" binding:"required,max=64"`
+	Scopes     []string `json:"scopes" binding:"required,min=1,unique,dive,oneof=stats export manage"`
+	ExpireDays int      `json:"expire_days" binding:"omitempty,min=1,max=365"` // 有效期 单位: 天, 为空时长期有效
+}
+
+// CreateAPIToken 创建个人 API 令牌
+func CreateAPIToken(c *gin.Context) {
+	var data createAPITokenData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	ttl := time.Duration(data.ExpireDays) * 24 * time.Hour
+	plain, token, err := service.CreateAPIToken(user.ID, data.Name, data.Scopes, ttl)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"id":        token.ID,
+		"token":     plain,
+		"expire_at": token.ExpireAt,
+	})
+}
+
+// GetAPITokens 获取个人 API 令牌列表
+func GetAPITokens(c *gin.Context) {
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	tokens, err := service.GetAPITokens(user.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"token_list": tokens})
+}
+
+type revokeAPITokenData struct {
+	ID int `form:"id" binding:"required"`
+}
+
+// RevokeAPIToken 吊销个人 API 令牌
+func RevokeAPIToken(c *gin.Context) {
+	var data revokeAPITokenData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	ok, err := service.RevokeAPIToken(user.ID, data.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
+		code.AbortWithException(c, code.APITokenInvalid, errors.New("令牌不存在"))
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
@@ -0,0 +1,278 @@
+package admin
+
+import (
+	"errors"
+	"strconv"
+
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetTOTPStatus 获取两步验证状态
+func GetTOTPStatus(c *gin.Context) {
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	required, err := service.IsTwoFactorRequired(user)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	var remaining int64
+	if user.TOTPEnabled {
+		remaining, err = service.CountRecoveryCodes(user.ID)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"enabled":        user.TOTPEnabled,
+		"required":       required,
+		"recovery_codes": remaining,
+	})
+}
+
+// SetupTOTP 开始绑定两步验证, 返回供认证器应用扫码的地址
+func SetupTOTP(c *gin.Context) {
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if user.TOTPEnabled {
+		code.AbortWithException(c, code.ParamError, errors.New(user.Username+"已开启两步验证"))
+		return
+	}
+	secret, uri, err := service.BeginTOTPEnrollment(user)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"secret": secret,
+		"uri":    uri,
+	})
+}
+
+type enableTOTPData struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+// EnableTOTP 验证验证码并开启两步验证, 返回一次性展示的恢复码
+func EnableTOTP(c *gin.Context) {
+	var data enableTOTPData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	recoveryCodes, ok, err := service.EnableTOTP(user, data.OTP)
+	if errors.Is(err, service.ErrTOTPNotPending) {
+		code.AbortWithException(c, code.TwoFactorNotPending, err)
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
+		code.AbortWithException(c, code.TwoFactorInvalid, errors.New(user.Username+"两步验证码错误"))
+		return
+	}
+	// 刷新会话中的两步验证状态
+	user.TOTPEnabled = true
+	err = service.SetUserSession(c, user)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"recovery_codes": recoveryCodes})
+}
+
+type disableTOTPData struct {
+	Password string `json:"password" binding:"required"`
+	OTP      string `json:"otp" binding:"required"`
+}
+
+// DisableTOTP 关闭两步验证
+func DisableTOTP(c *gin.Context) {
+	var data disableTOTPData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if !user.TOTPEnabled {
+		utils.JsonSuccessResponse(c, nil)
+		return
+	}
+	required, err := service.IsTwoFactorRequired(user)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if required {
+		code.AbortWithException(c, code.NoPermission, errors.New(user.Username+"必须开启两步验证"))
+		return
+	}
+	ok, err := service.CheckAdminPassword(user, data.Password)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
+		code.AbortWithException(c, code.NoThatPasswordOrWrong, errors.New("密码错误"))
+		return
+	}
+	ok, err = service.VerifySecondFactor(user, data.OTP, "")
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
+		code.AbortWithException(c, code.TwoFactorInvalid, errors.New(user.Username+"两步验证码错误"))
+		return
+	}
+	err = service.DisableTOTP(user.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type regenerateRecoveryCodesData struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码, 旧的恢复码全部失效
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var data regenerateRecoveryCodesData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	if !user.TOTPEnabled {
+		code.AbortWithException(c, code.TwoFactorEnrollRequired, errors.New(user.Username+"未开启两步验证"))
+		return
+	}
+	ok, err := service.VerifySecondFactor(user, data.OTP, "")
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !ok {
+		code.AbortWithException(c, code.TwoFactorInvalid, errors.New(user.Username+"两步验证码错误"))
+		return
+	}
+	recoveryCodes, err := service.RegenerateRecoveryCodes(user.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{"recovery_codes": recoveryCodes})
+}
+
+type resetTOTPData struct {
+	UserName string `json:"username" binding:"required"`
+}
+
+// ResetTOTP 超级管理员为丢失认证设备的管理员关闭两步验证
+func ResetTOTP(c *gin.Context) {
+	var data resetTOTPData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	user, err := service.GetAdminByUsername(data.UserName)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.UserNotFind, err)
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if user.ID == admin.ID {
+		code.AbortWithException(c, code.OperateSelfError, errors.New(admin.Username+"不能重置自己的两步验证"))
+		return
+	}
+	err = service.DisableTOTP(user.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditUserTOTPReset,
+		TargetType: service.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Before:     gin.H{"username": user.Username, "totp_enabled": user.TOTPEnabled},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type updateTwoFactorPolicyData struct {
+	RequireSuperAdmin bool `json:"require_super_admin"`
+}
+
+// UpdateTwoFactorPolicy 设置是否要求超级管理员开启两步验证
+func UpdateTwoFactorPolicy(c *gin.Context) {
+	var data updateTwoFactorPolicyData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	admin, ok := getSuperAdmin(c)
+	if !ok {
+		return
+	}
+	// 避免开启策略后把自己锁在外面
+	if data.RequireSuperAdmin && !admin.TOTPEnabled {
+		code.AbortWithException(c, code.TwoFactorEnrollRequired, errors.New(admin.Username+"未开启两步验证"))
+		return
+	}
+	required, err := service.GetTwoFactorPolicy()
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	err = service.SetTwoFactorPolicy(data.RequireSuperAdmin)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, admin, service.AuditEntry{
+		Action:     service.AuditSetting2FAPolicy,
+		TargetType: service.AuditTargetSetting,
+		TargetID:   service.SettingRequireSuperAdmin2FA,
+		Before:     gin.H{"require_super_admin": required},
+		After:      gin.H{"require_super_admin": data.RequireSuperAdmin},
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
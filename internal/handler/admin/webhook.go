@@ -0,0 +1,304 @@
+package admin
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	q "QA-System/internal/handler/queue"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// getWebhook 获取 Webhook 并校验问卷的 Webhook 管理权限
+func getWebhook(c *gin.Context, user *model.User, id int) (*model.Webhook, bool) {
+	webhook, err := service.GetWebhookByID(id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.WebhookNotExist, errors.New("Webhook 不存在"))
+		return nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	survey, err := service.GetSurveyByID(webhook.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return nil, false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, false
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionWebhook) {
+		return nil, false
+	}
+	return webhook, true
+}
+
+type createWebhookData struct {
+	SurveyID int      `json:"survey_id" binding:"required"`
+	URL      string   `json:"url" binding:"required,max=512"`
+	Events   []string `json:"events" binding:"required,min=1,unique"`
+	Quota    int      `json:"quota" binding:"min=0"` // 答卷数达到该值时推送 quota.reached
+}
+
+// CreateWebhook 创建 Webhook, 签名密钥只在创建时返回
+func CreateWebhook(c *gin.Context) {
+	var data createWebhookData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionWebhook) {
+		return
+	}
+	if err := service.CheckWebhookURL(data.URL); err != nil {
+		code.AbortWithException(c, code.WebhookURLError, err)
+		return
+	}
+	if err := service.CheckWebhookEvents(data.Events); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	webhook, secret, err := service.CreateWebhook(survey.ID, user.ID, data.URL, data.Events, data.Quota)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditWebhookCreate,
+		SurveyID:   survey.ID,
+		TargetType: service.AuditTargetWebhook,
+		TargetID:   strconv.Itoa(webhook.ID),
+		After:      webhook,
+	})
+	utils.JsonSuccessResponse(c, gin.H{
+		"webhook": webhook,
+		"secret":  secret,
+	})
+}
+
+type getWebhooksData struct {
+	SurveyID int `form:"survey_id" binding:"required"`
+}
+
+// GetWebhooks 获取问卷的 Webhook 列表
+func GetWebhooks(c *gin.Context) {
+	var data getWebhooksData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	survey, err := service.GetSurveyByID(data.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !checkSurveyPermission(c, user, survey, service.ActionWebhook) {
+		return
+	}
+	webhooks, err := service.GetWebhooks(survey.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"webhook_list": webhooks,
+		"events":       service.WebhookEvents,
+	})
+}
+
+type updateWebhookData struct {
+	ID           int      `json:"id" binding:"required"`
+	URL          string   `json:"url" binding:"required,max=512"`
+	Events       []string `json:"events" binding:"required,min=1,unique"`
+	Quota        int      `json:"quota" binding:"min=0"`
+	Enabled      bool     `json:"enabled"`
+	RotateSecret bool     `json:"rotate_secret"` // 是否重新生成签名密钥
+}
+
+// UpdateWebhook 修改 Webhook
+func UpdateWebhook(c *gin.Context) {
+	var data updateWebhookData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	webhook, ok := getWebhook(c, user, data.ID)
+	if !ok {
+		return
+	}
+	if err := service.CheckWebhookURL(data.URL); err != nil {
+		code.AbortWithException(c, code.WebhookURLError, err)
+		return
+	}
+	if err := service.CheckWebhookEvents(data.Events); err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	before := *webhook
+	secret, err := service.UpdateWebhook(webhook, data.URL, data.Events, data.Quota, data.Enabled, data.RotateSecret)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditWebhookUpdate,
+		SurveyID:   webhook.SurveyID,
+		TargetType: service.AuditTargetWebhook,
+		TargetID:   strconv.Itoa(webhook.ID),
+		Before:     before,
+		After:      webhook,
+	})
+	resp := gin.H{"webhook": webhook}
+	if secret != "" {
+		resp["secret"] = secret
+	}
+	utils.JsonSuccessResponse(c, resp)
+}
+
+type webhookIDData struct {
+	ID int `json:"id" form:"id" binding:"required"`
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func DeleteWebhook(c *gin.Context) {
+	var data webhookIDData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	webhook, ok := getWebhook(c, user, data.ID)
+	if !ok {
+		return
+	}
+	err = service.DeleteWebhook(webhook.ID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditWebhookDelete,
+		SurveyID:   webhook.SurveyID,
+		TargetType: service.AuditTargetWebhook,
+		TargetID:   strconv.Itoa(webhook.ID),
+		Before:     webhook,
+	})
+	utils.JsonSuccessResponse(c, nil)
+}
+
+type getWebhookDeliveriesData struct {
+	WebhookID int `form:"webhook_id" binding:"required"`
+	PageNum   int `form:"page_num" binding:"required,min=1"`
+	PageSize  int `form:"page_size" binding:"required,min=1,max=100"`
+}
+
+// GetWebhookDeliveries 分页获取 Webhook 的投递记录
+func GetWebhookDeliveries(c *gin.Context) {
+	var data getWebhookDeliveriesData
+	err := c.ShouldBindQuery(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	webhook, ok := getWebhook(c, user, data.WebhookID)
+	if !ok {
+		return
+	}
+	deliveries, total, err := service.GetWebhookDeliveries(webhook.ID, data.PageNum, data.PageSize)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	utils.JsonSuccessResponse(c, gin.H{
+		"delivery_list":  deliveries,
+		"total_page_num": math.Ceil(float64(*total) / float64(data.PageSize)),
+	})
+}
+
+// RedeliverWebhook 以原请求体重新投递
+func RedeliverWebhook(c *gin.Context) {
+	var data webhookIDData
+	err := c.ShouldBindJSON(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	user, err := service.GetUserSession(c)
+	if err != nil {
+		code.AbortWithException(c, code.NotLogin, err)
+		return
+	}
+	delivery, err := service.GetWebhookDeliveryByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.WebhookNotExist, errors.New("投递记录不存在"))
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	webhook, ok := getWebhook(c, user, delivery.WebhookID)
+	if !ok {
+		return
+	}
+	redelivery, err := service.RedeliverWebhook(delivery)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if err := q.EnqueueWebhookDelivery(redelivery); err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	service.RecordAudit(c, user, service.AuditEntry{
+		Action:     service.AuditWebhookRedeliver,
+		SurveyID:   webhook.SurveyID,
+		TargetType: service.AuditTargetWebhook,
+		TargetID:   strconv.Itoa(webhook.ID),
+		After:      gin.H{"delivery_id": delivery.ID, "redelivery_id": redelivery.ID},
+	})
+	utils.JsonSuccessResponse(c, gin.H{"delivery": redelivery})
+}
@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// NewServeMux 注册所有任务处理函数
+func NewServeMux() *asynq.ServeMux {
+	mux := asynq.NewServeMux()
+	mux.HandleFunc(TypeSubmitSurvey, HandleSubmitSurveyTask)
+	mux.HandleFunc(TypeExportSurvey, HandleExportSurveyTask)
+	mux.HandleFunc(TypeCleanExport, HandleCleanExportTask)
+	mux.HandleFunc(TypePurgeRecycle, HandlePurgeRecycleTask)
+	mux.HandleFunc(TypeProcessOutbox, HandleProcessOutboxTask)
+	mux.HandleFunc(TypeCollectUploads, HandleCollectUploadsTask)
+	mux.HandleFunc(TypeDeliverWebhook, HandleDeliverWebhookTask)
+	mux.HandleFunc(TypeNotifySheet, HandleNotifySheetTask)
+	mux.HandleFunc(TypeSendDigest, HandleSendDigestTask)
+	return mux
+}
+
+// RegisterPeriodicTasks 注册周期任务
+// 同一周期任务在队列中只保留一个, 避免多个调度器或任务积压时重复执行
+func RegisterPeriodicTasks(scheduler *asynq.Scheduler) error {
+	tasks := []struct {
+		spec   string
+		task   *asynq.Task
+		unique time.Duration
+	}{
+		{"@every 1h", NewCleanExportTask(), time.Hour},
+		{"@every 6h", NewPurgeRecycleTask(), 6 * time.Hour},
+		{"@every 1h", NewCollectUploadsTask(), time.Hour},
+		{"@every 1h", NewSendDigestTask(), time.Hour},
+		{"@every 1m", NewProcessOutboxTask(), time.Minute},
+	}
+	for _, t := range tasks {
+		if _, err := scheduler.Register(t.spec, t.task, asynq.Unique(t.unique)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"QA-System/internal/dao"
+	"QA-System/internal/pkg/queue"
+	"QA-System/internal/service"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+// notifyMaxRetry 邮件发送失败后的最大重试次数
+const notifyMaxRetry = 5
+
+// DispatchSheetNotifications 为新答卷发送邮件通知与答卷副本, 失败只记录日志而不影响请求
+func DispatchSheetNotifications(sheet *dao.AnswerSheet) {
+	if !service.MailEnabled() {
+		return
+	}
+	answerID := sheet.AnswerID.Hex()
+	notifications, err := service.GetSheetNotifications(sheet.SurveyID)
+	if err != nil {
+		zap.L().Error("Failed to get notifications", zap.Int("survey", sheet.SurveyID), zap.Error(err))
+	}
+	for _, notification := range notifications {
+		task, err := NewNotifySheetTask(answerID, notification.ID)
+		if err == nil {
+			_, err = queue.Client.Enqueue(task, asynq.MaxRetry(notifyMaxRetry))
+		}
+		if err != nil {
+			zap.L().Error("Failed to enqueue notification", zap.Int("notification", notification.ID),
+				zap.Error(err))
+		}
+	}
+
+	email, err := service.SheetCopyAddress(sheet)
+	if err != nil {
+		zap.L().Error("Failed to get copy address", zap.String("answer", answerID), zap.Error(err))
+		return
+	}
+	if email == "" {
+		return
+	}
+	allowed, err := service.CheckCopyLimit(email)
+	if err != nil {
+		zap.L().Error("Failed to check copy limit", zap.String("answer", answerID), zap.Error(err))
+		return
+	}
+	if !allowed {
+		zap.L().Warn("Sheet copy limited", zap.String("answer", answerID), zap.Int("survey", sheet.SurveyID))
+		return
+	}
+	task, err := NewSheetCopyTask(answerID)
+	if err == nil {
+		_, err = queue.Client.Enqueue(task, asynq.MaxRetry(notifyMaxRetry))
+	}
+	if err != nil {
+		zap.L().Error("Failed to enqueue sheet copy", zap.String("answer", answerID), zap.Error(err))
+	}
+}
@@ -12,17 +12,119 @@ type submitSurveyPayload struct {
 	ID            int                 `json:"id"`
 	Time          string              `json:"time"`
 	QuestionsList []dao.QuestionsList `json:"questions_list"`
+	StudentID     string              `json:"student_id"`
+	Uploader      string              `json:"uploader"`
 }
 
 // TypeSubmitSurvey 提交问卷任务类型
 const TypeSubmitSurvey = "survey:submit"
 
 // NewSubmitSurveyTask 创建提交问卷任务
-func NewSubmitSurveyTask(id int, questionsList []dao.QuestionsList) (*asynq.Task, error) {
+func NewSubmitSurveyTask(id int, questionsList []dao.QuestionsList, stuId string, uploader string) (
+	*asynq.Task, error) {
 	payload, err := json.Marshal(submitSurveyPayload{ID: id, QuestionsList: questionsList,
-		Time: time.Now().Format("2006-01-02 15:04:05")})
+		Time: time.Now().Format("2006-01-02 15:04:05"), StudentID: stuId, Uploader: uploader})
 	if err != nil {
 		return nil, err
 	}
 	return asynq.NewTask(TypeSubmitSurvey, payload), nil
 }
+
+type exportSurveyPayload struct {
+	JobID string `json:"job_id"`
+}
+
+// TypeExportSurvey 导出问卷任务类型
+const TypeExportSurvey = "survey:export"
+
+// NewExportSurveyTask 创建导出问卷任务
+func NewExportSurveyTask(jobID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(exportSurveyPayload{JobID: jobID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeExportSurvey, payload), nil
+}
+
+// TypeCleanExport 清理过期导出文件任务类型
+const TypeCleanExport = "export:clean"
+
+// NewCleanExportTask 创建清理过期导出文件任务
+func NewCleanExportTask() *asynq.Task {
+	return asynq.NewTask(TypeCleanExport, nil)
+}
+
+// TypePurgeRecycle 清除回收站过期数据任务类型
+const TypePurgeRecycle = "recycle:purge"
+
+// NewPurgeRecycleTask 创建清除回收站过期数据任务
+func NewPurgeRecycleTask() *asynq.Task {
+	return asynq.NewTask(TypePurgeRecycle, nil)
+}
+
+// TypeProcessOutbox 重试发件箱事件任务类型
+const TypeProcessOutbox = "outbox:process"
+
+// NewProcessOutboxTask 创建重试发件箱事件任务
+func NewProcessOutboxTask() *asynq.Task {
+	return asynq.NewTask(TypeProcessOutbox, nil)
+}
+
+// TypeCollectUploads 清理未关联上传文件任务类型
+const TypeCollectUploads = "upload:gc"
+
+// NewCollectUploadsTask 创建清理未关联上传文件任务
+func NewCollectUploadsTask() *asynq.Task {
+	return asynq.NewTask(TypeCollectUploads, nil)
+}
+
+type deliverWebhookPayload struct {
+	DeliveryID int `json:"delivery_id"`
+}
+
+// TypeDeliverWebhook 投递 Webhook 任务类型
+const TypeDeliverWebhook = "webhook:deliver"
+
+// NewDeliverWebhookTask 创建投递 Webhook 任务
+func NewDeliverWebhookTask(deliveryID int) (*asynq.Task, error) {
+	payload, err := json.Marshal(deliverWebhookPayload{DeliveryID: deliveryID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeDeliverWebhook, payload), nil
+}
+
+type notifySheetPayload struct {
+	AnswerID       string `json:"answer_id"`
+	NotificationID int    `json:"notification_id"` // 订阅id, 发送答卷副本时为0
+	Copy           bool   `json:"copy"`            // 是否为发送给答卷人的副本
+}
+
+// TypeNotifySheet 发送答卷邮件任务类型
+const TypeNotifySheet = "notify:sheet"
+
+// NewNotifySheetTask 创建发送新答卷通知任务
+func NewNotifySheetTask(answerID string, notificationID int) (*asynq.Task, error) {
+	payload, err := json.Marshal(notifySheetPayload{AnswerID: answerID, NotificationID: notificationID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeNotifySheet, payload), nil
+}
+
+// NewSheetCopyTask 创建发送答卷副本任务
+func NewSheetCopyTask(answerID string) (*asynq.Task, error) {
+	payload, err := json.Marshal(notifySheetPayload{AnswerID: answerID, Copy: true})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeNotifySheet, payload), nil
+}
+
+// TypeSendDigest 发送每日汇总任务类型
+const TypeSendDigest = "notify:digest"
+
+// NewSendDigestTask 创建发送每日汇总任务
+func NewSendDigestTask() *asynq.Task {
+	return asynq.NewTask(TypeSendDigest, nil)
+}
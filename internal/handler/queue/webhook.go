@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/queue"
+	"QA-System/internal/service"
+	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
+)
+
+// EnqueueWebhookDelivery 将投递记录加入任务队列
+func EnqueueWebhookDelivery(delivery *model.WebhookDelivery) error {
+	task, err := NewDeliverWebhookTask(delivery.ID)
+	if err != nil {
+		return err
+	}
+	_, err = queue.Client.Enqueue(task, asynq.MaxRetry(service.GetWebhookMaxRetry()),
+		asynq.Timeout(service.GetWebhookTimeout()+30*time.Second))
+	return err
+}
+
+// DispatchWebhooks 推送问卷事件, 失败只记录日志而不影响请求, 未入队的投递可在投递记录中重新投递
+func DispatchWebhooks(sid int, event string, data any) {
+	deliveries, err := service.CreateWebhookDeliveries(sid, event, data)
+	if err != nil {
+		zap.L().Error("Failed to create webhook deliveries", zap.Int("survey", sid), zap.String("event", event),
+			zap.Error(err))
+		return
+	}
+	enqueueWebhookDeliveries(deliveries)
+}
+
+// DispatchQuotaWebhooks 答卷数达到设定值时推送 quota.reached
+func DispatchQuotaWebhooks(sid int) {
+	deliveries, err := service.CreateQuotaDeliveries(sid)
+	if err != nil {
+		zap.L().Error("Failed to create webhook deliveries", zap.Int("survey", sid),
+			zap.String("event", service.EventQuotaReached), zap.Error(err))
+		return
+	}
+	enqueueWebhookDeliveries(deliveries)
+}
+
+// DispatchSheetSubmitted 推送答卷提交事件
+func DispatchSheetSubmitted(sheet *dao.AnswerSheet) {
+	data, err := service.SheetEventData(sheet)
+	if err != nil {
+		zap.L().Error("Failed to build webhook data", zap.Int("survey", sheet.SurveyID), zap.Error(err))
+	} else {
+		DispatchWebhooks(sheet.SurveyID, service.EventSheetSubmitted, data)
+	}
+	DispatchQuotaWebhooks(sheet.SurveyID)
+}
+
+// enqueueWebhookDeliveries 将投递记录逐条加入任务队列
+func enqueueWebhookDeliveries(deliveries []model.WebhookDelivery) {
+	for i := range deliveries {
+		if err := EnqueueWebhookDelivery(&deliveries[i]); err != nil {
+			zap.L().Error("Failed to enqueue webhook delivery", zap.Int("delivery", deliveries[i].ID),
+				zap.Error(err))
+		}
+	}
+}
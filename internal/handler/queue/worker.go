@@ -4,9 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 
 	"QA-System/internal/service"
 	"github.com/hibiken/asynq"
+	"go.uber.org/zap"
 )
 
 // HandleSubmitSurveyTask 处理提交问卷任务
@@ -16,10 +18,110 @@ func HandleSubmitSurveyTask(_ context.Context, t *asynq.Task) error {
 		return err
 	}
 	// 提交问卷
-	err := service.SubmitSurvey(p.ID, p.QuestionsList, p.Time)
+	sheet, err := service.SubmitSurvey(p.ID, p.QuestionsList, p.Time, p.StudentID, p.Uploader)
 	if err != nil {
 		return errors.New("提交问卷失败原因: " + err.Error())
 	}
+	DispatchSheetSubmitted(sheet)
+	DispatchSheetNotifications(sheet)
 
 	return nil
 }
+
+// HandleExportSurveyTask 处理导出问卷任务
+func HandleExportSurveyTask(_ context.Context, t *asynq.Task) error {
+	var p exportSurveyPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return err
+	}
+	err := service.RunExportJob(p.JobID)
+	if err != nil {
+		return errors.New("导出问卷失败原因: " + err.Error())
+	}
+	return nil
+}
+
+// HandleCleanExportTask 处理清理过期导出文件任务
+func HandleCleanExportTask(_ context.Context, _ *asynq.Task) error {
+	err := service.CleanExpiredExports()
+	if err != nil {
+		return errors.New("清理导出文件失败原因: " + err.Error())
+	}
+	return nil
+}
+
+// HandlePurgeRecycleTask 处理清除回收站过期数据任务
+func HandlePurgeRecycleTask(_ context.Context, _ *asynq.Task) error {
+	err := service.PurgeRecycleBin()
+	if err != nil {
+		return errors.New("清除回收站失败原因: " + err.Error())
+	}
+	return nil
+}
+
+// HandleProcessOutboxTask 处理重试发件箱事件任务
+func HandleProcessOutboxTask(_ context.Context, _ *asynq.Task) error {
+	err := service.ProcessOutbox()
+	if err != nil {
+		return errors.New("重试发件箱事件失败原因: " + err.Error())
+	}
+	return nil
+}
+
+// HandleCollectUploadsTask 处理清理未关联上传文件任务
+func HandleCollectUploadsTask(_ context.Context, _ *asynq.Task) error {
+	uploads, err := service.CollectPendingUploads(service.GetUploadPendingTTL(), false)
+	if len(uploads) > 0 {
+		zap.L().Info("Pending uploads removed", zap.Int("count", len(uploads)))
+	}
+	if err != nil {
+		return errors.New("清理上传文件失败原因: " + err.Error())
+	}
+	return nil
+}
+
+// HandleDeliverWebhookTask 处理投递 Webhook 任务, 失败时按 asynq 的退避策略重试
+func HandleDeliverWebhookTask(_ context.Context, t *asynq.Task) error {
+	var p deliverWebhookPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return err
+	}
+	err := service.DeliverWebhook(p.DeliveryID)
+	if errors.Is(err, service.ErrWebhookGone) || errors.Is(err, service.ErrWebhookRejected) {
+		return fmt.Errorf("投递 Webhook 失败原因: %v: %w", err, asynq.SkipRetry)
+	}
+	if err != nil {
+		return errors.New("投递 Webhook 失败原因: " + err.Error())
+	}
+	return nil
+}
+
+// HandleNotifySheetTask 处理发送答卷邮件任务
+func HandleNotifySheetTask(_ context.Context, t *asynq.Task) error {
+	var p notifySheetPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return err
+	}
+	var err error
+	if p.Copy {
+		err = service.SendSheetCopy(p.AnswerID)
+	} else {
+		err = service.SendSheetNotification(p.AnswerID, p.NotificationID)
+	}
+	if errors.Is(err, service.ErrMailDisabled) {
+		return fmt.Errorf("发送邮件失败原因: %v: %w", err, asynq.SkipRetry)
+	}
+	if err != nil {
+		return errors.New("发送邮件失败原因: " + err.Error())
+	}
+	return nil
+}
+
+// HandleSendDigestTask 处理发送每日汇总任务
+func HandleSendDigestTask(_ context.Context, _ *asynq.Task) error {
+	err := service.SendDigests()
+	if err != nil {
+		return errors.New("发送每日汇总失败原因: " + err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package user
+
+import (
+	"errors"
+	"image"
+	"io"
+	"io/fs"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"QA-System/internal/middleware"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/pkg/utils"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type uploadData struct {
+	SurveyID    int    `form:"survey_id"`
+	QuestionID  int    `form:"question_id"`
+	Token       string `form:"token"`        // 问卷需要统一验证时的登录凭证
+	UploadToken string `form:"upload_token"` // 问卷无需统一验证时的上传凭证, 首次上传时为空
+}
+
+// getUploadQuestion 校验上传的目标问题, 返回问题、上传者与上传凭证
+// 上传者为统一验证的学号, 问卷无需统一验证时为上传凭证的摘要, 未携带凭证时签发新的凭证
+func getUploadQuestion(c *gin.Context, data uploadData, questionType int) (*model.Question, string, string, bool) {
+	survey, err := service.GetSurveyByID(data.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
+		return nil, "", "", false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, "", "", false
+	}
+	if survey.Status != 2 {
+		code.AbortWithException(c, code.SurveyNotOpen, errors.New("问卷未开放"))
+		return nil, "", "", false
+	}
+	if (!survey.Deadline.IsZero() && survey.Deadline.Before(time.Now())) ||
+		(!survey.StartTime.IsZero() && survey.StartTime.After(time.Now())) {
+		code.AbortWithException(c, code.TimeBeyondError, errors.New("不在问卷填写时间内"))
+		return nil, "", "", false
+	}
+	question, err := service.GetQuestionByID(data.QuestionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && question.SurveyID != survey.ID) {
+		code.AbortWithException(c, code.ParamError, errors.New("问题不属于该问卷"))
+		return nil, "", "", false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return nil, "", "", false
+	}
+	if question.QuestionType != questionType {
+		code.AbortWithException(c, code.ParamError, errors.New("问题类型不支持上传该文件"))
+		return nil, "", "", false
+	}
+	if survey.Verify {
+		userInfo, err := utils.ParseJWT(data.Token)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return nil, "", "", false
+		}
+		return question, userInfo.StudentID, "", true
+	}
+	token := data.UploadToken
+	if token == "" {
+		if token, err = service.NewUploadToken(); err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return nil, "", "", false
+		}
+	}
+	uploader, err := service.UploadTokenUploader(token)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return nil, "", "", false
+	}
+	return question, uploader, token, true
+}
+
+// answerUploader 提交答卷时的上传者标识, 统一验证时为学号, 否则为上传凭证的摘要
+// 凭证缺失或无效时为空, 不会匹配任何上传的文件
+func answerUploader(survey *model.Survey, studentID string, uploadToken string) string {
+	if survey.Verify {
+		return studentID
+	}
+	uploader, err := service.UploadTokenUploader(uploadToken)
+	if err != nil {
+		return ""
+	}
+	return uploader
+}
+
+// uploadResponse 返回答卷人上传的文件地址, 问卷无需统一验证时一并返回上传凭证, 之后的上传与提交需携带
+func uploadResponse(c *gin.Context, url string, uploadToken string) {
+	resp := gin.H{"url": url}
+	if uploadToken != "" {
+		resp["upload_token"] = uploadToken
+	}
+	utils.JsonSuccessResponse(c, resp)
+}
+
+// checkUploadQuota 检查答卷人上传配额
+func checkUploadQuota(c *gin.Context, question *model.Question, uploader string, size int64) bool {
+	err := service.CheckUploadQuota(question, uploader, size)
+	if errors.Is(err, service.ErrUploadQuota) {
+		code.AbortWithException(c, code.UploadQuotaError, err)
+		return false
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return false
+	}
+	return true
+}
+
+// UploadImg 上传图片, 未指定问题时为管理员上传问题与选项的图片
+func UploadImg(c *gin.Context) {
+	var data uploadData
+	err := c.ShouldBind(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 获取文件
+	fileHeader, err := c.FormFile("img")
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+
+	var question *model.Question
+	var uploader, uploadToken string
+	limit := service.MaxImageSize
+	if data.QuestionID == 0 {
+		user, err := service.GetUserSession(c)
+		if err != nil {
+			code.AbortWithException(c, code.NotLogin, err)
+			return
+		}
+		uploader = "admin:" + strconv.Itoa(user.ID)
+	} else {
+		var ok bool
+		question, uploader, uploadToken, ok = getUploadQuestion(c, data, 5)
+		if !ok {
+			return
+		}
+		limit = service.GetUploadSizeLimit(question, limit)
+	}
+
+	// 检查文件大小是否超出限制
+	if fileHeader.Size > limit {
+		code.AbortWithException(c, code.FileSizeError, errors.New("文件大小超出限制"))
+		return
+	}
+	if question != nil && !checkUploadQuota(c, question, uploader, fileHeader.Size) {
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	defer func(file multipart.File) {
+		err := file.Close()
+		if err != nil {
+			zap.L().Error("Failed to close file", zap.Error(err))
+		}
+	}(file)
+
+	// 处理并保存图片与缩略图
+	saved, err := service.SaveImage(file, uuid.New().String())
+	if errors.Is(err, image.ErrFormat) {
+		code.AbortWithException(c, code.PictureError, err)
+		return
+	}
+	if errors.Is(err, service.ErrImageTooLarge) {
+		code.AbortWithException(c, code.FileSizeError, err)
+		return
+	}
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	surveyID, questionID := 0, 0
+	if question != nil {
+		surveyID, questionID = question.SurveyID, question.ID
+	}
+	if err := service.RecordUpload(surveyID, questionID, uploader, saved); err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	// 管理员上传问卷图片时只返回地址
+	if question == nil {
+		utils.JsonSuccessResponse(c, saved.URL)
+		return
+	}
+	uploadResponse(c, saved.URL, uploadToken)
+}
+
+// UploadFile 上传文件题的文件, 类型根据文件内容检测, 不使用客户端提供的扩展名
+func UploadFile(c *gin.Context) {
+	var data uploadData
+	err := c.ShouldBind(&data)
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	// 获取文件
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		code.AbortWithException(c, code.ParamError, err)
+		return
+	}
+	question, uploader, uploadToken, ok := getUploadQuestion(c, data, 6)
+	if !ok {
+		return
+	}
+
+	// 检查文件大小是否超出限制
+	if fileHeader.Size > service.GetUploadSizeLimit(question, service.MaxFileSize) {
+		code.AbortWithException(c, code.FileSizeError, errors.New("文件大小超出限制"))
+		return
+	}
+	if !checkUploadQuota(c, question, uploader, fileHeader.Size) {
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	defer func(file multipart.File) {
+		err := file.Close()
+		if err != nil {
+			zap.L().Error("Failed to close file", zap.Error(err))
+		}
+	}(file)
+
+	// 检查文件类型
+	mtype, err := service.DetectFileType(file)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if err := service.CheckFileType(question, mtype); err != nil {
+		code.AbortWithException(c, code.FileTypeError, err)
+		return
+	}
+
+	contentType, _, _ := strings.Cut(mtype.String(), ";")
+	filename := uuid.New().String() + mtype.Extension()
+	saved := &service.SavedUpload{Size: fileHeader.Size, ContentType: contentType}
+
+	// 安全扫描
+	err = service.ScanUpload(file, question, uploader, filename, saved)
+	if errors.Is(err, service.ErrInfected) {
+		code.AbortWithException(c, code.InfectedFileError, err)
+		return
+	}
+	if errors.Is(err, service.ErrScanUnavailable) {
+		code.AbortWithException(c, code.ScanUnavailableError, err)
+		return
+	}
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+
+	// 保存文件
+	saved.URL, err = service.SaveUpload(service.FileDir, filename, file, fileHeader.Size, contentType)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if err := service.RecordUpload(question.SurveyID, question.ID, uploader, saved); err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	uploadResponse(c, saved.URL, uploadToken)
+}
+
+// ServeUpload 下载上传的文件, 存储后端支持临时地址时重定向, 否则由服务读取后返回
+func ServeUpload(c *gin.Context) {
+	dir := c.Param("dir")
+	if dir != service.StaticDir && dir != service.FileDir {
+		middleware.HandleNotFound(c)
+		return
+	}
+	key := dir + "/" + path.Base(c.Param("name"))
+	// 文件题的附件附带安全扫描状态, 供管理端在下载前提示
+	if dir == service.FileDir {
+		status, err := service.GetUploadScanStatusByKey(key)
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+		c.Header("X-Scan-Status", status)
+	}
+	url, err := service.SignUploadURL(key)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if url != "" {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+	file, err := service.OpenUpload(key)
+	if errors.Is(err, fs.ErrNotExist) {
+		middleware.HandleNotFound(c)
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	defer func(file io.ReadCloser) {
+		err := file.Close()
+		if err != nil {
+			zap.L().Error("Failed to close file", zap.Error(err))
+		}
+	}(file)
+	if rs, ok := file.(io.ReadSeeker); ok {
+		http.ServeContent(c.Writer, c.Request, key, time.Time{}, rs)
+		return
+	}
+	c.DataFromReader(http.StatusOK, -1, mime.TypeByExtension(path.Ext(key)), file, nil)
+}
@@ -2,41 +2,55 @@ package user
 
 import (
 	"errors"
-	"image"
-	"mime/multipart"
-	"path/filepath"
+	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"QA-System/internal/dao"
+	q "QA-System/internal/handler/queue"
 	"QA-System/internal/model"
 	"QA-System/internal/pkg/code"
 	"QA-System/internal/pkg/utils"
 	"QA-System/internal/service"
-	"github.com/dustin/go-humanize"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/zjutjh/WeJH-SDK/oauth"
 	"github.com/zjutjh/WeJH-SDK/oauth/oauthException"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type submitSurveyData struct {
-	ID            int                 `json:"id" binding:"required"`
-	Token         string              `json:"token"`
-	QuestionsList []dao.QuestionsList `json:"questions_list"`
+	ID              int                 `json:"id" binding:"required"`
+	Token           string              `json:"token"`
+	QuestionsList   []dao.QuestionsList `json:"questions_list"`
+	ChallengeID     string              `json:"challenge_id"`
+	ChallengeAnswer string              `json:"challenge_answer"`
+	UploadToken     string              `json:"upload_token"`                  // 问卷无需统一验证时上传文件获得的凭证
+	Fingerprint     string              `json:"fingerprint" binding:"max=256"` // 前端生成的设备指纹
 }
 
 // SubmitSurvey 提交问卷
 func SubmitSurvey(c *gin.Context) {
 	var data submitSurveyData
-	err := c.ShouldBindJSON(&data)
+	err := c.ShouldBindBodyWith(&data, binding.JSON)
 	if err != nil {
 		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
+	// 蜜罐字段被填写时视为机器提交, 返回成功但不保存
+	var body map[string]any
+	if err := c.ShouldBindBodyWith(&body, binding.JSON); err == nil {
+		if field := service.CheckHoneypot(body); field != "" {
+			zap.L().Warn("Honeypot triggered", zap.Int("survey_id", data.ID), zap.String("field", field),
+				zap.String("ip", c.ClientIP()))
+			utils.JsonSuccessResponse(c, nil)
+			return
+		}
+	}
 	// 判断问卷问题和答卷问题数目是否一致
 	survey, err := service.GetSurveyByID(data.ID)
 	if err != nil {
@@ -52,6 +66,7 @@ func SubmitSurvey(c *gin.Context) {
 		}
 	}
 	stuId := userInfo.StudentID
+	uploader := answerUploader(survey, stuId, data.UploadToken)
 	questions, err := service.GetQuestionsBySurveyID(survey.ID)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
@@ -93,6 +108,30 @@ func SubmitSurvey(c *gin.Context) {
 				errors.New("问题"+strconv.Itoa(q.QuestionID)+"必填字段为空"))
 			return
 		}
+		// 判断邮箱题格式, 用于发送答卷副本
+		if question.SendCopy && q.Answer != "" && !service.CheckEmail(q.Answer) {
+			code.AbortWithException(c, code.EmailFormatError,
+				errors.New("问题"+strconv.Itoa(q.QuestionID)+"邮箱格式错误"))
+			return
+		}
+		// 判断图片题与文件题的文件数量, 以及文件是否为该问题上传
+		if question.QuestionType == 5 || question.QuestionType == 6 {
+			err := service.CheckAnswerUploads(question, q.Answer, uploader)
+			if errors.Is(err, service.ErrFileCount) {
+				code.AbortWithException(c, code.FileCountError,
+					errors.New("问题"+strconv.Itoa(q.QuestionID)+"文件数量超出限制"))
+				return
+			}
+			if errors.Is(err, service.ErrUploadMismatch) {
+				code.AbortWithException(c, code.UploadMismatchError,
+					errors.New("问题"+strconv.Itoa(q.QuestionID)+"的文件不属于该问题"))
+				return
+			}
+			if err != nil {
+				code.AbortWithException(c, code.ServerError, err)
+				return
+			}
+		}
 		// 判断多选题选项数量是否符合要求
 		if (question.QuestionType == 2 && survey.Type == 0) || (question.QuestionType == 1 && survey.Type == 1) {
 			length := uint(len(strings.Split(q.Answer, "┋")))
@@ -106,6 +145,32 @@ func SubmitSurvey(c *gin.Context) {
 			}
 		}
 	}
+	// 人机验证放在答案校验之后, 避免答案有误时白白消耗验证题目
+	if survey.Challenge != model.ChallengeNone {
+		err := service.VerifyChallenge(survey.ID, data.ChallengeID, data.ChallengeAnswer)
+		if errors.Is(err, service.ErrChallengeRequired) {
+			code.AbortWithException(c, code.ChallengeRequired, err)
+			return
+		}
+		if errors.Is(err, service.ErrChallengeFailed) {
+			code.AbortWithException(c, code.ChallengeFailed, err)
+			return
+		}
+		if err != nil {
+			code.AbortWithException(c, code.ServerError, err)
+			return
+		}
+	}
+	// 只统计通过校验的提交, 填写有误或验证码输错不占用同一出口 IP 下其他人的次数
+	allowed, err := service.CheckSubmitThrottle(survey.ID, c.ClientIP(), data.Fingerprint)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if !allowed {
+		code.AbortWithException(c, code.SubmitTooFrequent, errors.New("提交过于频繁"))
+		return
+	}
 	flagSum, flagDay := false, false
 
 	if survey.Verify {
@@ -133,11 +198,18 @@ func SubmitSurvey(c *gin.Context) {
 			return
 		}
 	}
-	err = service.SubmitSurvey(data.ID, data.QuestionsList, time.Now().Format("2006-01-02 15:04:05"))
+	sheet, err := service.SubmitSurvey(data.ID, data.QuestionsList, time.Now().Format("2006-01-02 15:04:05"), stuId,
+		uploader)
+	if errors.Is(err, service.ErrUploadMismatch) {
+		code.AbortWithException(c, code.UploadMismatchError, errors.New("答案中的文件已被使用或不属于该答卷人"))
+		return
+	}
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
+	q.DispatchSheetSubmitted(sheet)
+	q.DispatchSheetNotifications(sheet)
 
 	if survey.Verify {
 		if survey.DailyLimit > 0 {
@@ -213,6 +285,7 @@ func GetSurvey(c *gin.Context) {
 		for _, option := range options {
 			optionResponse := map[string]any{
 				"img":         option.Img,
+				"thumb":       service.ThumbURL(option.Img),
 				"content":     option.Content,
 				"description": option.Description,
 				"serial_num":  option.SerialNum,
@@ -228,6 +301,11 @@ func GetSurvey(c *gin.Context) {
 			"reg":            question.Reg,
 			"maximum_option": question.MaximumOption,
 			"minimum_option": question.MinimumOption,
+			"file_types":     question.FileTypes,
+			"max_file_size":  question.MaxFileSize,
+			"max_file_count": question.MaxFileCount,
+			"upload_quota":   question.UploadQuota,
+			"send_copy":      question.SendCopy,
 		}
 
 		questionListMap := map[string]any{
@@ -253,6 +331,12 @@ func GetSurvey(c *gin.Context) {
 		"day_limit":  survey.DailyLimit,
 		"sum_limit":  survey.SumLimit,
 		"verify":     survey.Verify,
+		"challenge":  survey.Challenge,
+	}
+	challenge, err := service.IssueChallenge(survey)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
 	}
 	response := map[string]any{
 		"id":          survey.ID,
@@ -260,87 +344,39 @@ func GetSurvey(c *gin.Context) {
 		"survey_type": survey.Type,
 		"base_config": baseConfigResponse,
 		"ques_config": questionsConfigResponse,
+		"challenge":   challenge,
+		"honeypot":    service.HoneypotFields(),
 	}
 
 	utils.JsonSuccessResponse(c, response)
 }
 
-// UploadImg 上传图片
-func UploadImg(c *gin.Context) {
-	// 获取文件
-	fileHeader, err := c.FormFile("img")
+// GetChallenge 重新获取人机验证题目, 用于验证失败或过期后刷新
+func GetChallenge(c *gin.Context) {
+	var data getSurveyData
+	err := c.ShouldBindQuery(&data)
 	if err != nil {
 		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
-
-	// 检查文件大小是否超出限制
-	if fileHeader.Size > 10*humanize.MiByte {
-		code.AbortWithException(c, code.FileSizeError, err)
-		return
-	}
-
-	file, err := fileHeader.Open()
-	if err != nil {
-		code.AbortWithException(c, code.ServerError, err)
-		return
-	}
-	defer func(file multipart.File) {
-		err := file.Close()
-		if err != nil {
-			zap.L().Error("Failed to close file", zap.Error(err))
-		}
-	}(file)
-
-	reader, err := service.ConvertToJPEG(file)
-	if errors.Is(err, image.ErrFormat) {
-		code.AbortWithException(c, code.PictureError, err)
-		return
-	}
-	if err != nil {
-		code.AbortWithException(c, code.ServerError, err)
+	survey, err := service.GetSurveyByID(data.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		code.AbortWithException(c, code.SurveyNotExist, errors.New("问卷不存在"))
 		return
-	}
-
-	// 保存图片
-	filename := uuid.New().String() + ".jpg"
-	dst := filepath.Join("./public/static/", filename)
-	err = service.SaveFile(reader, dst)
-	if err != nil {
+	} else if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-
-	url := service.GetConfigUrl() + "/public/static/" + filename
-	utils.JsonSuccessResponse(c, url)
-}
-
-// UploadFile 上传文件
-func UploadFile(c *gin.Context) {
-	// 获取文件
-	fileHeader, err := c.FormFile("file")
-	if err != nil {
-		code.AbortWithException(c, code.ParamError, err)
-		return
-	}
-
-	// 检查文件大小是否超出限制
-	if fileHeader.Size > 50*humanize.MiByte {
-		code.AbortWithException(c, code.FileSizeError, err)
+	if survey.Status != 2 {
+		code.AbortWithException(c, code.SurveyNotOpen, errors.New("问卷未开放"))
 		return
 	}
-
-	// 保存文件
-	filename := uuid.New().String() + filepath.Ext(fileHeader.Filename)
-	dst := filepath.Join("./public/file/", filename)
-	err = c.SaveUploadedFile(fileHeader, dst)
+	challenge, err := service.IssueChallenge(survey)
 	if err != nil {
 		code.AbortWithException(c, code.ServerError, err)
 		return
 	}
-
-	url := service.GetConfigUrl() + "/public/file/" + filename
-	utils.JsonSuccessResponse(c, url)
+	utils.JsonSuccessResponse(c, gin.H{"challenge": challenge})
 }
 
 type oauthData struct {
@@ -356,6 +392,17 @@ func Oauth(c *gin.Context) {
 		code.AbortWithException(c, code.ParamError, err)
 		return
 	}
+	// 防止借助本接口暴力破解统一身份认证密码
+	remain, err := service.CheckLoginLocked(service.LoginScopeOauth, data.StudentID, c.ClientIP())
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	if remain > 0 {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(remain.Seconds()))))
+		code.AbortWithException(c, code.LoginLocked, fmt.Errorf("%s登录已锁定, 剩余%s", data.StudentID, remain))
+		return
+	}
 	user, err := service.Oauth(data.StudentID, data.Password)
 	if err != nil {
 		var oauthErr *oauthException.Error
@@ -367,6 +414,11 @@ func Oauth(c *gin.Context) {
 		switch {
 		case errors.Is(oauthErr, oauthException.WrongPassword),
 			errors.Is(oauthErr, oauthException.WrongAccount):
+			_, recordErr := service.RecordLoginFailure(service.LoginScopeOauth, data.StudentID, c.ClientIP())
+			if recordErr != nil {
+				zap.L().Error("Failed to record login failure", zap.String("stu_id", data.StudentID),
+					zap.Error(recordErr))
+			}
 			code.AbortWithException(c, code.WrongOauthUsernameOrPassword, err)
 		case errors.Is(oauthErr, oauthException.ClosedError):
 			code.AbortWithException(c, code.OauthTimeError, err)
@@ -375,6 +427,11 @@ func Oauth(c *gin.Context) {
 		}
 		return
 	}
+	err = service.ResetLoginFailures(service.LoginScopeOauth, data.StudentID)
+	if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
 	token := utils.NewJWT(user.Name, user.College, user.StudentID, user.UserType, user.UserTypeDesc, user.Gender)
 	if token == "" {
 		code.AbortWithException(c, code.ServerError, errors.New("统一验证失败原因: token生成失败"))
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+
+	"QA-System/internal/pkg/code"
+	"QA-System/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// tokenScopes 允许使用 API 令牌访问的接口及所需的权限范围, 未列出的接口只能通过会话访问
+var tokenScopes = map[string]string{
+	"GET /api/admin/list/questions":  service.ScopeStats,
+	"GET /api/admin/single/question": service.ScopeStats,
+	"GET /api/admin/list/answers":    service.ScopeStats,
+	"GET /api/admin/statics/answers": service.ScopeStats,
+	"GET /api/admin/permission/list": service.ScopeStats,
+
+	"GET /api/admin/download":       service.ScopeExport,
+	"GET /api/admin/export/status":  service.ScopeExport,
+	"GET /api/admin/export/file":    service.ScopeExport,
+	"GET /api/admin/download/files": service.ScopeExport,
+
+	"GET /api/admin/create":                service.ScopeManage,
+	"POST /api/admin/create":               service.ScopeManage,
+	"POST /api/admin/new":                  service.ScopeManage,
+	"POST /api/admin/import":               service.ScopeManage,
+	"POST /api/admin/import/answers":       service.ScopeManage,
+	"PUT /api/admin/update/status":         service.ScopeManage,
+	"PUT /api/admin/update/questions":      service.ScopeManage,
+	"DELETE /api/admin/delete":             service.ScopeManage,
+	"DELETE /api/admin/delete/answersheet": service.ScopeManage,
+}
+
+// checkAPIToken 校验 Authorization 头中的 Bearer 令牌
+func checkAPIToken(c *gin.Context, header string) {
+	plain, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok {
+		code.AbortWithException(c, code.APITokenInvalid, errors.New("Authorization 头格式错误"))
+		return
+	}
+	user, token, err := service.AuthenticateAPIToken(strings.TrimSpace(plain))
+	if errors.Is(err, service.ErrAPITokenInvalid) {
+		code.AbortWithException(c, code.APITokenInvalid, err)
+		return
+	} else if err != nil {
+		code.AbortWithException(c, code.ServerError, err)
+		return
+	}
+	scope, ok := tokenScopes[c.Request.Method+" "+c.FullPath()]
+	if !ok || !service.HasTokenScope(token, scope) {
+		code.AbortWithException(c, code.APITokenScopeError,
+			errors.New(token.Prefix+"无权访问"+c.Request.Method+" "+c.FullPath()))
+		return
+	}
+	service.SetAPITokenUser(c, user, token)
+	c.Next()
+}
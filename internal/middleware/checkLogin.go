@@ -9,11 +9,28 @@ import (
 
 // CheckLogin 检查登录
 func CheckLogin(c *gin.Context) {
+	// 脚本等自动化工具使用 API 令牌访问
+	if header := c.GetHeader("Authorization"); header != "" {
+		checkAPIToken(c, header)
+		return
+	}
 	isLogin := service.CheckUserSession(c)
 	if !isLogin {
 		utils.JsonErrorResponse(c, code.NotLogin.Code, code.NotLogin.Msg)
 		c.Abort()
 		return
 	}
+	// 使用一次性密码登录后, 需先修改密码才能进行其他操作
+	if service.CheckPasswordChangeRequired(c) {
+		utils.JsonErrorResponse(c, code.PasswordChangeRequired.Code, code.PasswordChangeRequired.Msg)
+		c.Abort()
+		return
+	}
+	// 策略要求开启两步验证时, 需先完成绑定
+	if service.CheckTwoFactorEnrollRequired(c) {
+		utils.JsonErrorResponse(c, code.TwoFactorEnrollRequired.Code, code.TwoFactorEnrollRequired.Msg)
+		c.Abort()
+		return
+	}
 	c.Next()
 }
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// APIToken 管理员个人 API 令牌模型
+type APIToken struct {
+	ID         int        `json:"id"`                                 // 令牌id
+	UserID     int        `json:"user_id" gorm:"index"`               // 所属用户id
+	Name       string     `json:"name"`                               // 令牌名称
+	Prefix     string     `json:"prefix"`                             // 令牌前缀, 便于辨认
+	TokenHash  string     `json:"-" gorm:"type:char(64);uniqueIndex"` // 令牌的 SHA-256 摘要
+	Scopes     string     `json:"scopes"`                             // 权限范围, 以逗号分隔
+	CreatedAt  time.Time  `json:"created_at"`                         // 创建时间
+	ExpireAt   *time.Time `json:"expire_at"`                          // 过期时间, 为空时长期有效
+	LastUsedAt *time.Time `json:"last_used_at"`                       // 最近使用时间
+	Revoked    bool       `json:"revoked"`                            // 是否已吊销
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// AuditLog 管理操作审计日志模型
+type AuditLog struct {
+	ID         int       `json:"id"`                          // 日志id
+	UserID     int       `json:"user_id" gorm:"index"`        // 操作者id
+	Username   string    `json:"username"`                    // 操作者用户名
+	TokenID    int       `json:"token_id"`                    // 使用 API 令牌操作时的令牌id
+	Action     string    `json:"action" gorm:"size:64;index"` // 操作类型
+	SurveyID   int       `json:"survey_id" gorm:"index"`      // 相关问卷id
+	TargetType string    `json:"target_type" gorm:"size:32"`  // 操作对象类型
+	TargetID   string    `json:"target_id" gorm:"size:64"`    // 操作对象id
+	IP         string    `json:"ip"`                          // 操作者IP
+	Diff       string    `json:"diff" gorm:"type:mediumtext"` // 变更前后的差异, JSON 格式
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`     // 操作时间
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Invitation 管理员注册邀请码模型
+type Invitation struct {
+	ID        int        `json:"id"`                                 // 邀请码id
+	CodeHash  string     `json:"-" gorm:"type:char(64);uniqueIndex"` // 邀请码的 SHA-256 摘要
+	Username  string     `json:"username"`                           // 绑定的用户名, 为空时不限制
+	AdminType int        `json:"admin_type"`                         // 注册后的管理员类型
+	CreatedBy int        `json:"created_by"`                         // 创建者id
+	CreatedAt time.Time  `json:"created_at"`                         // 创建时间
+	ExpireAt  time.Time  `json:"expire_at"`                          // 过期时间
+	UsedBy    int        `json:"used_by"`                            // 使用者id, 未使用时为 0
+	UsedAt    *time.Time `json:"used_at"`                            // 使用时间
+	UsedIP    string     `json:"used_ip"`                            // 使用时的IP
+	Revoked   bool       `json:"revoked"`                            // 是否已作废
+}
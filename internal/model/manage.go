@@ -1,8 +1,17 @@
 package model
 
+// 问卷协作者角色, 高级角色拥有低级角色的全部权限
+const (
+	RoleViewer  = 1 // 查看者: 查看问卷、统计与答卷
+	RoleAnalyst = 2 // 分析者: 额外可导出答卷与文件
+	RoleEditor  = 3 // 编辑者: 额外可修改问卷内容与状态
+	RoleOwner   = 4 // 所有者: 额外可删除问卷、答卷及授权
+)
+
 // Manage 问卷权限模型
 type Manage struct {
 	ID       int `json:"id"`
 	UserID   int `json:"user_id"`
 	SurveyID int `json:"survey_id"`
+	Role     int `json:"role" gorm:"default:3"` // 协作者角色, 旧数据默认为编辑者
 }
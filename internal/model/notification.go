@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// 邮件通知方式
+const (
+	NotifyEach   = 1 // 每份答卷发送一封邮件
+	NotifyDigest = 2 // 每日汇总
+)
+
+// Notification 管理员订阅的问卷邮件通知模型
+type Notification struct {
+	ID           int        `json:"id"`                                                         // 订阅id
+	SurveyID     int        `json:"survey_id" gorm:"uniqueIndex:idx_notifications_survey_user"` // 问卷id
+	UserID       int        `json:"user_id" gorm:"uniqueIndex:idx_notifications_survey_user"`   // 订阅者id
+	Email        string     `json:"email" gorm:"size:255"`                                      // 收件地址
+	Mode         int        `json:"mode"`                                                       // 通知方式 1每份答卷 2每日汇总
+	LastDigestAt *time.Time `json:"last_digest_at"`                                             // 最近一次发送汇总的时间
+	CreatedAt    time.Time  `json:"created_at"`                                                 // 创建时间
+	UpdatedAt    time.Time  `json:"updated_at"`                                                 // 更新时间
+}
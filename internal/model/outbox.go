@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// OutboxEvent 事务发件箱事件模型, 与业务数据在同一事务中写入, 提交后再执行
+type OutboxEvent struct {
+	ID          int        `json:"id"`                          // 事件id
+	Type        string     `json:"type" gorm:"size:64"`         // 事件类型
+	Payload     string     `json:"payload" gorm:"type:text"`    // 事件参数, JSON 格式
+	Attempts    int        `json:"attempts"`                    // 已执行次数
+	LastError   string     `json:"last_error" gorm:"type:text"` // 最近一次失败原因
+	CreatedAt   time.Time  `json:"created_at" gorm:"index"`     // 创建时间
+	ProcessedAt *time.Time `json:"processed_at" gorm:"index"`   // 执行成功时间, 为空表示待执行
+}
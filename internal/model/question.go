@@ -15,4 +15,9 @@ type Question struct {
 	MaximumOption uint   `json:"maximum_option"` // 多选最多所选选项数 0为不限制
 	MinimumOption uint   `json:"minimum_option"` // 多选最少所选选项数 0为不限制
 	Reg           string `json:"reg"`            // 正则表达式
+	FileTypes     string `json:"file_types"`     // 文件题允许的 MIME 类型, 逗号分隔, 支持 image/* 形式, 为空不限制
+	MaxFileSize   int64  `json:"max_file_size"`  // 单个文件最大字节数 0为使用默认限制
+	MaxFileCount  uint   `json:"max_file_count"` // 最多上传文件数 0为不限制
+	UploadQuota   int64  `json:"upload_quota"`   // 每位答卷人在该题上传的总字节数 0为不限制
+	SendCopy      bool   `json:"send_copy"`      // 填空题的答案作为邮箱, 向答卷人发送答卷副本
 }
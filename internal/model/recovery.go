@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// RecoveryCode 两步验证恢复码模型
+type RecoveryCode struct {
+	ID       int        `json:"id"`                     // 恢复码id
+	UserID   int        `json:"user_id" gorm:"index"`   // 用户id
+	CodeHash string     `json:"-" gorm:"type:char(64)"` // 恢复码的 SHA-256 摘要
+	UsedAt   *time.Time `json:"used_at"`                // 使用时间, 未使用时为空
+}
@@ -0,0 +1,7 @@
+package model
+
+// Setting 系统设置模型, 保存超级管理员可修改的全局策略
+type Setting struct {
+	Key   string `json:"key" gorm:"primaryKey;size:64"` // 设置项
+	Value string `json:"value"`                         // 设置值
+}
@@ -1,6 +1,17 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 问卷提交前的人机验证方式
+const (
+	ChallengeNone    = 0 // 不验证
+	ChallengePoW     = 1 // 工作量证明, 由浏览器计算满足难度的哈希
+	ChallengeCaptcha = 2 // 图片验证码
+)
 
 // Survey 问卷模型
 type Survey struct {
@@ -16,6 +27,9 @@ type Survey struct {
 	Verify     bool      `json:"verify"`     // 问卷是否需要统一验证
 	Type       uint      `json:"type"`       // 问卷类型 0:调研 1:投票
 	Num        int       `json:"num"`        // 问卷填写数量
+	Challenge  int       `json:"challenge"`  // 提交前的人机验证 0:不验证 1:工作量证明 2:图片验证码
+
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"` // 删除时间, 删除后进入回收站
 }
 
 // SurveyResp 问卷响应模型
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// 上传文件状态
+const (
+	UploadPending  = 1 // 待关联: 已上传但问卷或答卷尚未保存
+	UploadAttached = 2 // 已关联: 被问卷或答卷引用
+	UploadInfected = 3 // 已隔离: 检出威胁, 不提供下载也不会被自动清理
+)
+
+// 上传文件安全扫描状态, 为空表示未扫描
+const (
+	ScanSkipped  = "skipped"  // 未开启扫描
+	ScanClean    = "clean"    // 未检出威胁
+	ScanInfected = "infected" // 检出威胁
+	ScanFailed   = "failed"   // 扫描服务不可用, 按配置放行
+)
+
+// Upload 上传文件登记
+type Upload struct {
+	ID          int        `json:"id"`
+	Key         string     `json:"key" gorm:"size:255;uniqueIndex"` // 存储中的 key
+	SurveyID    int        `json:"survey_id"`                       // 问卷ID, 管理员上传的图片在问卷保存时关联
+	QuestionID  int        `json:"question_id"`                     // 问题ID, 管理员上传的图片为0
+	Uploader    string     `json:"uploader" gorm:"size:64"`         // 上传者, 统一验证的学号、上传凭证的摘要或管理员
+	Size        int64      `json:"size"`                            // 文件大小
+	ContentType string     `json:"content_type" gorm:"size:128"`    // 检测到的 MIME 类型
+	Status      int        `json:"status" gorm:"default:1"`         // 状态 1待关联 2已关联 3已隔离
+	ScanStatus  string     `json:"scan_status" gorm:"size:16"`      // 安全扫描状态
+	ScanResult  string     `json:"scan_result" gorm:"size:255"`     // 检出的威胁名称或扫描失败原因
+	CreatedAt   time.Time  `json:"created_at"`                      // 上传时间
+	AttachedAt  *time.Time `json:"attached_at"`                     // 关联时间
+	ScannedAt   *time.Time `json:"scanned_at"`                      // 扫描时间
+}
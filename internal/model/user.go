@@ -2,8 +2,12 @@ package model
 
 // User 用户模型
 type User struct {
-	ID        int    `json:"id"`         // 用户id
-	Username  string `json:"username"`   // 用户名
-	Password  string `json:"password"`   // 密码
-	AdminType int    `json:"admin_type"` // 1:普通管理员	2:超级管理员
+	ID                 int    `json:"id"`                   // 用户id
+	Username           string `json:"username"`             // 用户名
+	Password           string `json:"-"`                    // 密码哈希
+	AdminType          int    `json:"admin_type"`           // 1:普通管理员	2:超级管理员
+	MustChangePassword bool   `json:"must_change_password"` // 是否需要在登录后修改密码
+	Disabled           bool   `json:"disabled"`             // 账号是否已停用
+	TOTPSecret         string `json:"-"`                    // 两步验证密钥
+	TOTPEnabled        bool   `json:"totp_enabled"`         // 是否已开启两步验证
 }
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// Webhook 投递状态
+const (
+	DeliveryPending = 1 // 待投递或等待重试
+	DeliverySuccess = 2 // 投递成功
+	DeliveryFailed  = 3 // 最近一次投递失败
+)
+
+// Webhook 问卷事件推送地址模型
+type Webhook struct {
+	ID            int       `json:"id"`                          // Webhook id
+	SurveyID      int       `json:"survey_id" gorm:"index"`      // 问卷id
+	UserID        int       `json:"user_id"`                     // 创建者id
+	URL           string    `json:"url" gorm:"size:512"`         // 推送地址
+	Secret        string    `json:"-" gorm:"size:64"`            // 签名密钥
+	Events        string    `json:"events" gorm:"size:255"`      // 订阅的事件, 以逗号分隔
+	Quota         int       `json:"quota"`                       // 答卷数达到该值时推送 quota.reached, 为0时不推送
+	QuotaNotified bool      `json:"quota_notified"`              // 是否已推送 quota.reached
+	Enabled       bool      `json:"enabled" gorm:"default:true"` // 是否启用
+	CreatedAt     time.Time `json:"created_at"`                  // 创建时间
+	UpdatedAt     time.Time `json:"updated_at"`                  // 更新时间
+}
+
+// WebhookDelivery Webhook 投递记录模型
+type WebhookDelivery struct {
+	ID           int        `json:"id"`                             // 投递id
+	WebhookID    int        `json:"webhook_id" gorm:"index"`        // Webhook id
+	SurveyID     int        `json:"survey_id"`                      // 问卷id
+	Event        string     `json:"event" gorm:"size:64"`           // 事件类型
+	Payload      string     `json:"payload" gorm:"type:mediumtext"` // 请求体, 重新投递时原样发送
+	Status       int        `json:"status" gorm:"default:1"`        // 状态 1待投递 2成功 3失败
+	Attempts     int        `json:"attempts"`                       // 已投递次数
+	ResponseCode int        `json:"response_code"`                  // 最近一次响应状态码
+	ResponseBody string     `json:"response_body" gorm:"size:1024"` // 最近一次响应内容, 超出部分截断
+	Error        string     `json:"error" gorm:"size:512"`          // 最近一次失败原因
+	CreatedAt    time.Time  `json:"created_at" gorm:"index"`        // 创建时间
+	DeliveredAt  *time.Time `json:"delivered_at"`                   // 最近一次投递时间
+}
@@ -2,8 +2,10 @@ package userCenterApi
 
 import global "QA-System/internal/global/config"
 
-// UserCenterHost 用户中心地址
-var UserCenterHost = global.Config.GetString("user.host")
+// UserCenterHost 获取用户中心地址
+func UserCenterHost() string {
+	return global.Config.GetString("user.host")
+}
 
 // 用户中心接口
 const (
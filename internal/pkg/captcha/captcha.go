@@ -0,0 +1,144 @@
+package captcha
+
+import (
+	"bytes"
+	"crypto/rand"
+	"image"
+	"image/color"
+	"image/png"
+	"math/big"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// alphabet 验证码字符, 去掉了容易混淆的 0、1、I、O
+const alphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+
+// 图片尺寸与字符放大倍数
+const (
+	width  = 160
+	height = 60
+	scale  = 3
+)
+
+// Generate 生成长度为 length 的验证码及其 PNG 图片
+func Generate(length int) (string, []byte, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := randInt(len(alphabet))
+		if err != nil {
+			return "", nil, err
+		}
+		code[i] = alphabet[n]
+	}
+	img, err := render(string(code))
+	if err != nil {
+		return "", nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", nil, err
+	}
+	return string(code), buf.Bytes(), nil
+}
+
+// render 逐个字符放大后随机偏移绘制, 并加入干扰线与噪点
+func render(code string) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 245, G: 245, B: 240, A: 255}), image.Point{}, draw.Src)
+
+	face := basicfont.Face7x13
+	glyphW, glyphH := face.Advance, face.Height
+	step := (width - 10) / len(code)
+	for i, ch := range code {
+		glyph := image.NewRGBA(image.Rect(0, 0, glyphW, glyphH))
+		ink, err := randColor(0, 120)
+		if err != nil {
+			return nil, err
+		}
+		d := &font.Drawer{Dst: glyph, Src: image.NewUniform(ink), Face: face, Dot: fixed.P(0, face.Ascent)}
+		d.DrawString(string(ch))
+
+		dx, err := randInt(step - glyphW*scale + 8)
+		if err != nil {
+			return nil, err
+		}
+		dy, err := randInt(height - glyphH*scale + 1)
+		if err != nil {
+			return nil, err
+		}
+		x := 5 + i*step + dx - 4
+		rect := image.Rect(x, dy, x+glyphW*scale, dy+glyphH*scale)
+		draw.ApproxBiLinear.Scale(img, rect, glyph, glyph.Bounds(), draw.Over, nil)
+	}
+
+	for range 4 {
+		if err := drawLine(img); err != nil {
+			return nil, err
+		}
+	}
+	for range width * height / 20 {
+		x, err := randInt(width)
+		if err != nil {
+			return nil, err
+		}
+		y, err := randInt(height)
+		if err != nil {
+			return nil, err
+		}
+		c, err := randColor(80, 220)
+		if err != nil {
+			return nil, err
+		}
+		img.Set(x, y, c)
+	}
+	return img, nil
+}
+
+// drawLine 绘制一条横穿图片的干扰线
+func drawLine(img *image.RGBA) error {
+	y0, err := randInt(height)
+	if err != nil {
+		return err
+	}
+	y1, err := randInt(height)
+	if err != nil {
+		return err
+	}
+	c, err := randColor(40, 160)
+	if err != nil {
+		return err
+	}
+	for x := 0; x < width; x++ {
+		y := y0 + (y1-y0)*x/width
+		img.Set(x, y, c)
+		img.Set(x, y+1, c)
+	}
+	return nil
+}
+
+func randInt(n int) (int, error) {
+	if n <= 1 {
+		return 0, nil
+	}
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+func randColor(lo, hi int) (color.RGBA, error) {
+	var rgb [3]uint8
+	for i := range rgb {
+		v, err := randInt(hi - lo)
+		if err != nil {
+			return color.RGBA{}, err
+		}
+		rgb[i] = uint8(lo + v)
+	}
+	return color.RGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}, nil
+}
@@ -53,6 +53,34 @@ var (
 	VoteSumLimitError            = NewError(200531, log.LevelInfo, "总投票次数已达上限")
 	NotUnderGraduateError        = NewError(200532, log.LevelInfo, "当前问卷仅允许本科生提交")
 	WrongOauthUsernameOrPassword = NewError(200534, log.LevelInfo, "统一登录账号或密码错误")
+	ExportJobNotExist            = NewError(200535, log.LevelInfo, "导出任务不存在或已过期")
+	ExportNotFinished            = NewError(200536, log.LevelInfo, "导出任务尚未完成，请稍后重试")
+	SurveyImportError            = NewError(200537, log.LevelInfo, "导入文件内容有误，请根据提示修改")
+	ImportFileTypeError          = NewError(200538, log.LevelInfo, "仅支持导入 JSON、YAML 或 Excel 文件")
+	PasswordChangeRequired       = NewError(200539, log.LevelInfo, "当前密码为临时密码，请先修改密码")
+	UserDisabled                 = NewError(200540, log.LevelInfo, "该账号已被停用")
+	UserHasSurveys               = NewError(200541, log.LevelInfo, "该用户仍有问卷，请先指定问卷接收人")
+	OperateSelfError             = NewError(200542, log.LevelInfo, "不能对自己的账号执行该操作")
+	InvitationInvalid            = NewError(200543, log.LevelInfo, "邀请码无效或已过期")
+	LoginLocked                  = NewError(200544, log.LevelWarn, "登录失败次数过多，请稍后再试")
+	TwoFactorRequired            = NewError(200545, log.LevelInfo, "请输入两步验证码")
+	TwoFactorInvalid             = NewError(200546, log.LevelInfo, "两步验证码错误")
+	TwoFactorEnrollRequired      = NewError(200547, log.LevelInfo, "请先开启两步验证")
+	TwoFactorNotPending          = NewError(200548, log.LevelInfo, "两步验证绑定已过期，请重新获取")
+	APITokenInvalid              = NewError(200549, log.LevelInfo, "API 令牌无效或已过期")
+	APITokenScopeError           = NewError(200550, log.LevelInfo, "API 令牌无权访问该接口")
+	FileTypeError                = NewError(200551, log.LevelInfo, "文件类型不允许上传")
+	UploadQuotaError             = NewError(200552, log.LevelInfo, "上传文件总大小超出限制")
+	FileCountError               = NewError(200553, log.LevelInfo, "文件数量超出限制")
+	UploadMismatchError          = NewError(200554, log.LevelInfo, "答案中的文件不属于该问题")
+	InfectedFileError            = NewError(200555, log.LevelWarn, "文件未通过安全扫描")
+	ScanUnavailableError         = NewError(200556, log.LevelError, "文件安全扫描暂不可用，请稍后重试")
+	WebhookNotExist              = NewError(200557, log.LevelInfo, "Webhook 不存在")
+	WebhookURLError              = NewError(200558, log.LevelInfo, "推送地址必须为 http 或 https 地址")
+	EmailFormatError             = NewError(200559, log.LevelInfo, "邮箱格式错误")
+	ChallengeRequired            = NewError(200560, log.LevelInfo, "请先完成人机验证")
+	ChallengeFailed              = NewError(200561, log.LevelInfo, "人机验证未通过，请重新验证")
+	SubmitTooFrequent            = NewError(200562, log.LevelWarn, "提交过于频繁，请稍后再试")
 	NotFound                     = NewError(200404, log.LevelInfo, http.StatusText(http.StatusNotFound))
 )
 
@@ -0,0 +1,177 @@
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// schemaVersionCollection 已执行的迁移记录集合
+const schemaVersionCollection = "schema_version"
+
+// Migration MongoDB 迁移, 集合名在初始化后才能确定, 因此以函数形式定义
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, mdb *mongo.Database) error
+	Down    func(ctx context.Context, mdb *mongo.Database) error
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"` // 为空表示尚未执行
+}
+
+type schemaVersion struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// migrations 全部迁移, 新的迁移只能追加在末尾
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "add_answer_sheet_indexes",
+		Up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection(QA).Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.D{{Key: "surveyid", Value: 1}}, Options: options.Index().SetName("surveyid_1")},
+				{Keys: bson.D{{Key: "answers.questionid", Value: 1}},
+					Options: options.Index().SetName("answers.questionid_1")},
+			})
+			return err
+		},
+		Down: func(ctx context.Context, mdb *mongo.Database) error {
+			return dropIndexes(ctx, mdb.Collection(QA), "surveyid_1", "answers.questionid_1")
+		},
+	},
+	{
+		Version: 2,
+		Name:    "add_record_sheet_indexes",
+		Up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection(Record).Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "survey_id", Value: 1}}, Options: options.Index().SetName("survey_id_1"),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, mdb *mongo.Database) error {
+			return dropIndexes(ctx, mdb.Collection(Record), "survey_id_1")
+		},
+	},
+	{
+		Version: 3,
+		Name:    "unset_null_deleted_at",
+		// 回收站以 deleted_at 字段是否存在区分答卷, 显式写入 null 的答卷会被误认为已删除
+		Up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection(QA).UpdateMany(ctx, bson.M{"deleted_at": bson.M{"$type": "null"}},
+				bson.M{"$unset": bson.M{"deleted_at": ""}})
+			return err
+		},
+		Down: func(context.Context, *mongo.Database) error {
+			return nil
+		},
+	},
+}
+
+func dropIndexes(ctx context.Context, coll *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		if _, err := coll.Indexes().DropOne(ctx, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, mdb *mongo.Database) (map[int]schemaVersion, error) {
+	cur, err := mdb.Collection(schemaVersionCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var versions []schemaVersion
+	if err := cur.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]schemaVersion, len(versions))
+	for _, v := range versions {
+		applied[v.Version] = v
+	}
+	return applied, nil
+}
+
+// MigrateUp 执行尚未执行的迁移, steps 不大于 0 时执行全部
+func MigrateUp(ctx context.Context, mdb *mongo.Database, steps int) ([]Migration, error) {
+	applied, err := appliedVersions(ctx, mdb)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0)
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if steps > 0 && len(done) >= steps {
+			break
+		}
+		if err := m.Up(ctx, mdb); err != nil {
+			return done, err
+		}
+		_, err := mdb.Collection(schemaVersionCollection).InsertOne(ctx,
+			schemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()})
+		if err != nil {
+			return done, err
+		}
+		zap.L().Info("Mongo migration applied", zap.Int("version", m.Version), zap.String("name", m.Name))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown 回滚最近执行的迁移, steps 不大于 0 时回滚一个
+func MigrateDown(ctx context.Context, mdb *mongo.Database, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	applied, err := appliedVersions(ctx, mdb)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0)
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := m.Down(ctx, mdb); err != nil {
+			return done, err
+		}
+		if _, err := mdb.Collection(schemaVersionCollection).DeleteOne(ctx, bson.M{"_id": m.Version}); err != nil {
+			return done, err
+		}
+		zap.L().Info("Mongo migration reverted", zap.Int("version", m.Version), zap.String("name", m.Name))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// GetMigrationStatus 获取全部迁移的执行状态
+func GetMigrationStatus(ctx context.Context, mdb *mongo.Database) ([]MigrationStatus, error) {
+	applied, err := appliedVersions(ctx, mdb)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if v, ok := applied[m.Version]; ok {
+			s.AppliedAt = &v.AppliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
@@ -48,3 +48,13 @@ func Init() *mongo.Database {
 	zap.L().Info("Connected to MongoDB")
 	return mdb
 }
+
+// AutoMigrate 启动时执行尚未执行的迁移, 配置关闭后需通过 migrate 命令手动执行
+func AutoMigrate(mdb *mongo.Database) {
+	if config.Config.IsSet("mongodb.auto-migrate") && !config.Config.GetBool("mongodb.auto-migrate") {
+		return
+	}
+	if _, err := MigrateUp(context.TODO(), mdb, 0); err != nil {
+		zap.L().Fatal("Failed to migrate MongoDB:" + err.Error())
+	}
+}
@@ -0,0 +1,269 @@
+package mysql
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration 数据库迁移, 文件名格式为 <版本号>_<名称>.up.sql 与 <版本号>_<名称>.down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus 迁移的执行状态
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"` // 为空表示尚未执行
+}
+
+// schemaVersion 已执行的迁移记录
+type schemaVersion struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName 迁移记录表名
+func (schemaVersion) TableName() string {
+	return "schema_version"
+}
+
+// loadMigrations 读取内嵌的迁移文件并按版本排序
+func loadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("迁移文件名格式错误: %s", name)
+		}
+		versionStr, migrationName, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("迁移文件名格式错误: %s", name)
+		}
+		content, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: migrationName}
+			byVersion[version] = m
+		} else if m.Name != migrationName {
+			return nil, fmt.Errorf("迁移版本 %d 重复", version)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements 按行尾分号拆分 SQL 语句, 并去除整行注释
+func splitStatements(sql string) []string {
+	statements := make([]string, 0)
+	var sb strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(sb.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// appliedVersions 获取已执行的迁移
+func appliedVersions(db *gorm.DB) (map[int]schemaVersion, error) {
+	err := db.Exec("CREATE TABLE IF NOT EXISTS `schema_version` (" +
+		"`version` bigint NOT NULL, `name` varchar(255), `applied_at` datetime(3) NULL, PRIMARY KEY (`version`))").Error
+	if err != nil {
+		return nil, err
+	}
+	var versions []schemaVersion
+	if err := db.Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int]schemaVersion, len(versions))
+	for _, v := range versions {
+		applied[v.Version] = v
+	}
+	return applied, nil
+}
+
+// runStatements 依次执行迁移语句
+// MySQL 的 DDL 会隐式提交, 无法整体回滚, 失败时需根据日志手动处理后重试
+func runStatements(db *gorm.DB, m Migration, sql string) error {
+	for _, statement := range splitStatements(sql) {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("迁移 %04d_%s 执行失败: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// migrateLockName 迁移时持有的 MySQL 命名锁, serve 与 worker 同时启动时依次执行迁移
+const migrateLockName = "qa_system_migrate"
+
+// migrateLockTimeout 等待迁移锁的秒数
+const migrateLockTimeout = 300
+
+// withMigrateLock 在同一连接上持有迁移锁并执行 fn, 命名锁与连接绑定, 因此 fn 需使用传入的 conn
+func withMigrateLock(db *gorm.DB, fn func(conn *gorm.DB) error) error {
+	return db.Connection(func(conn *gorm.DB) error {
+		var locked sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrateLockName, migrateLockTimeout).Row().Scan(&locked); err != nil {
+			return err
+		}
+		if !locked.Valid || locked.Int64 != 1 {
+			return errors.New("等待迁移锁超时, 其他实例可能正在执行迁移")
+		}
+		defer func() {
+			if err := conn.Exec("SELECT RELEASE_LOCK(?)", migrateLockName).Error; err != nil {
+				zap.L().Error("Failed to release migration lock", zap.Error(err))
+			}
+		}()
+		return fn(conn)
+	})
+}
+
+// MigrateUp 执行尚未执行的迁移, steps 不大于 0 时执行全部
+func MigrateUp(db *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0)
+	err = withMigrateLock(db, func(conn *gorm.DB) error {
+		done, err = migrateUp(conn, migrations, steps)
+		return err
+	})
+	return done, err
+}
+
+// migrateUp 在持有迁移锁时执行尚未执行的迁移
+func migrateUp(db *gorm.DB, migrations []Migration, steps int) ([]Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0)
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if steps > 0 && len(done) >= steps {
+			break
+		}
+		if err := runStatements(db, m, m.Up); err != nil {
+			return done, err
+		}
+		err := db.Create(&schemaVersion{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		if err != nil {
+			return done, err
+		}
+		zap.L().Info("Migration applied", zap.Int("version", m.Version), zap.String("name", m.Name))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// MigrateDown 回滚最近执行的迁移, steps 不大于 0 时回滚一个
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0)
+	err = withMigrateLock(db, func(conn *gorm.DB) error {
+		done, err = migrateDown(conn, migrations, steps)
+		return err
+	})
+	return done, err
+}
+
+// migrateDown 在持有迁移锁时回滚最近执行的迁移
+func migrateDown(db *gorm.DB, migrations []Migration, steps int) ([]Migration, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]Migration, 0)
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		if err := runStatements(db, m, m.Down); err != nil {
+			return done, err
+		}
+		if err := db.Delete(&schemaVersion{}, m.Version).Error; err != nil {
+			return done, err
+		}
+		zap.L().Info("Migration reverted", zap.Int("version", m.Version), zap.String("name", m.Name))
+		done = append(done, m)
+	}
+	return done, nil
+}
+
+// GetMigrationStatus 获取全部迁移的执行状态
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+		if v, ok := applied[m.Version]; ok {
+			s.AppliedAt = &v.AppliedAt
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
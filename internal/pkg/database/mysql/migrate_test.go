@@ -0,0 +1,50 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("迁移版本不连续: %04d_%s 应为 %04d", m.Version, m.Name, i+1)
+		}
+		if len(splitStatements(m.Up)) == 0 || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("迁移 %04d_%s 缺少 up 或 down 语句", m.Version, m.Name)
+		}
+	}
+}
+
+// 初始迁移必须与引入迁移前发布的版本一致, 升级时已有的表会被跳过, 此后的字段需由后续迁移添加
+func TestInitMigrationBaseline(t *testing.T) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	initSQL := migrations[0].Up
+	for _, column := range []string{"must_change_password", "disabled", "totp_secret", "totp_enabled",
+		"deleted_at", "role"} {
+		if strings.Contains(initSQL, "`"+column+"`") {
+			t.Errorf("初始迁移不应包含此后新增的字段 %s", column)
+		}
+	}
+	for _, table := range []string{"invitations", "settings", "recovery_codes", "api_tokens", "audit_logs",
+		"outbox_events"} {
+		if strings.Contains(initSQL, "`"+table+"`") {
+			t.Errorf("初始迁移不应包含此后新增的表 %s", table)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("-- 注释\nALTER TABLE `a`\n  ADD COLUMN `b` bigint;\n\nUPDATE `a` SET `b` = 1;\nSELECT 1")
+	want := []string{"ALTER TABLE `a`\n  ADD COLUMN `b` bigint;", "UPDATE `a` SET `b` = 1;", "SELECT 1"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("拆分结果为 %q", got)
+	}
+}
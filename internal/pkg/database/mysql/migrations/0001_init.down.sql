@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS `pres`;
+DROP TABLE IF EXISTS `manages`;
+DROP TABLE IF EXISTS `options`;
+DROP TABLE IF EXISTS `questions`;
+DROP TABLE IF EXISTS `surveys`;
+DROP TABLE IF EXISTS `users`;
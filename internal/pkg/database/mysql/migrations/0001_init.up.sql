@@ -0,0 +1,66 @@
+-- 初始表结构, 与引入版本化迁移前最后发布的版本中 GORM AutoMigrate 生成的结构一致
+-- 从该版本升级时表均已存在而被跳过, 此后新增的字段与表由后续迁移逐个添加
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `username` longtext,
+  `password` longtext,
+  `admin_type` bigint,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `surveys` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint,
+  `title` longtext,
+  `desc` longtext,
+  `start_time` datetime(3) NULL,
+  `deadline` datetime(3) NULL,
+  `status` bigint,
+  `daily_limit` bigint unsigned,
+  `sum_limit` bigint unsigned,
+  `verify` boolean,
+  `type` bigint unsigned,
+  `num` bigint,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `questions` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `survey_id` bigint,
+  `serial_num` bigint,
+  `img` longtext,
+  `subject` longtext,
+  `description` longtext,
+  `required` boolean,
+  `unique` boolean,
+  `other_option` boolean,
+  `question_type` bigint,
+  `maximum_option` bigint unsigned,
+  `minimum_option` bigint unsigned,
+  `reg` longtext,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `options` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `question_id` bigint,
+  `serial_num` bigint,
+  `content` longtext,
+  `description` longtext,
+  `img` longtext,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `manages` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint,
+  `survey_id` bigint,
+  PRIMARY KEY (`id`)
+);
+
+CREATE TABLE IF NOT EXISTS `pres` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `type` longtext,
+  `value` longtext,
+  PRIMARY KEY (`id`)
+);
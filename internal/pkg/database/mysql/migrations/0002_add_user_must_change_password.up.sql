@@ -0,0 +1,3 @@
+-- 管理员使用一次性密码登录后需先修改密码
+ALTER TABLE `users`
+  ADD COLUMN `must_change_password` tinyint(1) NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE `manages`
+  DROP COLUMN `role`;
@@ -0,0 +1,3 @@
+-- 问卷协作者角色 1:查看者 2:分析者 3:编辑者 4:所有者, 已有的权限记录为编辑者
+ALTER TABLE `manages`
+  ADD COLUMN `role` bigint NOT NULL DEFAULT 3;
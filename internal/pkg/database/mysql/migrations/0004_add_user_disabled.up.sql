@@ -0,0 +1,3 @@
+-- 超级管理员可停用管理员账号
+ALTER TABLE `users`
+  ADD COLUMN `disabled` tinyint(1) NOT NULL DEFAULT 0;
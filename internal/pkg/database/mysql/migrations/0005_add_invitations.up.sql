@@ -0,0 +1,16 @@
+-- 一次性管理员邀请码, 只保存邀请码的哈希
+CREATE TABLE IF NOT EXISTS `invitations` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `code_hash` char(64),
+  `username` longtext,
+  `admin_type` bigint,
+  `created_by` bigint,
+  `created_at` datetime(3) NULL,
+  `expire_at` datetime(3) NULL,
+  `used_by` bigint,
+  `used_at` datetime(3) NULL,
+  `used_ip` longtext,
+  `revoked` boolean,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_invitations_code_hash` (`code_hash`)
+);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS `recovery_codes`;
+DROP TABLE IF EXISTS `settings`;
+
+ALTER TABLE `users`
+  DROP COLUMN `totp_enabled`,
+  DROP COLUMN `totp_secret`;
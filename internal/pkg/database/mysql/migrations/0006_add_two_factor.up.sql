@@ -0,0 +1,21 @@
+-- 管理员两步验证
+ALTER TABLE `users`
+  ADD COLUMN `totp_secret` varchar(64) NOT NULL DEFAULT '',
+  ADD COLUMN `totp_enabled` tinyint(1) NOT NULL DEFAULT 0;
+
+-- 系统设置, 如是否要求超级管理员开启两步验证
+CREATE TABLE IF NOT EXISTS `settings` (
+  `key` varchar(64) NOT NULL,
+  `value` longtext,
+  PRIMARY KEY (`key`)
+);
+
+-- 两步验证的恢复码, 只保存哈希
+CREATE TABLE IF NOT EXISTS `recovery_codes` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint,
+  `code_hash` char(64),
+  `used_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_recovery_codes_user_id` (`user_id`)
+);
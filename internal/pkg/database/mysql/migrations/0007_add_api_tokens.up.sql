@@ -0,0 +1,16 @@
+-- 管理员的个人访问令牌, 只保存令牌的哈希
+CREATE TABLE IF NOT EXISTS `api_tokens` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint,
+  `name` longtext,
+  `prefix` longtext,
+  `token_hash` char(64),
+  `scopes` longtext,
+  `created_at` datetime(3) NULL,
+  `expire_at` datetime(3) NULL,
+  `last_used_at` datetime(3) NULL,
+  `revoked` boolean,
+  PRIMARY KEY (`id`),
+  INDEX `idx_api_tokens_user_id` (`user_id`),
+  UNIQUE INDEX `idx_api_tokens_token_hash` (`token_hash`)
+);
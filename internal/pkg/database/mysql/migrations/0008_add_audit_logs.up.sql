@@ -0,0 +1,19 @@
+-- 管理操作审计日志
+CREATE TABLE IF NOT EXISTS `audit_logs` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint,
+  `username` longtext,
+  `token_id` bigint,
+  `action` varchar(64),
+  `survey_id` bigint,
+  `target_type` varchar(32),
+  `target_id` varchar(64),
+  `ip` longtext,
+  `diff` mediumtext,
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_audit_logs_user_id` (`user_id`),
+  INDEX `idx_audit_logs_action` (`action`),
+  INDEX `idx_audit_logs_survey_id` (`survey_id`),
+  INDEX `idx_audit_logs_created_at` (`created_at`)
+);
@@ -0,0 +1,4 @@
+DROP INDEX `idx_surveys_deleted_at` ON `surveys`;
+
+ALTER TABLE `surveys`
+  DROP COLUMN `deleted_at`;
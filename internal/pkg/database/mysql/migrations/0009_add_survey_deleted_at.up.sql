@@ -0,0 +1,5 @@
+-- 问卷删除后进入回收站
+ALTER TABLE `surveys`
+  ADD COLUMN `deleted_at` datetime(3) NULL;
+
+CREATE INDEX `idx_surveys_deleted_at` ON `surveys` (`deleted_at`);
@@ -0,0 +1,13 @@
+-- 与业务数据在同一事务中写入的待处理事件
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `type` varchar(64),
+  `payload` text,
+  `attempts` bigint,
+  `last_error` text,
+  `created_at` datetime(3) NULL,
+  `processed_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_outbox_events_created_at` (`created_at`),
+  INDEX `idx_outbox_events_processed_at` (`processed_at`)
+);
@@ -0,0 +1,4 @@
+DROP INDEX `idx_manages_survey_id` ON `manages`;
+DROP INDEX `idx_manages_user_survey` ON `manages`;
+DROP INDEX `idx_options_question_id` ON `options`;
+DROP INDEX `idx_questions_survey_id` ON `questions`;
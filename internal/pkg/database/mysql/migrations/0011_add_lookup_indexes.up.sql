@@ -0,0 +1,9 @@
+-- 问题、选项与权限记录的常用查询条件
+CREATE INDEX `idx_questions_survey_id` ON `questions` (`survey_id`);
+CREATE INDEX `idx_options_question_id` ON `options` (`question_id`);
+
+-- 同一用户对同一问卷只保留最新的一条权限记录, 然后添加唯一索引
+DELETE m1 FROM `manages` m1
+  JOIN `manages` m2 ON m1.`user_id` = m2.`user_id` AND m1.`survey_id` = m2.`survey_id` AND m1.`id` < m2.`id`;
+CREATE UNIQUE INDEX `idx_manages_user_survey` ON `manages` (`user_id`, `survey_id`);
+CREATE INDEX `idx_manages_survey_id` ON `manages` (`survey_id`);
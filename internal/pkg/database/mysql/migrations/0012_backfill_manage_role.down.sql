@@ -0,0 +1 @@
+-- 回填的数据无需回滚
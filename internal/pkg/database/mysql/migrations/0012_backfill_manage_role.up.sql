@@ -0,0 +1,2 @@
+-- 添加角色前创建的权限记录角色为空, 统一设为编辑者
+UPDATE `manages` SET `role` = 3 WHERE `role` IS NULL OR `role` = 0;
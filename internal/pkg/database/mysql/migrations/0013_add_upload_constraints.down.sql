@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS `uploads`;
+
+ALTER TABLE `questions`
+  DROP COLUMN `upload_quota`,
+  DROP COLUMN `max_file_count`,
+  DROP COLUMN `max_file_size`,
+  DROP COLUMN `file_types`;
@@ -0,0 +1,21 @@
+-- 图片题与文件题的上传限制
+ALTER TABLE `questions`
+  ADD COLUMN `file_types` varchar(255) NOT NULL DEFAULT '',
+  ADD COLUMN `max_file_size` bigint NOT NULL DEFAULT 0,
+  ADD COLUMN `max_file_count` bigint unsigned NOT NULL DEFAULT 0,
+  ADD COLUMN `upload_quota` bigint NOT NULL DEFAULT 0;
+
+-- 答卷人上传的文件, 提交时校验答案中的文件是否上传到了对应问题
+CREATE TABLE IF NOT EXISTS `uploads` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `key` varchar(255) NOT NULL,
+  `survey_id` bigint NOT NULL DEFAULT 0,
+  `question_id` bigint NOT NULL DEFAULT 0,
+  `uploader` varchar(64) NOT NULL DEFAULT '',
+  `size` bigint NOT NULL DEFAULT 0,
+  `content_type` varchar(128) NOT NULL DEFAULT '',
+  `created_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_uploads_key` (`key`),
+  INDEX `idx_uploads_question_uploader` (`question_id`, `uploader`)
+);
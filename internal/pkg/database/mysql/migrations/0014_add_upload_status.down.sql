@@ -0,0 +1,5 @@
+DROP INDEX `idx_uploads_status_created_at` ON `uploads`;
+
+ALTER TABLE `uploads`
+  DROP COLUMN `attached_at`,
+  DROP COLUMN `status`;
@@ -0,0 +1,9 @@
+-- 上传文件登记状态, 长时间未关联到问卷或答卷的文件会被定时清理
+ALTER TABLE `uploads`
+  ADD COLUMN `status` bigint NOT NULL DEFAULT 1,
+  ADD COLUMN `attached_at` datetime(3) NULL;
+
+-- 已有记录无法确定是否已被答卷引用, 统一视为已关联, 避免被误删
+UPDATE `uploads` SET `status` = 2, `attached_at` = `created_at`;
+
+CREATE INDEX `idx_uploads_status_created_at` ON `uploads` (`status`, `created_at`);
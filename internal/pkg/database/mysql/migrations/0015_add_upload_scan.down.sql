@@ -0,0 +1,4 @@
+ALTER TABLE `uploads`
+  DROP COLUMN `scanned_at`,
+  DROP COLUMN `scan_result`,
+  DROP COLUMN `scan_status`;
@@ -0,0 +1,5 @@
+-- 上传文件的安全扫描结果, 升级前上传的文件为空, 表示未扫描
+ALTER TABLE `uploads`
+  ADD COLUMN `scan_status` varchar(16) NOT NULL DEFAULT '',
+  ADD COLUMN `scan_result` varchar(255) NOT NULL DEFAULT '',
+  ADD COLUMN `scanned_at` datetime(3) NULL;
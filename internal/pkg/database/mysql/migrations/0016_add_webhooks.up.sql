@@ -0,0 +1,35 @@
+-- 问卷事件推送地址
+CREATE TABLE IF NOT EXISTS `webhooks` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `survey_id` bigint NOT NULL DEFAULT 0,
+  `user_id` bigint NOT NULL DEFAULT 0,
+  `url` varchar(512) NOT NULL DEFAULT '',
+  `secret` varchar(64) NOT NULL DEFAULT '',
+  `events` varchar(255) NOT NULL DEFAULT '',
+  `quota` bigint NOT NULL DEFAULT 0,
+  `quota_notified` tinyint(1) NOT NULL DEFAULT 0,
+  `enabled` tinyint(1) NOT NULL DEFAULT 1,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_webhooks_survey_id` (`survey_id`)
+);
+
+-- Webhook 投递记录, 保留请求体以便重新投递
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `webhook_id` bigint NOT NULL DEFAULT 0,
+  `survey_id` bigint NOT NULL DEFAULT 0,
+  `event` varchar(64) NOT NULL DEFAULT '',
+  `payload` mediumtext,
+  `status` bigint NOT NULL DEFAULT 1,
+  `attempts` bigint NOT NULL DEFAULT 0,
+  `response_code` bigint NOT NULL DEFAULT 0,
+  `response_body` varchar(1024) NOT NULL DEFAULT '',
+  `error` varchar(512) NOT NULL DEFAULT '',
+  `created_at` datetime(3) NULL,
+  `delivered_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  INDEX `idx_webhook_deliveries_webhook_id` (`webhook_id`),
+  INDEX `idx_webhook_deliveries_created_at` (`created_at`)
+);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `notifications`;
+
+ALTER TABLE `questions`
+  DROP COLUMN `send_copy`;
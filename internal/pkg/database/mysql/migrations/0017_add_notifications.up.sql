@@ -0,0 +1,17 @@
+-- 填空题可作为邮箱题, 向答卷人发送答卷副本
+ALTER TABLE `questions`
+  ADD COLUMN `send_copy` tinyint(1) NOT NULL DEFAULT 0;
+
+-- 管理员订阅的问卷邮件通知
+CREATE TABLE IF NOT EXISTS `notifications` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `survey_id` bigint NOT NULL DEFAULT 0,
+  `user_id` bigint NOT NULL DEFAULT 0,
+  `email` varchar(255) NOT NULL DEFAULT '',
+  `mode` bigint NOT NULL DEFAULT 0,
+  `last_digest_at` datetime(3) NULL,
+  `created_at` datetime(3) NULL,
+  `updated_at` datetime(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE INDEX `idx_notifications_survey_user` (`survey_id`, `user_id`)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE `surveys`
+  DROP COLUMN `challenge`;
@@ -0,0 +1,3 @@
+-- 问卷提交前的人机验证方式 0:不验证 1:工作量证明 2:图片验证码
+ALTER TABLE `surveys`
+  ADD COLUMN `challenge` bigint NOT NULL DEFAULT 0;
@@ -26,10 +26,16 @@ func Init() *gorm.DB {
 		zap.L().Fatal("Failed to connect to MySQL:" + err.Error())
 	}
 
-	err = autoMigrate(db)
-	if err != nil {
-		zap.L().Fatal("DatabaseMigrateFailed" + err.Error())
-	}
 	zap.L().Info("Connected to MySQL")
 	return db
 }
+
+// AutoMigrate 启动时执行尚未执行的迁移, 配置关闭后需通过 migrate 命令手动执行
+func AutoMigrate(db *gorm.DB) {
+	if config.Config.IsSet("mysql.auto-migrate") && !config.Config.GetBool("mysql.auto-migrate") {
+		return
+	}
+	if _, err := MigrateUp(db, 0); err != nil {
+		zap.L().Fatal("DatabaseMigrateFailed" + err.Error())
+	}
+}
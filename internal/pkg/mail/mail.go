@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"QA-System/internal/global/config"
+)
+
+// Message 纯文本邮件
+type Message struct {
+	To      []string // 收件人地址
+	Subject string   // 主题
+	Body    string   // 正文
+}
+
+// Mailer 邮件发送
+type Mailer interface {
+	// Send 发送邮件, 服务不可用或收件人被拒绝时返回错误
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Sender 全局使用的邮件发送服务, 未配置 SMTP 时为 nil
+var Sender Mailer
+
+// Init 根据配置初始化邮件发送服务, smtp.host 为空时不发送邮件
+func Init() error {
+	host := config.Config.GetString("smtp.host")
+	if host == "" {
+		Sender = nil
+		return nil
+	}
+	port := 25
+	if config.Config.IsSet("smtp.port") {
+		port = config.Config.GetInt("smtp.port")
+	}
+	timeout := 30
+	if config.Config.IsSet("smtp.timeout") {
+		timeout = config.Config.GetInt("smtp.timeout")
+	}
+	sender, err := NewSMTP(SMTPConfig{
+		Host:     host,
+		Port:     port,
+		Username: config.Config.GetString("smtp.username"),
+		Password: config.Config.GetString("smtp.password"),
+		From:     config.Config.GetString("smtp.from"),
+		Security: config.Config.GetString("smtp.tls"),
+		Timeout:  time.Duration(timeout) * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	Sender = sender
+	return nil
+}
@@ -0,0 +1,182 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTP 连接方式
+const (
+	SecurityNone     = "none"     // 明文连接, 用于本机或内网的 SMTP 服务与测试收件箱
+	SecurityStartTLS = "starttls" // 连接后通过 STARTTLS 升级
+	SecurityTLS      = "tls"      // 直接建立 TLS 连接, 一般为 465 端口
+)
+
+// SMTPConfig SMTP 服务配置
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // 为空时不进行认证
+	Password string
+	From     string // 发件人, 可为 "名称 <地址>" 形式
+	Security string // 连接方式, 为空时为 none
+	Timeout  time.Duration
+}
+
+// SMTP 通过 SMTP 服务发送邮件
+type SMTP struct {
+	cfg  SMTPConfig
+	from *mail.Address
+}
+
+// NewSMTP 创建 SMTP 邮件发送服务
+func NewSMTP(cfg SMTPConfig) (*SMTP, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("发件人地址无效: %w", err)
+	}
+	if cfg.Security == "" {
+		cfg.Security = SecurityNone
+	}
+	if cfg.Security != SecurityNone && cfg.Security != SecurityStartTLS && cfg.Security != SecurityTLS {
+		return nil, fmt.Errorf("不支持的 SMTP 连接方式 %s", cfg.Security)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	return &SMTP{cfg: cfg, from: from}, nil
+}
+
+// Send 发送邮件
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("收件人为空")
+	}
+	to := make([]*mail.Address, 0, len(msg.To))
+	for _, raw := range msg.To {
+		addr, err := mail.ParseAddress(raw)
+		if err != nil {
+			return fmt.Errorf("收件人地址无效: %w", err)
+		}
+		to = append(to, addr)
+	}
+	data, err := s.build(to, msg)
+	if err != nil {
+		return err
+	}
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = client.Close() }()
+	if s.cfg.Security == SecurityStartTLS {
+		if err := client.StartTLS(&tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		// PlainAuth 只允许在 TLS 连接或本机地址上发送密码
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr.Address); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// dial 连接 SMTP 服务, 整个会话受超时限制
+func (s *SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	var conn net.Conn
+	var err error
+	if s.cfg.Security == SecurityTLS {
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    &tls.Config{ServerName: s.cfg.Host, MinVersion: tls.VersionTLS12},
+		}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(s.cfg.Timeout)); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// build 生成邮件内容, 主题与正文按 UTF-8 编码
+func (s *SMTP) build(to []*mail.Address, msg *Message) ([]byte, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	_, domain, _ := strings.Cut(s.from.Address, "@")
+	recipients := make([]string, 0, len(to))
+	for _, addr := range to {
+		recipients = append(recipients, addr.String())
+	}
+	// 去掉换行, 避免模板内容注入邮件头
+	subject := strings.Join(strings.Fields(msg.Subject), " ")
+
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		buf.WriteString(key + ": " + value + "\r\n")
+	}
+	header("From", s.from.String())
+	header("To", strings.Join(recipients, ", "))
+	header("Subject", mime.BEncoding.Encode("UTF-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", "<"+hex.EncodeToString(id)+"@"+domain+">")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=UTF-8")
+	header("Content-Transfer-Encoding", "base64")
+	buf.WriteString("\r\n")
+	body := base64.StdEncoding.EncodeToString([]byte(msg.Body))
+	for len(body) > 76 {
+		buf.WriteString(body[:76] + "\r\n")
+		body = body[76:]
+	}
+	buf.WriteString(body + "\r\n")
+	return buf.Bytes(), nil
+}
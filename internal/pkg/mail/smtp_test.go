@@ -0,0 +1,273 @@
+package mail
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sinkMessage 测试收件箱收到的邮件
+type sinkMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+// smtpSink 只实现发送所需命令的测试收件箱, 收件人以 reject 开头时拒收
+type smtpSink struct {
+	t    *testing.T
+	host string
+	port int
+
+	mu       sync.Mutex
+	messages []sinkMessage
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	addr := ln.Addr().(*net.TCPAddr)
+	s := &smtpSink{t: t, host: addr.IP.String(), port: addr.Port}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	tp := textproto.NewConn(conn)
+	reply := func(line string) { _ = tp.PrintfLine("%s", line) }
+	reply("220 sink ESMTP")
+	var msg sinkMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			reply("250-sink")
+			reply("250 8BITMIME")
+		case "MAIL":
+			// 忽略 BODY=8BITMIME 等参数
+			from, _, _ := strings.Cut(strings.TrimPrefix(arg, "FROM:"), " ")
+			msg = sinkMessage{From: from}
+			reply("250 OK")
+		case "RCPT":
+			to := strings.TrimPrefix(arg, "TO:")
+			if strings.HasPrefix(to, "<reject") {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			msg.To = append(msg.To, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 end with <CRLF>.<CRLF>")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpSink) Messages() []sinkMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sinkMessage(nil), s.messages...)
+}
+
+func newTestSMTP(t *testing.T, sink *smtpSink) *SMTP {
+	m, err := NewSMTP(SMTPConfig{
+		Host:     sink.host,
+		Port:     sink.port,
+		From:     "QA-System <noreply@example.com>",
+		Security: SecurityNone,
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// readMessage 解析收到的邮件, 返回解码后的主题与正文
+func readMessage(t *testing.T, data string) (*mail.Message, string, string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := io.ReadAll(msg.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 收件箱读取 DATA 时已将 CRLF 转换为 LF
+	lines := strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+	for _, line := range lines {
+		if len(line) > 76 {
+			t.Errorf("base64 行长度 %d 超出 76", len(line))
+		}
+	}
+	body, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg, subject, string(body)
+}
+
+func TestSMTPSend(t *testing.T) {
+	sink := newSMTPSink(t)
+	body := strings.Repeat("问卷「满意度调查」收到新的答卷。\n", 20)
+	err := newTestSMTP(t, sink).Send(context.Background(), &Message{
+		To:      []string{"张三 <a@example.com>", "b@example.com"},
+		Subject: "新的答卷",
+		Body:    body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := sink.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("收到 %d 封邮件", len(messages))
+	}
+	got := messages[0]
+	if got.From != "<noreply@example.com>" || strings.Join(got.To, ",") != "<a@example.com>,<b@example.com>" {
+		t.Fatalf("信封为 %s -> %v", got.From, got.To)
+	}
+	msg, subject, gotBody := readMessage(t, got.Data)
+	if subject != "新的答卷" {
+		t.Errorf("主题为 %q", subject)
+	}
+	if gotBody != body {
+		t.Errorf("正文为 %q", gotBody)
+	}
+	if msg.Header.Get("Content-Transfer-Encoding") != "base64" ||
+		msg.Header.Get("Content-Type") != "text/plain; charset=UTF-8" {
+		t.Errorf("邮件头为 %v", msg.Header)
+	}
+	to, err := msg.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[0].Name != "张三" || to[1].Address != "b@example.com" {
+		t.Errorf("收件人为 %v, %v", to, err)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID 为 %q", msg.Header.Get("Message-ID"))
+	}
+}
+
+func TestSMTPSubjectInjection(t *testing.T) {
+	sink := newSMTPSink(t)
+	err := newTestSMTP(t, sink).Send(context.Background(), &Message{
+		To:      []string{"a@example.com"},
+		Subject: "你好\r\nBcc: evil@example.com\nX-Test: 1",
+		Body:    "hi",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := sink.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("收到 %d 封邮件", len(messages))
+	}
+	msg, subject, _ := readMessage(t, messages[0].Data)
+	if msg.Header.Get("Bcc") != "" || msg.Header.Get("X-Test") != "" {
+		t.Fatalf("主题注入了邮件头: %v", msg.Header)
+	}
+	if subject != "你好 Bcc: evil@example.com X-Test: 1" {
+		t.Fatalf("主题为 %q", subject)
+	}
+	if strings.Join(messages[0].To, ",") != "<a@example.com>" {
+		t.Fatalf("收件人为 %v", messages[0].To)
+	}
+}
+
+func TestSMTPEmptyBody(t *testing.T) {
+	sink := newSMTPSink(t)
+	err := newTestSMTP(t, sink).Send(context.Background(), &Message{To: []string{"a@example.com"}, Subject: "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, body := readMessage(t, sink.Messages()[0].Data); body != "" {
+		t.Fatalf("正文为 %q", body)
+	}
+}
+
+func TestSMTPRejected(t *testing.T) {
+	sink := newSMTPSink(t)
+	m := newTestSMTP(t, sink)
+	err := m.Send(context.Background(), &Message{To: []string{"reject@example.com"}, Subject: "s", Body: "b"})
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("收件人被拒绝时应返回错误, 实际为 %v", err)
+	}
+	if err := m.Send(context.Background(), &Message{To: []string{"not an address"}}); err == nil {
+		t.Fatal("收件人地址无效时应返回错误")
+	}
+	if err := m.Send(context.Background(), &Message{}); err == nil {
+		t.Fatal("收件人为空时应返回错误")
+	}
+	if len(sink.Messages()) != 0 {
+		t.Fatal("出错时不应发送邮件")
+	}
+}
+
+func TestSMTPUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	_ = ln.Close()
+	m, err := NewSMTP(SMTPConfig{Host: "127.0.0.1", Port: port, From: "noreply@example.com", Timeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Send(context.Background(), &Message{To: []string{"a@example.com"}}); err == nil {
+		t.Fatal("SMTP 服务不可用时应返回错误")
+	}
+}
+
+func TestNewSMTPConfig(t *testing.T) {
+	if _, err := NewSMTP(SMTPConfig{From: "not an address"}); err == nil {
+		t.Error("发件人地址无效时应返回错误")
+	}
+	if _, err := NewSMTP(SMTPConfig{From: "a@example.com", Security: "ssl"}); err == nil {
+		t.Error("连接方式无效时应返回错误")
+	}
+	m, err := NewSMTP(SMTPConfig{From: "a@example.com"})
+	if err != nil || m.cfg.Security != SecurityNone || m.cfg.Timeout != 30*time.Second {
+		t.Errorf("默认配置为 %+v, %v", m, err)
+	}
+}
@@ -0,0 +1,37 @@
+package queue
+
+import (
+	"fmt"
+
+	"QA-System/internal/global/config"
+	"github.com/hibiken/asynq"
+)
+
+func getRedisConfig() asynq.RedisClientOpt {
+	host := "localhost"
+	port := "6379"
+	db := 0
+	if config.Config.IsSet("redis.host") {
+		host = config.Config.GetString("redis.host")
+	}
+	if config.Config.IsSet("redis.port") {
+		port = config.Config.GetString("redis.port")
+	}
+	if config.Config.IsSet("redis.db") {
+		db = config.Config.GetInt("redis.db")
+	}
+	// asynq 使用 redis.db+1, 避免与业务缓存混用
+	return asynq.RedisClientOpt{
+		Addr:     fmt.Sprintf("%s:%s", host, port),
+		Password: config.Config.GetString("redis.pass"),
+		DB:       db + 1,
+	}
+}
+
+func getConcurrency() int {
+	concurrency := 10
+	if config.Config.IsSet("queue.concurrency") {
+		concurrency = config.Config.GetInt("queue.concurrency")
+	}
+	return concurrency
+}
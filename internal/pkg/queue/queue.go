@@ -0,0 +1,25 @@
+package queue
+
+import (
+	"github.com/hibiken/asynq"
+)
+
+// Client asynq 任务投递客户端
+var Client *asynq.Client
+
+// Init 初始化 asynq 客户端
+func Init() {
+	Client = asynq.NewClient(getRedisConfig())
+}
+
+// NewServer 创建 asynq 任务处理服务
+func NewServer() *asynq.Server {
+	return asynq.NewServer(getRedisConfig(), asynq.Config{
+		Concurrency: getConcurrency(),
+	})
+}
+
+// NewScheduler 创建 asynq 周期任务调度器
+func NewScheduler() *asynq.Scheduler {
+	return asynq.NewScheduler(getRedisConfig(), nil)
+}
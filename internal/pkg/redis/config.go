@@ -8,7 +8,8 @@ import (
 // RedisClient Redis客户端
 var RedisClient *redis.Client
 
-func init() {
+// Init 初始化 Redis 客户端
+func Init() {
 	info := getConfig()
 
 	RedisClient = redisHelper.Init(&info)
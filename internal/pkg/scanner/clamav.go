@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamavChunkSize INSTREAM 每个数据块的大小
+const clamavChunkSize = 32 * 1024
+
+// ClamAV 通过 clamd 的 INSTREAM 命令扫描文件
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV 创建 ClamAV 扫描服务
+// address 为 tcp://host:port、host:port 或 unix:///path/to/clamd.ctl
+func NewClamAV(address string, timeout time.Duration) (*ClamAV, error) {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(address, "unix://"); ok {
+		network, address = "unix", path
+	} else {
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+	if address == "" {
+		return nil, errors.New("ClamAV 需要配置 clamd 地址")
+	}
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	return &ClamAV{network: network, address: address, timeout: timeout}, nil
+}
+
+// Scan 按 INSTREAM 协议发送文件: 以 4 字节大端长度为前缀分块发送, 以长度为 0 的块结束
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.send(conn, r); err != nil {
+		// clamd 超出 StreamMaxLength 等情况会先返回错误再断开连接
+		if reply, readErr := readReply(conn); readErr == nil && reply != "" {
+			return nil, fmt.Errorf("clamd: %s", reply)
+		}
+		return nil, err
+	}
+	reply, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+	return parseReply(reply)
+}
+
+func (c *ClamAV) send(conn net.Conn, r io.Reader) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+	buf := make([]byte, 4+clamavChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// readReply 读取以 \0 结尾的响应
+func readReply(conn net.Conn) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(conn, 4096))
+	if err != nil && len(data) == 0 {
+		return "", err
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// parseReply 解析响应, 如 "stream: OK" 或 "stream: Eicar-Signature FOUND"
+func parseReply(reply string) (*Result, error) {
+	status := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case status == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(status, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(status, " FOUND")}, nil
+	default:
+		return nil, fmt.Errorf("clamd: %s", reply)
+	}
+}
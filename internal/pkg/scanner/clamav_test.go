@@ -0,0 +1,208 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd 模拟 clamd 的 INSTREAM 命令, 检查分块格式并记录收到的数据
+type fakeClamd struct {
+	t        *testing.T
+	addr     string
+	maxBytes int // 超出时返回 size limit 错误, 与 clamd 的 StreamMaxLength 相同
+
+	chunks   chan []int
+	received chan []byte
+}
+
+func newFakeClamd(t *testing.T, maxBytes int) *fakeClamd {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	f := &fakeClamd{
+		t: t, addr: ln.Addr().String(), maxBytes: maxBytes,
+		chunks: make(chan []int, 1), received: make(chan []byte, 1),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeClamd) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	cmd := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(conn, cmd); err != nil || string(cmd) != "zINSTREAM\x00" {
+		f.t.Errorf("命令错误: %q, %v", cmd, err)
+		return
+	}
+	var data []byte
+	var sizes []int
+	for {
+		var size uint32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			f.t.Errorf("读取块长度失败: %v", err)
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if size > clamavChunkSize {
+			f.t.Errorf("块长度 %d 超出 %d", size, clamavChunkSize)
+		}
+		chunk := make([]byte, size)
+		if _, err := io.ReadFull(conn, chunk); err != nil {
+			f.t.Errorf("读取块失败: %v", err)
+			return
+		}
+		sizes = append(sizes, int(size))
+		data = append(data, chunk...)
+		if f.maxBytes > 0 && len(data) > f.maxBytes {
+			_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			// clamd 返回错误后断开, 这里先读完剩余数据, 避免连接重置导致客户端读不到响应
+			_ = conn.(*net.TCPConn).CloseWrite()
+			_, _ = io.Copy(io.Discard, conn)
+			return
+		}
+	}
+	f.chunks <- sizes
+	f.received <- data
+	if bytes.Contains(data, []byte(eicar)) {
+		_, _ = conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+		return
+	}
+	_, _ = conn.Write([]byte("stream: OK\x00"))
+}
+
+func newTestClamAV(t *testing.T, f *fakeClamd) *ClamAV {
+	c, err := NewClamAV("tcp://"+f.addr, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestClamAVClean(t *testing.T) {
+	f := newFakeClamd(t, 0)
+	data := bytes.Repeat([]byte("a"), 3*clamavChunkSize+123)
+	result, err := newTestClamAV(t, f).Scan(context.Background(), bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Infected {
+		t.Fatalf("误报: %+v", result)
+	}
+	sizes := <-f.chunks
+	want := []int{clamavChunkSize, clamavChunkSize, clamavChunkSize, 123}
+	if len(sizes) != len(want) {
+		t.Fatalf("分块为 %v, want %v", sizes, want)
+	}
+	for i := range want {
+		if sizes[i] != want[i] {
+			t.Fatalf("分块为 %v, want %v", sizes, want)
+		}
+	}
+	if !bytes.Equal(<-f.received, data) {
+		t.Fatal("clamd 收到的数据与文件不一致")
+	}
+}
+
+func TestClamAVEmpty(t *testing.T) {
+	f := newFakeClamd(t, 0)
+	result, err := newTestClamAV(t, f).Scan(context.Background(), strings.NewReader(""))
+	if err != nil || result.Infected {
+		t.Fatalf("扫描空文件结果 %+v, %v", result, err)
+	}
+	if sizes := <-f.chunks; len(sizes) != 0 {
+		t.Fatalf("空文件不应发送数据块, 实际为 %v", sizes)
+	}
+}
+
+func TestClamAVInfected(t *testing.T) {
+	f := newFakeClamd(t, 0)
+	result, err := newTestClamAV(t, f).Scan(context.Background(), strings.NewReader(eicar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Infected || result.Signature != "Eicar-Signature" {
+		t.Fatalf("扫描结果为 %+v", result)
+	}
+}
+
+func TestClamAVSizeLimit(t *testing.T) {
+	f := newFakeClamd(t, 2*clamavChunkSize)
+	data := bytes.Repeat([]byte("a"), 8*clamavChunkSize)
+	_, err := newTestClamAV(t, f).Scan(context.Background(), bytes.NewReader(data))
+	if err == nil || !strings.Contains(err.Error(), "INSTREAM size limit exceeded") {
+		t.Fatalf("应返回 clamd 的错误, 实际为 %v", err)
+	}
+}
+
+func TestClamAVUnavailable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	c, err := NewClamAV(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Scan(context.Background(), strings.NewReader("a")); err == nil {
+		t.Fatal("clamd 不可用时应返回错误")
+	}
+}
+
+func TestReadReply(t *testing.T) {
+	cases := map[string]string{
+		"stream: OK\x00":                "stream: OK",
+		"stream: OK\x00trailing":        "stream: OK",
+		"stream: OK\n":                  "stream: OK",
+		"stream: Eicar-Signature FOUND": "stream: Eicar-Signature FOUND",
+	}
+	for raw, want := range cases {
+		server, client := net.Pipe()
+		go func() {
+			_, _ = server.Write([]byte(raw))
+			_ = server.Close()
+		}()
+		got, err := readReply(client)
+		_ = client.Close()
+		if err != nil || got != want {
+			t.Errorf("readReply(%q) = %q, %v, want %q", raw, got, err, want)
+		}
+	}
+}
+
+func TestParseReply(t *testing.T) {
+	result, err := parseReply("stream: OK")
+	if err != nil || result.Infected {
+		t.Fatalf("解析 OK 结果为 %+v, %v", result, err)
+	}
+	result, err = parseReply("stream: Win.Test.EICAR_HDB-1 FOUND")
+	if err != nil || !result.Infected || result.Signature != "Win.Test.EICAR_HDB-1" {
+		t.Fatalf("解析 FOUND 结果为 %+v, %v", result, err)
+	}
+	for _, reply := range []string{"INSTREAM size limit exceeded. ERROR", "UNKNOWN COMMAND", ""} {
+		if _, err := parseReply(reply); err == nil {
+			t.Errorf("parseReply(%q) 应返回错误", reply)
+		}
+	}
+}
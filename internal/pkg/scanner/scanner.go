@@ -0,0 +1,55 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"QA-System/internal/global/config"
+)
+
+// Result 扫描结果
+type Result struct {
+	Infected  bool   // 是否检出威胁
+	Signature string // 检出的威胁名称
+}
+
+// Scanner 上传文件安全扫描
+type Scanner interface {
+	// Scan 扫描 r 中的全部内容, 扫描服务不可用时返回错误
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// Engine 全局使用的扫描服务, 未开启扫描时为 nil
+var Engine Scanner
+
+// Init 根据配置初始化扫描服务
+func Init() error {
+	driver := "none"
+	if config.Config.IsSet("scan.driver") {
+		driver = config.Config.GetString("scan.driver")
+	}
+	switch driver {
+	case "none", "":
+		Engine = nil
+	case "clamav":
+		timeout := 60
+		if config.Config.IsSet("scan.clamav.timeout") {
+			timeout = config.Config.GetInt("scan.clamav.timeout")
+		}
+		clamav, err := NewClamAV(config.Config.GetString("scan.clamav.address"), time.Duration(timeout)*time.Second)
+		if err != nil {
+			return err
+		}
+		Engine = clamav
+	default:
+		return fmt.Errorf("不支持的扫描服务 %s", driver)
+	}
+	return nil
+}
+
+// FailOpen 扫描服务不可用时是否放行上传
+func FailOpen() bool {
+	return config.Config.GetBool("scan.fail-open")
+}
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Local 本地磁盘存储, 只适用于单实例部署
+type Local struct {
+	dir string
+}
+
+// NewLocal 创建本地磁盘存储, dir 为存储根目录
+func NewLocal(dir string) *Local {
+	return &Local{dir: dir}
+}
+
+func (l *Local) path(key string) (string, error) {
+	key = CleanKey(key)
+	if key == "" {
+		return "", fs.ErrNotExist
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put 先写入临时文件再重命名, 避免读到写入一半的文件
+func (l *Local) Put(_ context.Context, key string, r io.Reader, _ int64, _ string) error {
+	dst, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dst)
+	}
+	if err != nil {
+		if removeErr := os.Remove(tmp.Name()); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			zap.L().Error("Failed to remove temp file", zap.String("path", tmp.Name()), zap.Error(removeErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// Open 读取文件, 返回的 *os.File 支持 Seek
+func (l *Local) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	src, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filepath.Clean(src))
+}
+
+// Delete 删除文件
+func (l *Local) Delete(_ context.Context, key string) error {
+	dst, err := l.path(key)
+	if err != nil {
+		return nil
+	}
+	if err := os.Remove(dst); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List 列出以 prefix 开头的全部文件, 忽略写入中的临时文件
+func (l *Local) List(_ context.Context, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	root := filepath.Clean(l.dir)
+	// 只遍历前缀所在的目录
+	start := root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = filepath.Join(root, filepath.FromSlash(CleanKey(prefix[:i])))
+	}
+	err := filepath.WalkDir(start, func(p string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return objects, err
+}
+
+// SignURL 本地存储由服务自身提供下载
+func (l *Local) SignURL(context.Context, string, time.Duration) (string, error) {
+	return "", nil
+}
@@ -0,0 +1,225 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Config S3 兼容存储配置
+type S3Config struct {
+	Endpoint  string // 服务地址, 如 https://s3.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string // 区域, MinIO 默认为 us-east-1
+	Bucket    string // 存储桶
+	AccessKey string
+	SecretKey string
+	PathStyle bool // 使用 endpoint/bucket/key 形式的地址, MinIO 等需要开启
+}
+
+// S3 S3 兼容存储, 使用 SigV4 签名直接调用 REST 接口
+type S3 struct {
+	endpoint  *url.URL
+	bucket    string
+	pathStyle bool
+	signer    signer
+	client    *http.Client
+}
+
+// NewS3 创建 S3 兼容存储
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3 存储需要配置 endpoint 与 bucket")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint 格式错误: %s", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3{
+		endpoint:  endpoint,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		signer: signer{
+			accessKey: cfg.AccessKey,
+			secretKey: cfg.SecretKey,
+			region:    cfg.Region,
+			service:   "s3",
+		},
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// objectURL 构造文件地址, key 为空时为存储桶地址
+func (s *S3) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	p := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		p += "/" + s.bucket
+	} else {
+		u.Host = s.bucket + "." + u.Host
+	}
+	u.Path = p + "/" + key
+	u.RawPath = ""
+	return &u
+}
+
+// do 签名并发送请求, 非 2xx 响应转换为错误
+func (s *S3) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64,
+	header http.Header, payloadHash string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.signer.sign(req, payloadHash, time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer closeBody(resp)
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fs.ErrNotExist
+	}
+	var e struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if xml.Unmarshal(data, &e) == nil && e.Code != "" {
+		return nil, fmt.Errorf("S3 %s %s: %s %s", method, u.Path, e.Code, e.Message)
+	}
+	return nil, fmt.Errorf("S3 %s %s: %s", method, u.Path, resp.Status)
+}
+
+func closeBody(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
+
+// Put 上传文件, size 未知时先读入内存
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key = CleanKey(key)
+	if key == "" {
+		return errors.New("文件 key 无效")
+	}
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(key), r, size, header, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	closeBody(resp)
+	return nil
+}
+
+// Open 读取文件
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	key = CleanKey(key)
+	if key == "" {
+		return nil, fs.ErrNotExist
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil, 0, nil, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete 删除文件, S3 删除不存在的文件同样返回成功
+func (s *S3) Delete(ctx context.Context, key string) error {
+	key = CleanKey(key)
+	if key == "" {
+		return nil
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil, 0, nil, emptyPayloadHash)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	closeBody(resp)
+	return nil
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List 使用 ListObjectsV2 分页列出文件
+func (s *S3) List(ctx context.Context, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	token := ""
+	for {
+		u := s.objectURL("")
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = query.Encode()
+		resp, err := s.do(ctx, http.MethodGet, u, nil, 0, nil, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		closeBody(resp)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range result.Contents {
+			objects = append(objects, Object{Key: c.Key, Size: c.Size, ModTime: c.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// SignURL 生成预签名下载地址
+func (s *S3) SignURL(_ context.Context, key string, expire time.Duration) (string, error) {
+	key = CleanKey(key)
+	if key == "" {
+		return "", fs.ErrNotExist
+	}
+	return s.signer.presign(http.MethodGet, s.objectURL(key), expire, time.Now()), nil
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"QA-System/internal/global/config"
+)
+
+// Object 存储中的文件信息
+type Object struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Storage 上传文件存储后端, key 为以 / 分隔的相对路径, 如 static/xxx.jpg
+// 文件不存在时 Open 返回 fs.ErrNotExist, Delete 视为成功
+type Storage interface {
+	// Put 保存文件, size 未知时为 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open 读取文件
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete 删除文件
+	Delete(ctx context.Context, key string) error
+	// List 列出以 prefix 开头的全部文件
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// SignURL 返回可直接下载的临时地址, 不支持时返回空字符串, 由服务自身提供下载
+	SignURL(ctx context.Context, key string, expire time.Duration) (string, error)
+}
+
+// Store 全局使用的存储后端
+var Store Storage
+
+// Init 根据配置初始化存储后端
+func Init() error {
+	driver := "local"
+	if config.Config.IsSet("storage.driver") {
+		driver = config.Config.GetString("storage.driver")
+	}
+	switch driver {
+	case "local":
+		dir := "./public"
+		if config.Config.IsSet("storage.local.dir") {
+			dir = config.Config.GetString("storage.local.dir")
+		}
+		Store = NewLocal(dir)
+	case "s3":
+		s3, err := NewS3(S3Config{
+			Endpoint:  config.Config.GetString("storage.s3.endpoint"),
+			Region:    config.Config.GetString("storage.s3.region"),
+			Bucket:    config.Config.GetString("storage.s3.bucket"),
+			AccessKey: config.Config.GetString("storage.s3.access-key"),
+			SecretKey: config.Config.GetString("storage.s3.secret-key"),
+			PathStyle: config.Config.GetBool("storage.s3.path-style"),
+		})
+		if err != nil {
+			return err
+		}
+		Store = s3
+	default:
+		return fmt.Errorf("不支持的存储后端 %s", driver)
+	}
+	return nil
+}
+
+// GetSignExpire 获取临时下载地址的有效期
+func GetSignExpire() time.Duration {
+	expire := 3600
+	if config.Config.IsSet("storage.sign-expire") {
+		expire = config.Config.GetInt("storage.sign-expire")
+	}
+	return time.Duration(expire) * time.Second
+}
+
+// CleanKey 规范化 key, 去除 .. 等可能越过存储根目录的部分, 无效时返回空字符串
+func CleanKey(key string) string {
+	key = strings.TrimPrefix(path.Clean("/"+key), "/")
+	if key == "" || key == "." {
+		return ""
+	}
+	return key
+}
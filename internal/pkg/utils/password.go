@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2id 参数, 取自 OWASP 推荐配置
+const (
+	argonMemory  uint32 = 19 * 1024
+	argonTime    uint32 = 2
+	argonThreads uint8  = 1
+	argonKeyLen  uint32 = 32
+	argonSaltLen        = 16
+)
+
+const argonPrefix = "$argon2id$"
+
+// HashPassword 使用 argon2id 生成带盐的密码哈希
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	hash := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argonPrefix, argon2.Version, argonMemory, argonTime,
+		argonThreads, base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(hash)), nil
+}
+
+// IsPasswordHash 判断存储的密码是否为 argon2id 哈希, 否则为旧版 AES 密文
+func IsPasswordHash(stored string) bool {
+	return strings.HasPrefix(stored, argonPrefix)
+}
+
+// VerifyPassword 校验密码, needRehash 表示哈希参数已过时需要重新计算
+func VerifyPassword(stored string, password string) (ok bool, needRehash bool, err error) {
+	parts := strings.Split(stored, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, false, errors.New("密码哈希格式错误")
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, err
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, false, err
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, err
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, false, err
+	}
+	// #nosec G115 -- 哈希长度固定为 argonKeyLen
+	other := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(hash)))
+	if subtle.ConstantTimeCompare(hash, other) != 1 {
+		return false, false, nil
+	}
+	needRehash = version != argon2.Version || memory != argonMemory || iterations != argonTime ||
+		threads != argonThreads || uint32(len(hash)) != argonKeyLen // #nosec G115
+	return true, needRehash, nil
+}
+
+// passwordAlphabet 随机密码字符集, 去除了易混淆的字符
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+
+// RandomPassword 生成指定长度的随机密码
+func RandomPassword(length int) (string, error) {
+	var sb strings.Builder
+	limit := big.NewInt(int64(len(passwordAlphabet)))
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(passwordAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,28 @@
+package webp
+
+// bitWriter 按低位优先写入比特流
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	nacc uint
+}
+
+// writeBits 写入 v 的低 n 位, n 不超过 32
+func (w *bitWriter) writeBits(v uint32, n uint) {
+	w.acc |= uint64(v&(1<<n-1)) << w.nacc
+	w.nacc += n
+	for w.nacc >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nacc -= 8
+	}
+}
+
+// bytes 补齐最后一个字节并返回写入的数据
+func (w *bitWriter) bytes() []byte {
+	if w.nacc > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nacc = 0, 0
+	}
+	return w.buf
+}
@@ -0,0 +1,195 @@
+package webp
+
+import (
+	"math/bits"
+	"sort"
+)
+
+// 前缀码的最大码长与码长码的最大码长
+const (
+	maxCodeLength       = 15
+	maxCodeLengthLength = 7
+)
+
+// codeLengthCodeOrder 码长码各码长的写入顺序
+var codeLengthCodeOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// prefixCode 前缀码, codes 已按位反转, 可按低位优先直接写入
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint16
+}
+
+func (c *prefixCode) write(bw *bitWriter, symbol int) {
+	bw.writeBits(uint32(c.codes[symbol]), uint(c.lengths[symbol]))
+}
+
+// writePrefixCode 按符号频率生成并写入前缀码
+// 至多两个符号且均小于 256 时使用简单码, 否则按码长码写入各符号的码长
+func writePrefixCode(bw *bitWriter, freq []int) *prefixCode {
+	symbols := make([]int, 0, 2)
+	for s, f := range freq {
+		if f > 0 {
+			symbols = append(symbols, s)
+			if len(symbols) > 2 {
+				break
+			}
+		}
+	}
+	if len(symbols) <= 2 && (len(symbols) == 0 || symbols[len(symbols)-1] < 256) {
+		return writeSimpleCode(bw, len(freq), symbols)
+	}
+
+	lengths := huffmanLengths(freq, maxCodeLength)
+	lengthFreq := make([]int, len(codeLengthCodeOrder))
+	for _, l := range lengths {
+		lengthFreq[l]++
+	}
+	// 码长码只有一个符号时无法构成完整的前缀码, 补充一个不会用到的符号
+	if used := countNonZero(lengthFreq); used < 2 {
+		if lengthFreq[0] == 0 {
+			lengthFreq[0] = 1
+		} else {
+			lengthFreq[1] = 1
+		}
+	}
+	lengthLengths := huffmanLengths(lengthFreq, maxCodeLengthLength)
+	numCodes := 4
+	for i, s := range codeLengthCodeOrder {
+		if lengthLengths[s] > 0 {
+			numCodes = max(numCodes, i+1)
+		}
+	}
+	bw.writeBits(0, 1) // 普通码
+	bw.writeBits(uint32(numCodes-4), 4)
+	for _, s := range codeLengthCodeOrder[:numCodes] {
+		bw.writeBits(uint32(lengthLengths[s]), 3)
+	}
+	bw.writeBits(0, 1) // 写入字母表全部符号的码长
+	lengthCode := &prefixCode{lengths: lengthLengths, codes: canonicalCodes(lengthLengths)}
+	for _, l := range lengths {
+		lengthCode.write(bw, int(l))
+	}
+	return &prefixCode{lengths: lengths, codes: canonicalCodes(lengths)}
+}
+
+// writeSimpleCode 写入至多两个符号的简单码, 只有一个符号时读取该符号不占用位
+func writeSimpleCode(bw *bitWriter, alphabetSize int, symbols []int) *prefixCode {
+	if len(symbols) == 0 {
+		symbols = []int{0}
+	}
+	c := &prefixCode{lengths: make([]uint8, alphabetSize), codes: make([]uint16, alphabetSize)}
+	bw.writeBits(1, 1)
+	bw.writeBits(uint32(len(symbols)-1), 1)
+	if symbols[0] < 2 {
+		bw.writeBits(0, 1)
+		bw.writeBits(uint32(symbols[0]), 1)
+	} else {
+		bw.writeBits(1, 1)
+		bw.writeBits(uint32(symbols[0]), 8)
+	}
+	if len(symbols) == 2 {
+		bw.writeBits(uint32(symbols[1]), 8)
+		c.lengths[symbols[0]], c.lengths[symbols[1]] = 1, 1
+		c.codes[symbols[1]] = 1
+	}
+	return c
+}
+
+func countNonZero(freq []int) int {
+	n := 0
+	for _, f := range freq {
+		if f > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// huffmanLengths 按频率计算哈夫曼码长, 超出 maxLength 时将频率减半后重新计算
+// 调用方保证至少有两个符号的频率大于 0
+func huffmanLengths(freq []int, maxLength int) []uint8 {
+	weights := append([]int(nil), freq...)
+	for {
+		lengths := buildHuffman(weights)
+		longest := uint8(0)
+		for _, l := range lengths {
+			longest = max(longest, l)
+		}
+		if int(longest) <= maxLength {
+			return lengths
+		}
+		for i, w := range weights {
+			if w > 0 {
+				weights[i] = (w + 1) / 2
+			}
+		}
+	}
+}
+
+// buildHuffman 构建哈夫曼树并返回各符号的深度
+func buildHuffman(freq []int) []uint8 {
+	type node struct {
+		weight int
+		parent int
+	}
+	leaves := make([]int, 0, len(freq))
+	for s, f := range freq {
+		if f > 0 {
+			leaves = append(leaves, s)
+		}
+	}
+	sort.SliceStable(leaves, func(i, j int) bool { return freq[leaves[i]] < freq[leaves[j]] })
+	nodes := make([]node, 0, 2*len(leaves))
+	for _, s := range leaves {
+		nodes = append(nodes, node{weight: freq[s], parent: -1})
+	}
+	// 叶子已按权重排序, 合并产生的内部节点权重单调不减, 两个队列中取最小者即可
+	leaf, inner := 0, len(leaves)
+	pop := func() int {
+		if leaf < len(leaves) && (inner >= len(nodes) || nodes[leaf].weight <= nodes[inner].weight) {
+			leaf++
+			return leaf - 1
+		}
+		inner++
+		return inner - 1
+	}
+	for i := 1; i < len(leaves); i++ {
+		a, b := pop(), pop()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, parent: -1})
+		nodes[a].parent, nodes[b].parent = len(nodes)-1, len(nodes)-1
+	}
+	lengths := make([]uint8, len(freq))
+	for i, s := range leaves {
+		depth := uint8(0)
+		for n := i; nodes[n].parent >= 0; n = nodes[n].parent {
+			depth++
+		}
+		lengths[s] = depth
+	}
+	return lengths
+}
+
+// canonicalCodes 按码长生成范式哈夫曼码, 并按位反转以便低位优先写入
+func canonicalCodes(lengths []uint8) []uint16 {
+	var count [maxCodeLength + 1]int
+	for _, l := range lengths {
+		count[l]++
+	}
+	count[0] = 0
+	var next [maxCodeLength + 1]int
+	code := 0
+	for l := 1; l <= maxCodeLength; l++ {
+		code = (code + count[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint16, len(lengths))
+	for s, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		codes[s] = bits.Reverse16(uint16(next[l])) >> (16 - l)
+		next[l]++
+	}
+	return codes
+}
@@ -0,0 +1,250 @@
+// Package webp 提供无损 WebP (VP8L) 编码, golang.org/x/image/webp 只支持解码
+//
+// 编码时依次应用减绿变换与预测变换, 再按各通道的统计生成前缀码, 不使用颜色缓存与向后引用
+// 格式参考 https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification
+package webp
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// maxDimension VP8L 以 14 位记录宽高, 单边最大像素
+const maxDimension = 1 << 14
+
+// ErrTooLarge 图片宽高超出 WebP 的限制
+var ErrTooLarge = errors.New("webp: 图片宽高超出 16384 像素")
+
+// 变换类型
+const (
+	predictorTransform     = 0
+	subtractGreenTransform = 2
+)
+
+// predictorBits 预测变换的分块大小为 1<<predictorBits
+const predictorBits = 4
+
+// Encode 将图片编码为无损 WebP
+func Encode(w io.Writer, img image.Image) error {
+	b := img.Bounds()
+	width, height := b.Dx(), b.Dy()
+	if width > maxDimension || height > maxDimension {
+		return ErrTooLarge
+	}
+	if width < 1 || height < 1 {
+		return errors.New("webp: 图片为空")
+	}
+	argb, hasAlpha := toARGB(img)
+
+	bw := &bitWriter{}
+	bw.writeBits(0x2f, 8) // VP8L 签名
+	bw.writeBits(uint32(width-1), 14)
+	bw.writeBits(uint32(height-1), 14)
+	if hasAlpha {
+		bw.writeBits(1, 1)
+	} else {
+		bw.writeBits(0, 1)
+	}
+	bw.writeBits(0, 3) // 版本号
+
+	// 解码时按写入的相反顺序还原, 因此编码时按写入顺序依次应用
+	bw.writeBits(1, 1)
+	bw.writeBits(subtractGreenTransform, 2)
+	subtractGreen(argb)
+
+	bw.writeBits(1, 1)
+	bw.writeBits(predictorTransform, 2)
+	bw.writeBits(predictorBits-2, 3)
+	residuals, modes := predict(argb, width, height)
+	writeImage(bw, modes, false)
+
+	bw.writeBits(0, 1) // 没有更多变换
+	writeImage(bw, residuals, true)
+	return writeRIFF(w, bw.bytes())
+}
+
+// writeRIFF 写入 RIFF 容器, 块长度为奇数时补一个字节
+func writeRIFF(w io.Writer, data []byte) error {
+	padded := len(data) + len(data)&1
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+padded))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if padded != len(data) {
+		_, err := w.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// toARGB 转换为非预乘的 ARGB 像素, 并返回是否存在透明像素
+func toARGB(img image.Image) ([]uint32, bool) {
+	b := img.Bounds()
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(nrgba, nrgba.Bounds(), img, b.Min, draw.Src)
+		b = nrgba.Bounds()
+	}
+	argb := make([]uint32, 0, b.Dx()*b.Dy())
+	hasAlpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := nrgba.Pix[nrgba.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := row[x*4 : x*4+4]
+			if p[3] != 0xff {
+				hasAlpha = true
+			}
+			argb = append(argb, uint32(p[3])<<24|uint32(p[0])<<16|uint32(p[1])<<8|uint32(p[2]))
+		}
+	}
+	return argb, hasAlpha
+}
+
+// subtractGreen 红、蓝通道减去绿色通道
+func subtractGreen(argb []uint32) {
+	for i, p := range argb {
+		g := (p >> 8) & 0xff
+		r := ((p >> 16) - g) & 0xff
+		b := (p - g) & 0xff
+		argb[i] = p&0xff00ff00 | r<<16 | b
+	}
+}
+
+// 预测模式, 只使用不依赖右上像素的几种
+const (
+	predictLeft        = 1
+	predictTop         = 2
+	predictTopLeft     = 4
+	predictAverageLeft = 7 // 左侧与上方像素的平均
+)
+
+var predictModes = []uint32{predictLeft, predictTop, predictTopLeft, predictAverageLeft}
+
+// predict 逐块选择残差最小的预测模式, 返回残差与各块的模式 (记录在绿色通道)
+func predict(argb []uint32, width, height int) ([]uint32, []uint32) {
+	blockSize := 1 << predictorBits
+	tilesX := (width + blockSize - 1) >> predictorBits
+	tilesY := (height + blockSize - 1) >> predictorBits
+	residuals := make([]uint32, len(argb))
+	modes := make([]uint32, tilesX*tilesY)
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			x0, y0 := tx*blockSize, ty*blockSize
+			x1, y1 := min(x0+blockSize, width), min(y0+blockSize, height)
+			best, bestCost := predictModes[0], -1
+			for _, mode := range predictModes {
+				cost := 0
+				for y := max(y0, 1); y < y1; y++ {
+					for x := max(x0, 1); x < x1; x++ {
+						cost += residualCost(subPixels(argb[y*width+x], predictPixel(argb, width, x, y, mode)))
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			modes[ty*tilesX+tx] = 0xff000000 | best<<8
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					var pred uint32
+					switch {
+					case x == 0 && y == 0:
+						pred = 0xff000000
+					case y == 0:
+						pred = argb[x-1]
+					case x == 0:
+						pred = argb[(y-1)*width]
+					default:
+						pred = predictPixel(argb, width, x, y, best)
+					}
+					residuals[y*width+x] = subPixels(argb[y*width+x], pred)
+				}
+			}
+		}
+	}
+	return residuals, modes
+}
+
+// predictPixel 按模式计算 x, y 处的预测值, 调用方保证 x, y 均大于 0
+func predictPixel(argb []uint32, width, x, y int, mode uint32) uint32 {
+	left, top := argb[y*width+x-1], argb[(y-1)*width+x]
+	switch mode {
+	case predictTop:
+		return top
+	case predictTopLeft:
+		return argb[(y-1)*width+x-1]
+	case predictAverageLeft:
+		return average2(left, top)
+	default:
+		return left
+	}
+}
+
+// average2 逐通道取平均值并向下取整
+func average2(a, b uint32) uint32 {
+	return (((a ^ b) & 0xfefefefe) >> 1) + (a & b)
+}
+
+// subPixels 逐通道相减, 结果对 256 取模
+func subPixels(a, b uint32) uint32 {
+	alphaGreen := 0x00ff00ff + (a & 0xff00ff00) - (b & 0xff00ff00)
+	redBlue := 0xff00ff00 + (a & 0x00ff00ff) - (b & 0x00ff00ff)
+	return alphaGreen&0xff00ff00 | redBlue&0x00ff00ff
+}
+
+// residualCost 残差各通道与 0 的距离之和, 用于选择预测模式
+func residualCost(p uint32) int {
+	cost := 0
+	for shift := 0; shift < 32; shift += 8 {
+		v := int((p >> shift) & 0xff)
+		cost += min(v, 256-v)
+	}
+	return cost
+}
+
+// 各前缀码的字母表大小, 绿色通道包含 24 个长度前缀码, 不使用颜色缓存
+const (
+	greenAlphabetSize    = 256 + 24
+	channelAlphabetSize  = 256
+	distanceAlphabetSize = 40
+)
+
+// writeImage 写入熵编码的图像, 主图像需额外写入是否使用元前缀码
+func writeImage(bw *bitWriter, pixels []uint32, isMain bool) {
+	bw.writeBits(0, 1) // 不使用颜色缓存
+	if isMain {
+		bw.writeBits(0, 1) // 不使用元前缀码, 整幅图像共用一组前缀码
+	}
+	green := make([]int, greenAlphabetSize)
+	red := make([]int, channelAlphabetSize)
+	blue := make([]int, channelAlphabetSize)
+	alpha := make([]int, channelAlphabetSize)
+	for _, p := range pixels {
+		green[(p>>8)&0xff]++
+		red[(p>>16)&0xff]++
+		blue[p&0xff]++
+		alpha[p>>24]++
+	}
+	greenCode := writePrefixCode(bw, green)
+	redCode := writePrefixCode(bw, red)
+	blueCode := writePrefixCode(bw, blue)
+	alphaCode := writePrefixCode(bw, alpha)
+	writePrefixCode(bw, make([]int, distanceAlphabetSize))
+	for _, p := range pixels {
+		greenCode.write(bw, int((p>>8)&0xff))
+		redCode.write(bw, int((p>>16)&0xff))
+		blueCode.write(bw, int(p&0xff))
+		alphaCode.write(bw, int(p>>24))
+	}
+}
@@ -0,0 +1,131 @@
+package webp
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// roundTrip 编码后用 golang.org/x/image/webp 解码, 检查像素一致
+func roundTrip(t *testing.T, img *image.NRGBA) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := webp.DecodeConfig(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := img.Bounds()
+	if cfg.Width != b.Dx() || cfg.Height != b.Dy() {
+		t.Fatalf("尺寸为 %dx%d, want %dx%d", cfg.Width, cfg.Height, b.Dx(), b.Dy())
+	}
+	decoded, err := webp.Decode(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			want := img.NRGBAAt(b.Min.X+x, b.Min.Y+y)
+			got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+			if got != want {
+				t.Fatalf("(%d, %d) 解码为 %v, want %v", x, y, got, want)
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestEncodeGradient(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 67, 45))
+	for y := 0; y < 45; y++ {
+		for x := 0; x < 67; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 3), G: uint8(y * 5), B: uint8(x + y), A: 255})
+		}
+	}
+	data := roundTrip(t, img)
+	if len(data) >= 67*45*3 {
+		t.Errorf("渐变图片编码后 %d 字节, 未被压缩", len(data))
+	}
+}
+
+func TestEncodeNoiseWithAlpha(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	img := image.NewNRGBA(image.Rect(0, 0, 100, 37))
+	rng.Read(img.Pix)
+	roundTrip(t, img)
+}
+
+func TestEncodeSolid(t *testing.T) {
+	for _, size := range []image.Rectangle{image.Rect(0, 0, 1, 1), image.Rect(0, 0, 40, 3)} {
+		img := image.NewNRGBA(size)
+		for i := range img.Pix {
+			img.Pix[i] = 0x80
+		}
+		roundTrip(t, img)
+	}
+}
+
+func TestEncodeSubImage(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	img := image.NewNRGBA(image.Rect(0, 0, 50, 50))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(rng.Intn(4)) // 符号较少时使用简单码
+	}
+	roundTrip(t, img.SubImage(image.Rect(10, 5, 43, 30)).(*image.NRGBA))
+}
+
+func TestEncodeRGBA(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 20; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 10), G: 100, B: uint8(y * 10), A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.RGBAModel.Convert(decoded.At(7, 3)); got != img.At(7, 3) {
+		t.Fatalf("解码为 %v, want %v", got, img.At(7, 3))
+	}
+}
+
+func TestEncodeTooLarge(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, maxDimension+1, 1))
+	if err := Encode(&bytes.Buffer{}, img); err != ErrTooLarge {
+		t.Fatalf("应返回 ErrTooLarge, 实际为 %v", err)
+	}
+}
+
+// libwebp 要求前缀码完整, 即各码长满足 Kraft 等式
+func TestHuffmanLengthsComplete(t *testing.T) {
+	// 斐波那契频率会生成深度超过限制的哈夫曼树
+	freq := make([]int, 30)
+	freq[0], freq[1] = 1, 1
+	for i := 2; i < len(freq); i++ {
+		freq[i] = freq[i-1] + freq[i-2]
+	}
+	for _, limit := range []int{maxCodeLength, maxCodeLengthLength} {
+		lengths := huffmanLengths(freq, limit)
+		sum := 0
+		for _, l := range lengths {
+			if int(l) > limit || l == 0 {
+				t.Fatalf("码长 %v 超出限制 %d", lengths, limit)
+			}
+			sum += 1 << (limit - int(l))
+		}
+		if sum != 1<<limit {
+			t.Fatalf("码长 %v 不完整", lengths)
+		}
+	}
+}
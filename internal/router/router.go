@@ -11,14 +11,18 @@ import (
 func Init(r *gin.Engine) {
 	const pre = "/api"
 
+	r.GET("/public/:dir/:name", u.ServeUpload)
+
 	api := r.Group(pre)
 	{
 		api.POST("/admin/reg", a.Register)
 		api.POST("/admin/login", a.Login)
+		api.POST("/admin/logout", a.Logout)
 		user := api.Group("/user")
 		{
 			user.POST("/submit", u.SubmitSurvey)
 			user.GET("/get", u.GetSurvey)
+			user.GET("/challenge", u.GetChallenge)
 			user.GET("/statistic", u.GetSurveyStatistics)
 			user.POST("/upload/img", u.UploadImg)
 			user.POST("/upload/file", u.UploadFile)
@@ -26,9 +30,19 @@ func Init(r *gin.Engine) {
 		}
 		admin := api.Group("/admin", middleware.CheckLogin)
 		{
+			// 需修改密码或开启两步验证时, 仍需可以访问自助修改与绑定接口
 			api.POST("/admin/update", a.UpdatePassword)
-			api.POST("/admin/reset", a.ResetPassword)
+			api.GET("/admin/2fa/status", a.GetTOTPStatus)
+			api.POST("/admin/2fa/setup", a.SetupTOTP)
+			api.POST("/admin/2fa/enable", a.EnableTOTP)
+			admin.POST("/reset", a.ResetPassword)
+			admin.POST("/2fa/disable", a.DisableTOTP)
+			admin.POST("/2fa/recovery", a.RegenerateRecoveryCodes)
+			admin.POST("/2fa/reset", a.ResetTOTP)
+			admin.PUT("/2fa/policy", a.UpdateTwoFactorPolicy)
 			admin.POST("/create", a.CreateSurvey)
+			admin.POST("/import", a.ImportSurvey)
+			admin.POST("/import/answers", a.ImportAnswerSheets)
 			admin.GET("/create", a.GetQuestionPre)
 			admin.POST("/new", a.CreateQuestionPre)
 			admin.PUT("/update/status", a.UpdateSurveyStatus)
@@ -38,12 +52,53 @@ func Init(r *gin.Engine) {
 			admin.DELETE("/delete", a.DeleteSurvey)
 			admin.DELETE("/delete/answersheet", a.DeleteAnswerSheet)
 
+			admin.GET("/recycle/list", a.GetRecycleSurveys)
+			admin.GET("/recycle/answers", a.GetRecycleAnswerSheets)
+			admin.PUT("/recycle/restore", a.RestoreSurvey)
+			admin.PUT("/recycle/restore/answersheet", a.RestoreAnswerSheet)
+			admin.DELETE("/recycle/purge", a.PurgeSurvey)
+
 			admin.POST("/permission/create", a.CreatePermission)
+			admin.PUT("/permission/update", a.UpdatePermission)
+			admin.GET("/permission/list", a.GetPermissions)
 			admin.DELETE("/permission/delete", a.DeletePermission)
 
+			admin.GET("/user/list", a.GetAdminList)
+			admin.PUT("/user/status", a.UpdateAdminStatus)
+			admin.PUT("/user/type", a.UpdateAdminType)
+			admin.DELETE("/user/delete", a.DeleteAdmin)
+			admin.PUT("/user/unlock", a.UnlockLogin)
+			admin.DELETE("/user/sessions", a.RevokeUserSessions)
+
+			admin.GET("/session/list", a.GetSessions)
+			admin.DELETE("/session/revoke", a.RevokeSession)
+
+			admin.POST("/invitation/create", a.CreateInvitation)
+			admin.GET("/invitation/list", a.GetInvitationList)
+			admin.DELETE("/invitation/revoke", a.RevokeInvitation)
+
+			admin.GET("/audit/list", a.GetAuditLogs)
+
+			admin.POST("/token/create", a.CreateAPIToken)
+			admin.GET("/token/list", a.GetAPITokens)
+			admin.DELETE("/token/revoke", a.RevokeAPIToken)
+
+			admin.POST("/webhook/create", a.CreateWebhook)
+			admin.GET("/webhook/list", a.GetWebhooks)
+			admin.PUT("/webhook/update", a.UpdateWebhook)
+			admin.DELETE("/webhook/delete", a.DeleteWebhook)
+			admin.GET("/webhook/deliveries", a.GetWebhookDeliveries)
+			admin.POST("/webhook/redeliver", a.RedeliverWebhook)
+
+			admin.GET("/notify/get", a.GetNotification)
+			admin.PUT("/notify/update", a.UpdateNotification)
+
 			admin.GET("/list/questions", a.GetAllSurvey)
 			admin.GET("/single/question", a.GetSurvey)
 			admin.GET("/download", a.DownloadFile)
+			admin.GET("/export/status", a.GetExportStatus)
+			admin.GET("/export/file", a.DownloadExportFile)
+			admin.GET("/download/files", a.DownloadSurveyFiles)
 		}
 	}
 }
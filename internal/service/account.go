@@ -0,0 +1,82 @@
+package service
+
+import (
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+)
+
+// AdminInfo 管理员列表项
+type AdminInfo struct {
+	ID        int    `json:"id"`
+	Username  string `json:"username"`
+	AdminType int    `json:"admin_type"`
+	Disabled  bool   `json:"disabled"`
+	SurveyNum int64  `json:"survey_num"` // 创建的问卷数量
+}
+
+// GetAdminList 分页查询管理员
+func GetAdminList(keyword string, adminType int, pageNum, pageSize int) ([]AdminInfo, *int64, error) {
+	users, total, err := d.GetUserList(ctx, keyword, adminType, pageNum, pageSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	list := make([]AdminInfo, 0, len(users))
+	for _, user := range users {
+		num, err := d.CountSurveyByUserID(ctx, user.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		list = append(list, AdminInfo{
+			ID:        user.ID,
+			Username:  user.Username,
+			AdminType: user.AdminType,
+			Disabled:  user.Disabled,
+			SurveyNum: num,
+		})
+	}
+	return list, total, nil
+}
+
+// SetAdminDisabled 停用或启用管理员
+func SetAdminDisabled(uid int, disabled bool) error {
+	return d.UpdateUserDisabled(ctx, uid, disabled)
+}
+
+// SetAdminType 修改管理员类型
+func SetAdminType(uid int, adminType int) error {
+	return d.UpdateUserAdminType(ctx, uid, adminType)
+}
+
+// CountAdminSurveys 统计管理员创建的问卷数量
+func CountAdminSurveys(uid int) (int64, error) {
+	return d.CountSurveyByUserID(ctx, uid)
+}
+
+// DeleteAdmin 删除管理员及其令牌、恢复码与通知订阅, receiver 不为空时先将其问卷转移给 receiver
+// 全部写入在同一事务中完成, 避免问卷已转移而用户仍存在
+func DeleteAdmin(user *model.User, receiver *model.User) error {
+	return d.Transaction(ctx, func(tx *dao.Dao) error {
+		if receiver != nil {
+			if err := tx.TransferSurveys(ctx, user.ID, receiver.ID); err != nil {
+				return err
+			}
+			// 接收人成为所有者后, 原有的协作权限不再需要
+			if err := tx.DeleteManageOfOwnedSurveys(ctx, receiver.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.DeleteManageByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.DeleteAPITokensByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.DeleteRecoveryCodesByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		if err := tx.DeleteNotificationsByUserID(ctx, user.ID); err != nil {
+			return err
+		}
+		return tx.DeleteUser(ctx, user.ID)
+	})
+}
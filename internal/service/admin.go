@@ -1,11 +1,12 @@
 package service
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -18,26 +19,44 @@ import (
 
 // GetAdminByUsername 根据用户名获取管理员
 func GetAdminByUsername(username string) (*model.User, error) {
-	user, err := d.GetUserByUsername(ctx, username)
-	if err != nil {
-		return nil, err
-	}
-	if user.Password != "" {
-		aesDecryptPassword(user)
-	}
-	return user, nil
+	return d.GetUserByUsername(ctx, username)
 }
 
 // GetAdminByID 根据ID获取管理员
 func GetAdminByID(id int) (*model.User, error) {
-	user, err := d.GetUserByID(ctx, id)
-	if err != nil {
-		return nil, err
+	return d.GetUserByID(ctx, id)
+}
+
+// CheckAdminPassword 校验管理员密码
+// 旧版 AES 加密或参数过时的密码在校验成功后会透明地重新哈希
+func CheckAdminPassword(user *model.User, password string) (bool, error) {
+	if user.Password == "" {
+		return false, nil
+	}
+	if !utils.IsPasswordHash(user.Password) {
+		legacy := utils.AesDecrypt(user.Password)
+		if legacy == "" || subtle.ConstantTimeCompare([]byte(legacy), []byte(password)) != 1 {
+			return false, nil
+		}
+		return true, rehashAdminPassword(user, password)
+	}
+	ok, needRehash, err := utils.VerifyPassword(user.Password, password)
+	if err != nil || !ok {
+		return false, err
 	}
-	if user.Password != "" {
-		aesDecryptPassword(user)
+	if needRehash {
+		return true, rehashAdminPassword(user, password)
 	}
-	return user, nil
+	return true, nil
+}
+
+func rehashAdminPassword(user *model.User, password string) error {
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	return d.UpdateUserPassword(ctx, user.ID, hash, user.MustChangePassword)
 }
 
 // IsAdminExist 判断管理员是否存在
@@ -48,8 +67,12 @@ func IsAdminExist(username string) error {
 
 // CreateAdmin 创建管理员
 func CreateAdmin(user model.User) error {
-	aesEncryptPassword(&user)
-	err := d.CreateUser(ctx, &user)
+	hash, err := utils.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	err = d.CreateUser(ctx, &user)
 	return err
 }
 
@@ -60,8 +83,14 @@ func GetUserByName(username string) (*model.User, error) {
 }
 
 // CreatePermission 创建权限
-func CreatePermission(id int, surveyID int) error {
-	err := d.CreateManage(ctx, id, surveyID)
+func CreatePermission(id int, surveyID int, role int) error {
+	err := d.CreateManage(ctx, id, surveyID, role)
+	return err
+}
+
+// UpdatePermission 更新协作者角色
+func UpdatePermission(id int, surveyID int, role int) error {
+	err := d.UpdateManageRole(ctx, id, surveyID, role)
 	return err
 }
 
@@ -71,6 +100,11 @@ func DeletePermission(id int, surveyID int) error {
 	return err
 }
 
+// GetPermission 获取用户在问卷上的协作权限
+func GetPermission(id int, surveyID int) (*model.Manage, error) {
+	return d.GetManageByUIDAndSID(ctx, id, surveyID)
+}
+
 // CheckPermission 检查权限
 func CheckPermission(id int, surveyID int) error {
 	err := d.CheckManage(ctx, id, surveyID)
@@ -79,7 +113,7 @@ func CheckPermission(id int, surveyID int) error {
 
 // CreateSurvey 创建问卷
 func CreateSurvey(id int, question_list []dao.QuestionList, status int, surveyType, limit uint,
-	sumLimit uint, verify bool, ddl, startTime time.Time, title string, desc string) error {
+	sumLimit uint, verify bool, challenge int, ddl, startTime time.Time, title string, desc string) (int, error) {
 	var survey model.Survey
 	survey.UserID = id
 	survey.Status = status
@@ -88,15 +122,24 @@ func CreateSurvey(id int, question_list []dao.QuestionList, status int, surveyTy
 	survey.DailyLimit = limit
 	survey.SumLimit = sumLimit
 	survey.Verify = verify
+	survey.Challenge = challenge
 	survey.StartTime = startTime
 	survey.Title = title
 	survey.Desc = desc
-	survey, err := d.CreateSurvey(ctx, survey)
-	if err != nil {
+	// 问卷、问题与选项在同一事务中创建, 避免留下不完整的问卷
+	err := d.Transaction(ctx, func(tx *dao.Dao) error {
+		var err error
+		survey, err = tx.CreateSurvey(ctx, survey)
+		if err != nil {
+			return err
+		}
+		_, err = createQuestionsAndOptions(tx, question_list, survey.ID)
 		return err
+	})
+	if err != nil {
+		return 0, err
 	}
-	_, err = createQuestionsAndOptions(question_list, survey.ID)
-	return err
+	return survey.ID, nil
 }
 
 // UpdateSurveyStatus 更新问卷状态
@@ -107,140 +150,53 @@ func UpdateSurveyStatus(id int, status int) error {
 
 // UpdateSurvey 更新问卷
 func UpdateSurvey(id int, question_list []dao.QuestionList, surveyType,
-	limit uint, sumLimit uint, verify bool, desc string, title string, ddl, startTime time.Time) error {
-	// 遍历原有问题，删除对应选项
-	var oldQuestions []model.Question
-	var old_imgs []string
-	new_imgs := make([]string, 0)
-	// 获取原有图片
-	oldQuestions, err := d.GetQuestionsBySurveyID(ctx, id)
-	if err != nil {
-		return err
-	}
-	old_imgs, err = getOldImgs(oldQuestions)
-	if err != nil {
-		return err
-	}
-	// 删除原有问题和选项
-	for _, oldQuestion := range oldQuestions {
-		oldOptions, err := d.GetOptionsByQuestionID(ctx, oldQuestion.ID)
+	limit uint, sumLimit uint, verify bool, challenge int, desc string, title string, ddl, startTime time.Time) error {
+	return withOutbox(func(tx *dao.Dao, o *outbox) error {
+		// 获取原有图片
+		oldQuestions, err := tx.GetQuestionsBySurveyID(ctx, id)
 		if err != nil {
 			return err
 		}
-		for _, oldOption := range oldOptions {
-			err = d.DeleteOption(ctx, oldOption.ID)
+		oldImgs, err := getOldImgs(tx, oldQuestions)
+		if err != nil {
+			return err
+		}
+		// 删除原有问题和选项
+		for _, oldQuestion := range oldQuestions {
+			err = tx.DeleteOption(ctx, oldQuestion.ID)
 			if err != nil {
 				return err
 			}
 		}
-		err = d.DeleteQuestion(ctx, oldQuestion.ID)
+		err = tx.DeleteQuestionBySurveyID(ctx, id)
 		if err != nil {
 			return err
 		}
-		err = dao.DeleteAllQuestionCache(ctx)
+		// 修改问卷信息
+		err = tx.UpdateSurvey(ctx, id, surveyType, limit, sumLimit, verify, challenge, desc, title, ddl, startTime)
 		if err != nil {
 			return err
 		}
-		err = dao.DeleteAllOptionCache(ctx)
+		// 重新添加问题和选项
+		newImgs, err := createQuestionsAndOptions(tx, question_list, id)
 		if err != nil {
 			return err
 		}
-	}
-	// 修改问卷信息
-	err = d.UpdateSurvey(ctx, id, surveyType, limit, sumLimit, verify, desc, title, ddl, startTime)
-	if err != nil {
-		return err
-	}
-	// 重新添加问题和选项
-	imgs, err := createQuestionsAndOptions(question_list, id)
-	if err != nil {
-		return err
-	}
-	new_imgs = append(new_imgs, imgs...)
-	urlHost := GetConfigUrl()
-	// 删除无用图片
-	for _, oldImg := range old_imgs {
-		if !contains(new_imgs, oldImg) {
-			err = os.Remove("./public/static/" + strings.TrimPrefix(oldImg, urlHost+"/public/static/"))
-			if err != nil {
-				return err
-			}
-		}
-	}
-	return nil
-}
-
-// UserInManage 用户是否在管理中
-func UserInManage(uid int, sid int) bool {
-	_, err := d.GetManageByUIDAndSID(ctx, uid, sid)
-	return err == nil
-}
-
-// DeleteSurvey 删除问卷
-func DeleteSurvey(id int) error {
-	var questions []model.Question
-	questions, err := d.GetQuestionsBySurveyID(ctx, id)
-	if err != nil {
-		return err
-	}
-	var answerSheets []dao.AnswerSheet
-	answerSheets, _, err = d.GetAnswerSheetBySurveyID(ctx, id, 0, 0, "", false)
-	if err != nil {
-		return err
-	}
-	// 删除图片
-	imgs, err := getDelImgs(questions, answerSheets)
-	if err != nil {
-		return err
-	}
-	// 删除文件
-	files, err := getDelFiles(answerSheets)
-	if err != nil {
-		return err
-	}
-	urlHost := GetConfigUrl()
-	for _, img := range imgs {
-		err = os.Remove("./public/static/" + strings.TrimPrefix(img, urlHost+"/public/static/"))
-		if err != nil {
+		// 提交后再清除缓存和删除无用图片
+		if err := o.add(OutboxClearQuestionCache, nil); err != nil {
 			return err
 		}
-	}
-	for _, file := range files {
-		err = os.Remove("./public/file/" + strings.TrimPrefix(file, urlHost+"/public/file/"))
-		if err != nil {
-			return err
+		unusedImgs := make([]string, 0)
+		for _, oldImg := range oldImgs {
+			if !contains(newImgs, oldImg) {
+				unusedImgs = append(unusedImgs, oldImg)
+			}
 		}
-	}
-	// 删除答卷
-	err = DeleteAnswerSheetBySurveyID(id)
-	if err != nil {
-		return err
-	}
-	// 删除问题、选项、问卷、管理
-	for _, question := range questions {
-		err = d.DeleteOption(ctx, question.ID)
-		if err != nil {
-			return err
+		if len(unusedImgs) == 0 {
+			return nil
 		}
-	}
-	err = d.DeleteQuestionBySurveyID(ctx, id)
-	if err != nil {
-		return err
-	}
-	err = dao.DeleteAllQuestionCache(ctx)
-	if err != nil {
-		return err
-	}
-	err = dao.DeleteAllOptionCache(ctx)
-	if err != nil {
-		return err
-	}
-	err = d.DeleteSurvey(ctx, id)
-	if err != nil {
-		return err
-	}
-	err = d.DeleteManageBySurveyID(ctx, id)
-	return err
+		return o.add(OutboxRemoveUploads, removeUploadsPayload{URLs: unusedImgs})
+	})
 }
 
 // GetSurveyAnswers 获取问卷答案
@@ -280,6 +236,16 @@ func GetSurveyAnswers(id int, num int, size int, text string, unique bool) (dao.
 			for i, q := range data {
 				if q.Title == question.Subject {
 					data[i].Answers = append(data[i].Answers, answer.Content)
+					if q.QuestionType == 5 {
+						data[i].Thumbs = append(data[i].Thumbs, answerThumbs(answer.Content))
+					}
+					if q.QuestionType == 6 {
+						status, err := answerScanStatus(answer.Content)
+						if err != nil {
+							return dao.AnswersResonse{}, nil, err
+						}
+						data[i].ScanStatus = append(data[i].ScanStatus, status)
+					}
 				}
 			}
 		}
@@ -287,6 +253,38 @@ func GetSurveyAnswers(id int, num int, size int, text string, unique bool) (dao.
 	return dao.AnswersResonse{QuestionAnswers: data, AnswerIDs: aids, Time: times}, total, nil
 }
 
+// answerThumbs 获取图片题答案的缩略图
+func answerThumbs(content string) string {
+	if content == "" {
+		return ""
+	}
+	urls := strings.Split(content, "┋")
+	for i, url := range urls {
+		urls[i] = ThumbURL(url)
+	}
+	return strings.Join(urls, "┋")
+}
+
+// answerScanStatus 获取文件题答案的安全扫描状态, 未扫描的文件为 unscanned
+func answerScanStatus(content string) (string, error) {
+	if content == "" {
+		return "", nil
+	}
+	urls := strings.Split(content, "┋")
+	status, err := GetUploadScanStatus(urls)
+	if err != nil {
+		return "", err
+	}
+	result := make([]string, len(urls))
+	for i, url := range urls {
+		result[i] = status[url]
+		if result[i] == "" {
+			result[i] = "unscanned"
+		}
+	}
+	return strings.Join(result, "┋"), nil
+}
+
 // GetSurveyByUserID 获取用户的所有问卷
 func GetSurveyByUserID(userId int) ([]model.Survey, error) {
 	return d.GetSurveyByUserID(ctx, userId)
@@ -432,12 +430,12 @@ func contains(arr []string, str string) bool {
 	return false
 }
 
-func getOldImgs(questions []model.Question) ([]string, error) {
+func getOldImgs(tx *dao.Dao, questions []model.Question) ([]string, error) {
 	imgs := make([]string, 0)
 	for _, question := range questions {
 		imgs = append(imgs, question.Img)
 		var options []model.Option
-		options, err := d.GetOptionsByQuestionID(ctx, question.ID)
+		options, err := tx.GetOptionsByQuestionID(ctx, question.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -448,50 +446,7 @@ func getOldImgs(questions []model.Question) ([]string, error) {
 	return imgs, nil
 }
 
-func getDelImgs(questions []model.Question, answerSheets []dao.AnswerSheet) ([]string, error) {
-	imgs := make([]string, 0)
-	for _, question := range questions {
-		imgs = append(imgs, question.Img)
-		var options []model.Option
-		options, err := d.GetOptionsByQuestionID(ctx, question.ID)
-		if err != nil {
-			return nil, err
-		}
-		for _, option := range options {
-			imgs = append(imgs, option.Img)
-		}
-	}
-	for _, answerSheet := range answerSheets {
-		for _, answer := range answerSheet.Answers {
-			question, err := d.GetQuestionByID(ctx, answer.QuestionID)
-			if err != nil {
-				return nil, err
-			}
-			if question.QuestionType == 5 {
-				imgs = append(imgs, answer.Content)
-			}
-		}
-	}
-	return imgs, nil
-}
-
-func getDelFiles(answerSheets []dao.AnswerSheet) ([]string, error) {
-	var files []string
-	for _, answerSheet := range answerSheets {
-		for _, answer := range answerSheet.Answers {
-			question, err := d.GetQuestionByID(ctx, answer.QuestionID)
-			if err != nil {
-				return nil, err
-			}
-			if question.QuestionType == 6 {
-				files = append(files, answer.Content)
-			}
-		}
-	}
-	return files, nil
-}
-
-func createQuestionsAndOptions(question_list []dao.QuestionList, sid int) ([]string, error) {
+func createQuestionsAndOptions(tx *dao.Dao, question_list []dao.QuestionList, sid int) ([]string, error) {
 	imgs := make([]string, 0)
 	for _, question_list := range question_list {
 		var q model.Question
@@ -507,8 +462,13 @@ func createQuestionsAndOptions(question_list []dao.QuestionList, sid int) ([]str
 		q.MaximumOption = question_list.QuestionSetting.MaximumOption
 		q.MinimumOption = question_list.QuestionSetting.MinimumOption
 		q.Reg = question_list.QuestionSetting.Reg
+		q.FileTypes = question_list.QuestionSetting.FileTypes
+		q.MaxFileSize = question_list.QuestionSetting.MaxFileSize
+		q.MaxFileCount = question_list.QuestionSetting.MaxFileCount
+		q.UploadQuota = question_list.QuestionSetting.UploadQuota
+		q.SendCopy = question_list.QuestionSetting.SendCopy && q.QuestionType == 3
 		imgs = append(imgs, question_list.Img)
-		q, err := d.CreateQuestion(ctx, q)
+		q, err := tx.CreateQuestion(ctx, q)
 		if err != nil {
 			return nil, err
 		}
@@ -520,12 +480,15 @@ func createQuestionsAndOptions(question_list []dao.QuestionList, sid int) ([]str
 			o.Img = option.Img
 			o.Description = option.Description
 			imgs = append(imgs, option.Img)
-			err := d.CreateOption(ctx, o)
+			err := tx.CreateOption(ctx, o)
 			if err != nil {
 				return nil, err
 			}
 		}
 	}
+	if err := attachUploads(tx, imgs, sid); err != nil {
+		return nil, err
+	}
 	return imgs, nil
 }
 
@@ -535,30 +498,22 @@ func DeleteAnswerSheetBySurveyID(surveyID int) error {
 	return err
 }
 
-func aesDecryptPassword(user *model.User) {
-	user.Password = utils.AesDecrypt(user.Password)
-}
-
-func aesEncryptPassword(user *model.User) {
-	user.Password = utils.AesEncrypt(user.Password)
-}
-
-// HandleDownloadFile 处理下载文件
-func HandleDownloadFile(answers dao.AnswersResonse, survey *model.Survey) (string, error) {
+// HandleDownloadFile 将答卷数据写入 Excel 文件, progress 用于汇报写入进度(0-100)
+func HandleDownloadFile(answers dao.AnswersResonse, filePath string, progress func(int)) error {
 	questionAnswers := answers.QuestionAnswers
 	times := answers.Time
 	// 创建一个新的Excel文件
 	f := excelize.NewFile()
 	streamWriter, err := f.NewStreamWriter("Sheet1")
 	if err != nil {
-		return "", errors.New("创建Excel文件失败原因: " + err.Error())
+		return errors.New("创建Excel文件失败原因: " + err.Error())
 	}
 	// 设置字体样式
 	styleID, err := f.NewStyle(&excelize.Style{
 		Font: &excelize.Font{Bold: true},
 	})
 	if err != nil {
-		return "", errors.New("设置字体样式失败原因: " + err.Error())
+		return errors.New("设置字体样式失败原因: " + err.Error())
 	}
 	// 计算每列的最大宽度
 	maxWidths := make(map[int]int)
@@ -578,7 +533,7 @@ func HandleDownloadFile(answers dao.AnswersResonse, survey *model.Survey) (strin
 			width = 255
 		}
 		if err := streamWriter.SetColWidth(colIndex+1, colIndex+1, float64(width)); err != nil {
-			return "", errors.New("设置列宽失败原因: " + err.Error())
+			return errors.New("设置列宽失败原因: " + err.Error())
 		}
 	}
 	// 写入标题行
@@ -589,66 +544,68 @@ func HandleDownloadFile(answers dao.AnswersResonse, survey *model.Survey) (strin
 		rowData = append(rowData, excelize.Cell{Value: qa.Title, StyleID: styleID})
 	}
 	if err := streamWriter.SetRow("A1", rowData); err != nil {
-		return "", errors.New("写入标题行失败原因: " + err.Error())
+		return errors.New("写入标题行失败原因: " + err.Error())
 	}
 	// 写入数据
+	lastProgress := -1
 	for i, t := range times {
 		row := []any{i + 1, t}
-		for j, qa := range questionAnswers {
+		for _, qa := range questionAnswers {
 			if len(qa.Answers) <= i {
 				continue
 			}
-			answer := qa.Answers[i]
-			row = append(row, answer)
-			colName, err := excelize.ColumnNumberToName(j + 3)
-			if err != nil {
-				return "", errors.New("转换列名失败原因: " + err.Error())
-			}
-			if err := f.SetCellValue("Sheet1", colName+strconv.Itoa(i+2), answer); err != nil {
-				return "", errors.New("写入数据失败原因: " + err.Error())
-			}
+			row = append(row, qa.Answers[i])
 		}
 		if err := streamWriter.SetRow(fmt.Sprintf("A%d", i+2), row); err != nil {
-			return "", errors.New("写入数据失败原因: " + err.Error())
+			return errors.New("写入数据失败原因: " + err.Error())
+		}
+		// 仅在进度变化时汇报
+		if p := (i + 1) * 100 / len(times); progress != nil && p != lastProgress {
+			lastProgress = p
+			progress(p)
 		}
 	}
 	// 关闭
 	if err := streamWriter.Flush(); err != nil {
-		return "", errors.New("关闭失败原因: " + err.Error())
+		return errors.New("关闭失败原因: " + err.Error())
 	}
 	// 保存Excel文件
-	fileName := survey.Title + ".xlsx"
-	filePath := "./public/xlsx/" + fileName
-	if _, err := os.Stat("./public/xlsx/"); os.IsNotExist(err) {
-		err := os.Mkdir("./public/xlsx/", 0750)
-		if err != nil {
-			return "", errors.New("创建文件夹失败原因: " + err.Error())
-		}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0750); err != nil {
+		return errors.New("创建文件夹失败原因: " + err.Error())
 	}
-	// 删除旧文件
-	if _, err := os.Stat(filePath); err == nil {
-		if err := os.Remove(filePath); err != nil {
-			return "", errors.New("删除旧文件失败原因: " + err.Error())
-		}
-	}
-	// 保存
 	if err := f.SaveAs(filePath); err != nil {
-		return "", errors.New("保存文件失败原因: " + err.Error())
+		return errors.New("保存文件失败原因: " + err.Error())
 	}
-
-	urlHost := GetConfigUrl()
-	url := urlHost + "/public/xlsx/" + fileName
-
-	return url, nil
+	return nil
 }
 
 // UpdateAdminPassword 更新管理员密码
 func UpdateAdminPassword(id int, password string) error {
-	encryptedPassword := utils.AesEncrypt(password)
-	err := d.UpdateUserPassword(ctx, id, encryptedPassword)
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	err = d.UpdateUserPassword(ctx, id, hash, false)
 	return err
 }
 
+// ResetAdminPassword 重置管理员密码为随机的一次性密码, 该密码在首次登录后必须修改
+func ResetAdminPassword(id int) (string, error) {
+	password, err := utils.RandomPassword(12)
+	if err != nil {
+		return "", err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return "", err
+	}
+	err = d.UpdateUserPassword(ctx, id, hash, true)
+	if err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
 // CreateQuestionPre 创建问题预先信息
 func CreateQuestionPre(name string, value []string) error {
 	// 将String[]类型转化为String,以逗号分隔
@@ -669,19 +626,7 @@ func GetQuestionPre(name string) ([]string, error) {
 	return pre, nil
 }
 
-// DeleteOauthRecord 删除统一记录
-func DeleteOauthRecord(sid int) error {
-	return d.DeleteRecordSheets(ctx, sid)
-}
-
-// DeleteAnswerSheetByAnswerID 根据问卷ID删除问卷
-func DeleteAnswerSheetByAnswerID(answerID primitive.ObjectID) error {
-	err := d.DeleteAnswerSheetByAnswerID(ctx, answerID)
-	return err
-}
-
-// GetAnswerSheetByAnswerID 根据答卷ID删除答卷
-func GetAnswerSheetByAnswerID(answerID primitive.ObjectID) error {
-	err := d.GetAnswerSheetByAnswerID(ctx, answerID)
-	return err
+// GetAnswerSheetByAnswerID 根据答卷ID获取答卷
+func GetAnswerSheetByAnswerID(answerID primitive.ObjectID) (dao.AnswerSheet, error) {
+	return d.GetAnswerSheetByAnswerID(ctx, answerID)
 }
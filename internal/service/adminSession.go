@@ -0,0 +1,152 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"QA-System/internal/pkg/redis"
+	"github.com/gin-gonic/gin"
+	redisPkg "github.com/redis/go-redis/v9"
+)
+
+// sessionMaxAge 管理员会话有效期, 与 cookie 有效期一致
+const sessionMaxAge = 7 * 24 * time.Hour
+
+// AdminSession 管理员登录会话
+type AdminSession struct {
+	ID        string    `json:"id"`         // 会话ID
+	UserID    int       `json:"user_id"`    // 用户ID
+	IP        string    `json:"ip"`         // 最近访问IP
+	UserAgent string    `json:"user_agent"` // 浏览器标识
+	CreatedAt time.Time `json:"created_at"` // 登录时间
+	LastSeen  time.Time `json:"last_seen"`  // 最近访问时间
+	Current   bool      `json:"current"`    // 是否为当前会话, 仅在列表中返回
+}
+
+func adminSessionKey(sid string) string {
+	return "admin:session:" + sid
+}
+
+func userSessionsKey(uid int) string {
+	return "admin:sessions:" + strconv.Itoa(uid)
+}
+
+func saveAdminSession(s *AdminSession) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.Set(ctx, adminSessionKey(s.ID), data, sessionMaxAge)
+	pipe.SAdd(ctx, userSessionsKey(s.UserID), s.ID)
+	pipe.Expire(ctx, userSessionsKey(s.UserID), sessionMaxAge)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func getAdminSession(sid string) (*AdminSession, error) {
+	data, err := redis.RedisClient.Get(ctx, adminSessionKey(sid)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var s AdminSession
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// createAdminSession 登记新的登录会话
+func createAdminSession(c *gin.Context, uid int) (string, error) {
+	sid, err := randomFileName()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	err = saveAdminSession(&AdminSession{
+		ID:        sid,
+		UserID:    uid,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		CreatedAt: now,
+		LastSeen:  now,
+	})
+	return sid, err
+}
+
+// touchAdminSession 检查会话是否仍然有效并记录访问, 会话已被注销时返回 nil
+func touchAdminSession(c *gin.Context, uid int, sid string) (*AdminSession, error) {
+	s, err := getAdminSession(sid)
+	if errors.Is(err, redisPkg.Nil) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if s.UserID != uid {
+		return nil, nil
+	}
+	// 降低写入频率, 最近访问时间精确到分钟即可
+	if time.Since(s.LastSeen) > time.Minute {
+		s.LastSeen = time.Now()
+		s.IP = c.ClientIP()
+		if err := saveAdminSession(s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// ListAdminSessions 获取用户的全部有效会话
+func ListAdminSessions(uid int) ([]AdminSession, error) {
+	sids, err := redis.RedisClient.SMembers(ctx, userSessionsKey(uid)).Result()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]AdminSession, 0, len(sids))
+	for _, sid := range sids {
+		s, err := getAdminSession(sid)
+		if errors.Is(err, redisPkg.Nil) {
+			// 会话已过期, 顺便清理索引
+			if err := redis.RedisClient.SRem(ctx, userSessionsKey(uid), sid).Err(); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		list = append(list, *s)
+	}
+	return list, nil
+}
+
+// RevokeAdminSession 注销用户的指定会话, 返回会话是否存在
+func RevokeAdminSession(uid int, sid string) (bool, error) {
+	ok, err := redis.RedisClient.SIsMember(ctx, userSessionsKey(uid), sid).Result()
+	if err != nil || !ok {
+		return false, err
+	}
+	pipe := redis.RedisClient.TxPipeline()
+	pipe.Del(ctx, adminSessionKey(sid))
+	pipe.SRem(ctx, userSessionsKey(uid), sid)
+	_, err = pipe.Exec(ctx)
+	return true, err
+}
+
+// RevokeAdminSessions 注销用户除 except 外的全部会话
+func RevokeAdminSessions(uid int, except string) error {
+	sids, err := redis.RedisClient.SMembers(ctx, userSessionsKey(uid)).Result()
+	if err != nil {
+		return err
+	}
+	for _, sid := range sids {
+		if sid == except {
+			continue
+		}
+		if _, err := RevokeAdminSession(uid, sid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/captcha"
+	"QA-System/internal/pkg/redis"
+	"QA-System/internal/pkg/utils"
+	redisPkg "github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrChallengeRequired 问卷需要人机验证但未提交验证结果
+	ErrChallengeRequired = errors.New("请先完成人机验证")
+	// ErrChallengeFailed 验证结果错误或已过期, 需重新获取
+	ErrChallengeFailed = errors.New("人机验证未通过")
+)
+
+// Challenge 下发给前端的人机验证题目
+type Challenge struct {
+	ID         string `json:"id"`
+	Type       int    `json:"type"`                 // 1:工作量证明 2:图片验证码
+	Prefix     string `json:"prefix,omitempty"`     // 工作量证明的前缀
+	Difficulty int    `json:"difficulty,omitempty"` // sha256(prefix+answer) 需要的前导零比特数
+	Image      string `json:"image,omitempty"`      // 验证码图片, data URL
+	ExpireAt   int64  `json:"expire_at"`
+}
+
+// challengeState 存放在 Redis 中的验证状态
+type challengeState struct {
+	SurveyID   int    `json:"survey_id"`
+	Type       int    `json:"type"`
+	Prefix     string `json:"prefix,omitempty"`
+	Difficulty int    `json:"difficulty,omitempty"`
+	Code       string `json:"code,omitempty"`
+}
+
+func challengeKey(id string) string {
+	return "challenge:" + id
+}
+
+func throttleKey(kind string, surveyID int, value string) string {
+	return "throttle:submit:" + kind + ":" + strconv.Itoa(surveyID) + ":" + value
+}
+
+func getAntibotConfig(key string, def int) int {
+	if global.Config.IsSet("antibot." + key) {
+		return global.Config.GetInt("antibot." + key)
+	}
+	return def
+}
+
+// HoneypotFields 蜜罐字段名, 前端渲染为隐藏输入框, 正常用户提交时为空
+func HoneypotFields() []string {
+	if global.Config.IsSet("antibot.honeypot-fields") {
+		return global.Config.GetStringSlice("antibot.honeypot-fields")
+	}
+	return []string{"website"}
+}
+
+// CheckHoneypot 返回被填写的蜜罐字段, 均为空时返回空字符串
+func CheckHoneypot(body map[string]any) string {
+	for _, field := range HoneypotFields() {
+		v, ok := body[field]
+		if !ok || v == nil {
+			continue
+		}
+		if s, isStr := v.(string); isStr && s == "" {
+			continue
+		}
+		return field
+	}
+	return ""
+}
+
+// IssueChallenge 为问卷生成一次性的人机验证题目, 问卷未开启时返回 nil
+func IssueChallenge(survey *model.Survey) (*Challenge, error) {
+	if survey.Challenge == model.ChallengeNone {
+		return nil, nil
+	}
+	id, err := utils.RandomPassword(32)
+	if err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(getAntibotConfig("challenge-ttl", 600)) * time.Second
+	challenge := &Challenge{ID: id, Type: survey.Challenge, ExpireAt: time.Now().Add(ttl).Unix()}
+	state := challengeState{SurveyID: survey.ID, Type: survey.Challenge}
+	switch survey.Challenge {
+	case model.ChallengePoW:
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		state.Prefix = hex.EncodeToString(buf)
+		state.Difficulty = getAntibotConfig("pow-difficulty", 18)
+		challenge.Prefix, challenge.Difficulty = state.Prefix, state.Difficulty
+	case model.ChallengeCaptcha:
+		code, img, err := captcha.Generate(getAntibotConfig("captcha-length", 5))
+		if err != nil {
+			return nil, err
+		}
+		state.Code = code
+		challenge.Image = "data:image/png;base64," + base64.StdEncoding.EncodeToString(img)
+	default:
+		return nil, errors.New("未知的人机验证方式")
+	}
+	value, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	if err := redis.RedisClient.Set(ctx, challengeKey(id), value, ttl).Err(); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// VerifyChallenge 校验人机验证结果, 每个题目只能使用一次
+func VerifyChallenge(surveyID int, id, answer string) error {
+	if id == "" || answer == "" {
+		return ErrChallengeRequired
+	}
+	value, err := redis.RedisClient.GetDel(ctx, challengeKey(id)).Bytes()
+	if errors.Is(err, redisPkg.Nil) {
+		return ErrChallengeFailed
+	} else if err != nil {
+		return err
+	}
+	var state challengeState
+	if err := json.Unmarshal(value, &state); err != nil {
+		return err
+	}
+	if state.SurveyID != surveyID {
+		return ErrChallengeFailed
+	}
+	switch state.Type {
+	case model.ChallengePoW:
+		// 限制长度, 避免超长答案占用哈希计算
+		if len(answer) > 64 || leadingZeroBits(sha256.Sum256([]byte(state.Prefix+answer))) < state.Difficulty {
+			return ErrChallengeFailed
+		}
+	case model.ChallengeCaptcha:
+		if !strings.EqualFold(strings.TrimSpace(answer), state.Code) {
+			return ErrChallengeFailed
+		}
+	default:
+		return ErrChallengeFailed
+	}
+	return nil
+}
+
+func leadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// CheckSubmitThrottle 按 IP 与设备指纹统计问卷的提交次数, 超出限制时返回 false
+func CheckSubmitThrottle(surveyID int, ip, fingerprint string) (bool, error) {
+	window := time.Duration(getAntibotConfig("window", 3600)) * time.Second
+	limits := []struct {
+		kind  string
+		value string
+		limit int64
+	}{
+		{"ip", ip, int64(getAntibotConfig("ip-limit", 30))},
+		{"fp", fingerprint, int64(getAntibotConfig("fingerprint-limit", 10))},
+	}
+	allowed := true
+	for _, l := range limits {
+		if l.limit <= 0 || l.value == "" {
+			continue
+		}
+		// 指纹由前端提供, 取哈希避免超长键
+		if l.kind == "fp" {
+			sum := sha256.Sum256([]byte(l.value))
+			l.value = hex.EncodeToString(sum[:])
+		}
+		key := throttleKey(l.kind, surveyID, l.value)
+		count, err := redis.RedisClient.Incr(ctx, key).Result()
+		if err != nil {
+			return false, err
+		}
+		// 固定窗口, 只在窗口内首次提交时设置过期时间
+		if count == 1 {
+			if err := redis.RedisClient.Expire(ctx, key, window).Err(); err != nil {
+				return false, err
+			}
+		}
+		if count > l.limit {
+			zap.L().Warn("Submit throttled", zap.Int("survey_id", surveyID), zap.String("kind", l.kind),
+				zap.String("ip", ip), zap.Int64("count", count))
+			allowed = false
+		}
+	}
+	return allowed, nil
+}
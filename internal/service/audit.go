@@ -0,0 +1,189 @@
+package service
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 审计日志的操作类型
+const (
+	AuditSurveyCreate       = "survey.create"
+	AuditSurveyImport       = "survey.import"
+	AuditSurveyUpdate       = "survey.update"
+	AuditSurveyStatus       = "survey.status"
+	AuditSurveyDelete       = "survey.delete"
+	AuditSurveyRestore      = "survey.restore"
+	AuditSurveyPurge        = "survey.purge"
+	AuditAnswerSheetDelete  = "answer_sheet.delete"
+	AuditAnswerSheetRestore = "answer_sheet.restore"
+	AuditAnswerSheetImport  = "answer_sheet.import"
+	AuditPermissionCreate   = "permission.create"
+	AuditPermissionUpdate   = "permission.update"
+	AuditPermissionDelete   = "permission.delete"
+	AuditExportCreate       = "export.create"
+	AuditExportFiles        = "export.files"
+	AuditUserCreate         = "user.create"
+	AuditUserPasswordReset  = "user.password_reset"
+	AuditUserStatus         = "user.status"
+	AuditUserType           = "user.type"
+	AuditUserDelete         = "user.delete"
+	AuditUserUnlock         = "user.unlock"
+	AuditUserTOTPReset      = "user.totp_reset"
+	AuditUserSessionsRevoke = "user.sessions_revoke"
+	AuditInvitationCreate   = "invitation.create"
+	AuditInvitationRevoke   = "invitation.revoke"
+	AuditWebhookCreate      = "webhook.create"
+	AuditWebhookUpdate      = "webhook.update"
+	AuditWebhookDelete      = "webhook.delete"
+	AuditWebhookRedeliver   = "webhook.redeliver"
+	AuditSetting2FAPolicy   = "setting.2fa_policy"
+	AuditNotificationUpdate = "notification.update"
+)
+
+// 审计日志的操作对象类型
+const (
+	AuditTargetSurvey      = "survey"
+	AuditTargetAnswerSheet = "answer_sheet"
+	AuditTargetUser        = "user"
+	AuditTargetInvitation  = "invitation"
+	AuditTargetExport      = "export"
+	AuditTargetWebhook     = "webhook"
+	AuditTargetSetting     = "setting"
+	AuditTargetNotify      = "notification" // 操作对象id为订阅者id
+)
+
+// AuditEntry 一条待记录的审计日志
+type AuditEntry struct {
+	Action     string // 操作类型
+	SurveyID   int    // 相关问卷id, 与问卷无关时为 0
+	TargetType string // 操作对象类型
+	TargetID   string // 操作对象id
+	Before     any    // 变更前的数据, 创建时为空
+	After      any    // 变更后的数据, 删除时为空
+}
+
+// RecordAudit 记录管理操作, 写入失败只记录日志而不影响请求
+func RecordAudit(c *gin.Context, user *model.User, entry AuditEntry) {
+	diff, err := auditDiff(entry.Before, entry.After)
+	if err != nil {
+		zap.L().Error("Failed to build audit diff", zap.String("action", entry.Action), zap.Error(err))
+	}
+	log := &model.AuditLog{
+		UserID:     user.ID,
+		Username:   user.Username,
+		Action:     entry.Action,
+		SurveyID:   entry.SurveyID,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		IP:         c.ClientIP(),
+		Diff:       diff,
+		CreatedAt:  time.Now(),
+	}
+	if value, ok := c.Get(apiTokenKey); ok {
+		if token, ok := value.(*model.APIToken); ok {
+			log.TokenID = token.ID
+		}
+	}
+	if err := d.CreateAuditLog(ctx, log); err != nil {
+		zap.L().Error("Failed to create audit log", zap.String("action", entry.Action),
+			zap.Int("user", user.ID), zap.Error(err))
+	}
+}
+
+// SystemAuditUsername 命令行等非 HTTP 操作在审计日志中的操作者
+const SystemAuditUsername = "system"
+
+// RecordSystemAudit 记录命令行执行的管理操作
+func RecordSystemAudit(entry AuditEntry) {
+	diff, err := auditDiff(entry.Before, entry.After)
+	if err != nil {
+		zap.L().Error("Failed to build audit diff", zap.String("action", entry.Action), zap.Error(err))
+	}
+	log := &model.AuditLog{
+		Username:   SystemAuditUsername,
+		Action:     entry.Action,
+		SurveyID:   entry.SurveyID,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Diff:       diff,
+		CreatedAt:  time.Now(),
+	}
+	if err := d.CreateAuditLog(ctx, log); err != nil {
+		zap.L().Error("Failed to create audit log", zap.String("action", entry.Action), zap.Error(err))
+	}
+}
+
+// auditDiff 计算变更前后的差异, 仅保留发生变化的字段
+func auditDiff(before, after any) (string, error) {
+	if before == nil && after == nil {
+		return "", nil
+	}
+	if before == nil || after == nil {
+		data, err := json.Marshal(map[string]any{"before": before, "after": after})
+		return string(data), err
+	}
+	beforeMap, err := toAuditMap(before)
+	if err != nil {
+		return "", err
+	}
+	afterMap, err := toAuditMap(after)
+	if err != nil {
+		return "", err
+	}
+	changes := make(map[string]map[string]any)
+	for key, value := range afterMap {
+		if old, ok := beforeMap[key]; !ok || !reflect.DeepEqual(old, value) {
+			changes[key] = map[string]any{"before": beforeMap[key], "after": value}
+		}
+	}
+	for key, old := range beforeMap {
+		if _, ok := afterMap[key]; !ok {
+			changes[key] = map[string]any{"before": old, "after": nil}
+		}
+	}
+	data, err := json.Marshal(changes)
+	return string(data), err
+}
+
+func toAuditMap(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]any)
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// GetSurveySnapshot 获取问卷及题目的快照, 用于审计日志对比
+func GetSurveySnapshot(sid int) (map[string]any, error) {
+	survey, err := d.GetSurveyByID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	subjects := make([]string, 0, len(questions))
+	for _, question := range questions {
+		subjects = append(subjects, question.Subject)
+	}
+	snapshot, err := toAuditMap(survey)
+	if err != nil {
+		return nil, err
+	}
+	snapshot["questions"] = subjects
+	return snapshot, nil
+}
+
+// GetAuditLogs 分页查询审计日志
+func GetAuditLogs(filter dao.AuditFilter, pageNum, pageSize int) ([]model.AuditLog, *int64, error) {
+	return d.GetAuditLogs(ctx, filter, pageNum, pageSize)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"QA-System/internal/dao"
+	r "QA-System/internal/pkg/redis"
+)
+
+// RebuildCache 清除问题、选项与配置缓存, 并为已发布的问卷预热问题与选项缓存, 返回预热的问卷数
+func RebuildCache() (int, error) {
+	if err := dao.DeleteAllQuestionCache(ctx); err != nil {
+		return 0, err
+	}
+	if err := dao.DeleteAllOptionCache(ctx); err != nil {
+		return 0, err
+	}
+	// 配置项缓存在下次读取时重新加载
+	if err := r.RedisClient.Del(ctx, "url", "key").Err(); err != nil {
+		return 0, err
+	}
+	surveys, err := d.GetAllSurvey(ctx)
+	if err != nil {
+		return 0, err
+	}
+	num := 0
+	for _, survey := range surveys {
+		if survey.Status != 2 {
+			continue
+		}
+		questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+		if err != nil {
+			return num, err
+		}
+		for _, question := range questions {
+			if _, err := d.GetQuestionByID(ctx, question.ID); err != nil {
+				return num, err
+			}
+			if _, err := d.GetOptionsByQuestionID(ctx, question.ID); err != nil {
+				return num, err
+			}
+		}
+		num++
+	}
+	return num, nil
+}
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"QA-System/internal/dao"
@@ -59,3 +61,9 @@ func GetRedis(key string) string {
 	}
 	return result
 }
+
+// hashSecret 计算邀请码、恢复码等一次性凭据的摘要, 数据库中不保存明文
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,122 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"QA-System/internal/model"
+	"go.uber.org/zap"
+)
+
+// ConsistencyReport 数据一致性检查结果
+type ConsistencyReport struct {
+	OrphanQuestions    []int `json:"orphan_questions"`     // 所属问卷已被彻底删除的问题
+	OrphanOptions      []int `json:"orphan_options"`       // 所属问题已不存在的选项
+	OrphanManages      []int `json:"orphan_manages"`       // 所属问卷已被彻底删除的权限记录
+	OrphanAnswerSheets []int `json:"orphan_answer_sheets"` // 答卷所属问卷已被彻底删除的问卷id
+	OrphanRecordSheets []int `json:"orphan_record_sheets"` // 统一验证记录所属问卷已被彻底删除的问卷id
+	PendingOutbox      int64 `json:"pending_outbox"`       // 未执行成功的发件箱事件数
+	Repaired           bool  `json:"repaired"`             // 是否已修复
+}
+
+// CheckConsistency 检查 MySQL 与 MongoDB 之间的孤立数据, repair 为 true 时删除孤立数据并重试发件箱事件
+func CheckConsistency(repair bool) (*ConsistencyReport, error) {
+	var report ConsistencyReport
+	var err error
+	if report.OrphanQuestions, err = d.GetOrphanQuestionIDs(ctx); err != nil {
+		return nil, err
+	}
+	if report.OrphanOptions, err = d.GetOrphanOptionIDs(ctx); err != nil {
+		return nil, err
+	}
+	if report.OrphanManages, err = d.GetOrphanManageIDs(ctx); err != nil {
+		return nil, err
+	}
+	surveyIDs, err := d.GetAllSurveyIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exists := make(map[int]bool, len(surveyIDs))
+	for _, id := range surveyIDs {
+		exists[id] = true
+	}
+	answerSurveyIDs, err := d.GetAnswerSheetSurveyIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.OrphanAnswerSheets = missingIDs(answerSurveyIDs, exists)
+	recordSurveyIDs, err := d.GetRecordSheetSurveyIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.OrphanRecordSheets = missingIDs(recordSurveyIDs, exists)
+	if report.PendingOutbox, err = d.CountPendingOutboxEvents(ctx); err != nil {
+		return nil, err
+	}
+	if !repair {
+		return &report, nil
+	}
+
+	if err := repairConsistency(&report); err != nil {
+		return nil, err
+	}
+	report.Repaired = true
+	if report.PendingOutbox, err = d.CountPendingOutboxEvents(ctx); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func repairConsistency(report *ConsistencyReport) error {
+	// 先重试发件箱事件, 其中多数孤立数据本就应由发件箱清除
+	events, err := d.GetPendingOutboxEvents(ctx, time.Now(), math.MaxInt32, math.MaxInt32)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		dispatchOutboxEvent(&events[i])
+	}
+	if len(report.OrphanOptions) > 0 {
+		if err := d.DeleteOptionsByIDs(ctx, report.OrphanOptions); err != nil {
+			return err
+		}
+	}
+	if len(report.OrphanQuestions) > 0 {
+		if err := d.DeleteQuestionsByIDs(ctx, report.OrphanQuestions); err != nil {
+			return err
+		}
+	}
+	if len(report.OrphanManages) > 0 {
+		if err := d.DeleteManagesByIDs(ctx, report.OrphanManages); err != nil {
+			return err
+		}
+	}
+	for _, sid := range report.OrphanAnswerSheets {
+		if err := d.DeleteAnswerSheetBySurveyID(ctx, sid); err != nil {
+			return err
+		}
+	}
+	for _, sid := range report.OrphanRecordSheets {
+		if err := d.DeleteRecordSheets(ctx, sid); err != nil {
+			return err
+		}
+	}
+	if len(report.OrphanOptions) > 0 || len(report.OrphanQuestions) > 0 {
+		if err := runOutboxEvent(&model.OutboxEvent{Type: OutboxClearQuestionCache}); err != nil {
+			return err
+		}
+	}
+	zap.L().Info("Consistency repaired", zap.Any("report", report))
+	return nil
+}
+
+// missingIDs 返回不在 exists 中的id
+func missingIDs(ids []int, exists map[int]bool) []int {
+	missing := make([]int, 0)
+	for _, id := range ids {
+		if !exists[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/pkg/redis"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// 导出任务状态
+const (
+	ExportPending = "pending" // 排队中
+	ExportRunning = "running" // 导出中
+	ExportDone    = "done"    // 已完成
+	ExportFailed  = "failed"  // 失败
+)
+
+// ExportDir 导出文件存放目录, 不对外静态开放
+const ExportDir = "./public/xlsx/"
+
+// ExportJob 导出任务
+type ExportJob struct {
+	ID        string    `json:"id"`         // 任务ID
+	SurveyID  int       `json:"survey_id"`  // 问卷ID
+	UserID    int       `json:"user_id"`    // 发起导出的管理员ID
+	Status    string    `json:"status"`     // 任务状态
+	Progress  int       `json:"progress"`   // 导出进度 0-100
+	FileName  string    `json:"file_name"`  // 下载时展示的文件名
+	FilePath  string    `json:"file_path"`  // 文件实际存储路径
+	Error     string    `json:"error"`      // 失败原因
+	CreatedAt time.Time `json:"created_at"` // 创建时间
+	ExpireAt  time.Time `json:"expire_at"`  // 文件过期时间
+}
+
+// GetExportTTL 获取导出文件保留时间
+func GetExportTTL() time.Duration {
+	ttl := 24
+	if global.Config.IsSet("export.ttl") {
+		ttl = global.Config.GetInt("export.ttl")
+	}
+	return time.Duration(ttl) * time.Hour
+}
+
+func exportJobKey(id string) string {
+	return "export:job:" + id
+}
+
+func saveExportJob(job *ExportJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return redis.RedisClient.Set(ctx, exportJobKey(job.ID), data, GetExportTTL()).Err()
+}
+
+// CreateExportJob 创建导出任务
+func CreateExportJob(uid int, sid int, fileName string) (*ExportJob, error) {
+	job := &ExportJob{
+		ID:        uuid.New().String(),
+		SurveyID:  sid,
+		UserID:    uid,
+		Status:    ExportPending,
+		FileName:  fileName,
+		CreatedAt: time.Now(),
+	}
+	if err := saveExportJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// GetExportJob 获取导出任务
+func GetExportJob(id string) (*ExportJob, error) {
+	data, err := redis.RedisClient.Get(ctx, exportJobKey(id)).Bytes()
+	if err != nil {
+		return nil, err
+	}
+	var job ExportJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RunExportJob 执行导出任务
+func RunExportJob(id string) error {
+	job, err := GetExportJob(id)
+	if err != nil {
+		return err
+	}
+	job.Status = ExportRunning
+	job.Progress = 0
+	job.Error = ""
+	if err := saveExportJob(job); err != nil {
+		return err
+	}
+
+	err = runExportJob(job)
+	if err != nil {
+		job.Status = ExportFailed
+		job.Error = err.Error()
+		if saveErr := saveExportJob(job); saveErr != nil {
+			zap.L().Error("Failed to save export job", zap.String("job", job.ID), zap.Error(saveErr))
+		}
+		return err
+	}
+	return nil
+}
+
+func runExportJob(job *ExportJob) error {
+	answers, err := GetAllSurveyAnswers(job.SurveyID)
+	if err != nil {
+		return err
+	}
+	// 数据查询完成记为一半进度
+	job.Progress = 50
+	if err := saveExportJob(job); err != nil {
+		return err
+	}
+
+	name, err := randomFileName()
+	if err != nil {
+		return err
+	}
+	filePath := filepath.Join(ExportDir, name+".xlsx")
+	err = HandleDownloadFile(answers, filePath, func(p int) {
+		job.Progress = 50 + p/2
+		if err := saveExportJob(job); err != nil {
+			zap.L().Error("Failed to save export job", zap.String("job", job.ID), zap.Error(err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	job.Status = ExportDone
+	job.Progress = 100
+	job.FilePath = filePath
+	job.ExpireAt = time.Now().Add(GetExportTTL())
+	return saveExportJob(job)
+}
+
+// randomFileName 生成不可猜测的文件名
+func randomFileName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// CleanExpiredExports 清理过期的导出文件
+func CleanExpiredExports() error {
+	entries, err := os.ReadDir(ExportDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(-GetExportTTL())
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(deadline) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(ExportDir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteAnswersCSV 将问卷全部答卷以 CSV 格式写入 w, 每份答卷一行
+func WriteAnswersCSV(w io.Writer, sid int) error {
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return err
+	}
+	// 与 Excel 导出一致, 不包含被同一答卷人重新提交覆盖的答卷
+	answerSheets, _, err := d.GetAnswerSheetBySurveyID(ctx, sid, 0, 0, "", true)
+	if err != nil {
+		return err
+	}
+	// 写入 BOM 以便 Excel 正确识别 UTF-8
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	header := []string{"答卷ID", "提交时间", "学号"}
+	columns := make(map[int]int, len(questions))
+	for _, question := range questions {
+		header = append(header, question.Subject)
+		columns[question.ID] = len(header) - 1
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, answerSheet := range answerSheets {
+		row := make([]string, len(header))
+		row[0], row[1], row[2] = answerSheet.AnswerID.Hex(), answerSheet.Time, answerSheet.StudentID
+		for _, answer := range answerSheet.Answers {
+			if i, ok := columns[answer.QuestionID]; ok {
+				row[i] = answer.Content
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"QA-System/internal/dao"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeExec 记录的写入语句
+type fakeExec struct {
+	Query string
+	Args  []driver.NamedValue
+}
+
+// fakeDB 记录 gorm 生成的写入语句, 查询均返回空结果
+// 用于测试只关心写入了什么的业务逻辑, 不依赖真实的 MySQL
+type fakeDB struct {
+	mu    sync.Mutex
+	execs []fakeExec
+	// rowsAffected 返回写入语句影响的行数, 为空时为 1
+	rowsAffected func(query string, args []driver.NamedValue) int64
+	// execErr 返回写入语句的错误, 为空时不出错
+	execErr func(query string) error
+	// 事务提交与回滚的次数
+	commits, rollbacks int
+}
+
+// useFakeDB 将服务使用的数据访问对象替换为 fakeDB, 测试结束后恢复
+func useFakeDB(t *testing.T) *fakeDB {
+	f := &fakeDB{}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(f),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := d
+	d = dao.New(db, nil)
+	t.Cleanup(func() { d = old })
+	return f
+}
+
+// Execs 返回包含 keyword 的写入语句
+func (f *fakeDB) Execs(keyword string) []fakeExec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var execs []fakeExec
+	for _, e := range f.execs {
+		if strings.Contains(e.Query, keyword) {
+			execs = append(execs, e)
+		}
+	}
+	return execs
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+
+func (f *fakeDB) Driver() driver.Driver { return nil }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeDB 不支持预处理语句")
+}
+
+func (c fakeConn) Close() error { return nil }
+
+func (c fakeConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (c fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.commits++
+	return nil
+}
+
+func (c fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rollbacks++
+	return nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	f := c.db
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.execErr != nil {
+		if err := f.execErr(query); err != nil {
+			return nil, err
+		}
+	}
+	f.execs = append(f.execs, fakeExec{Query: query, Args: args})
+	affected := int64(1)
+	if f.rowsAffected != nil {
+		affected = f.rowsAffected(query, args)
+	}
+	return fakeResult(affected), nil
+}
+
+func (c fakeConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return fakeRows{}, nil
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return 1, nil }
+
+func (r fakeResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string { return nil }
+
+func (fakeRows) Close() error { return nil }
+
+func (fakeRows) Next([]driver.Value) error { return io.EOF }
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// 上传文件在存储中的目录
+const (
+	StaticDir = "static"
+	FileDir   = "file"
+)
+
+// uploadKey 将上传文件的访问地址转换为存储 key
+func uploadKey(url string) (string, bool) {
+	name, ok := strings.CutPrefix(url, GetConfigUrl()+"/public/")
+	if !ok {
+		return "", false
+	}
+	dir, base, ok := strings.Cut(name, "/")
+	if !ok || (dir != StaticDir && dir != FileDir) || base == "" {
+		return "", false
+	}
+	return dir + "/" + path.Base(base), true
+}
+
+// SaveUpload 保存上传文件并返回访问地址, 访问地址不随存储后端变化
+func SaveUpload(dir string, name string, r io.Reader, size int64, contentType string) (string, error) {
+	key := dir + "/" + path.Base(name)
+	if err := storage.Store.Put(ctx, key, r, size, contentType); err != nil {
+		return "", err
+	}
+	return GetConfigUrl() + "/public/" + key, nil
+}
+
+// SignUploadURL 获取上传文件的临时下载地址, 存储后端不支持时返回空字符串
+func SignUploadURL(key string) (string, error) {
+	return storage.Store.SignURL(ctx, key, storage.GetSignExpire())
+}
+
+// OpenUpload 读取上传文件
+func OpenUpload(key string) (io.ReadCloser, error) {
+	return storage.Store.Open(ctx, key)
+}
+
+// OrphanUpload 未被引用的上传文件
+type OrphanUpload struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// referencedUploads 获取问题、选项与答卷(包括回收站中的答卷)引用的文件 key
+func referencedUploads() (map[string]bool, error) {
+	urls, err := d.GetQuestionAndOptionImgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	surveyIDs, err := d.GetAllSurveyIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, sid := range surveyIDs {
+		questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+		if err != nil {
+			return nil, err
+		}
+		answerSheets, err := d.GetAllAnswerSheetsBySurveyID(ctx, sid)
+		if err != nil {
+			return nil, err
+		}
+		for _, answerSheet := range answerSheets {
+			urls = append(urls, answerSheetUploads(questions, answerSheet)...)
+		}
+	}
+	referenced := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		if key, ok := uploadKey(url); ok {
+			referenced[key] = true
+			referenced[thumbKey(key)] = true
+		}
+	}
+	return referenced, nil
+}
+
+// CollectOrphanUploads 查找未被引用的上传文件, dryRun 为 false 时删除
+// 只处理修改时间早于 minAge 的文件, 避免删除刚上传还未随问卷或答卷提交的文件
+func CollectOrphanUploads(minAge time.Duration, dryRun bool) ([]OrphanUpload, error) {
+	referenced, err := referencedUploads()
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(-minAge)
+	orphans := make([]OrphanUpload, 0)
+	for _, dir := range []string{StaticDir, FileDir} {
+		objects, err := storage.Store.List(ctx, dir+"/")
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			if referenced[object.Key] || object.ModTime.After(deadline) {
+				continue
+			}
+			if !dryRun {
+				if err := storage.Store.Delete(ctx, object.Key); err != nil {
+					return orphans, err
+				}
+				if err := d.DeleteUploadsByKeys(ctx, []string{object.Key}); err != nil {
+					return orphans, err
+				}
+			}
+			orphans = append(orphans, OrphanUpload{Path: object.Key, Size: object.Size, ModTime: object.ModTime})
+		}
+	}
+	return orphans, nil
+}
+
+// uniqueZipName 生成压缩包内未使用的文件路径, 同一答卷人多次上传时追加序号
+// 追加序号后仍可能与其他答卷人的文件重名 (如 abc_2), 因此递增序号直到未被使用
+func uniqueZipName(used map[string]bool, dir, base, ext string) string {
+	name := path.Join(dir, base+ext)
+	for n := 2; used[name]; n++ {
+		name = path.Join(dir, fmt.Sprintf("%s_%d%s", base, n, ext))
+	}
+	used[name] = true
+	return name
+}
+
+// sanitizeZipName 去除文件名中不允许出现的字符
+func sanitizeZipName(name string) string {
+	replacer := strings.NewReplacer("/", "_", "\\", "_", ":", "_", "*", "_",
+		"?", "_", "\"", "_", "<", "_", ">", "_", "|", "_", "\n", " ", "\r", " ")
+	name = strings.TrimSpace(replacer.Replace(name))
+	// 避免目录名过长
+	if r := []rune(name); len(r) > 40 {
+		name = string(r[:40])
+	}
+	return name
+}
+
+// WriteSurveyFilesZip 将问卷所有上传的图片和文件打包写入 w
+func WriteSurveyFilesZip(w io.Writer, sid int) error {
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return err
+	}
+	fileQuestions := make(map[int]model.Question)
+	for _, question := range questions {
+		if question.QuestionType == 5 || question.QuestionType == 6 {
+			fileQuestions[question.ID] = question
+		}
+	}
+	answerSheets, _, err := d.GetAnswerSheetBySurveyID(ctx, sid, 0, 0, "", true)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	manifest := [][]string{{"答卷ID", "学号", "提交时间", "题号", "题目", "原始地址", "压缩包内路径", "状态", "安全扫描"}}
+	used := make(map[string]bool)
+	for _, sheet := range answerSheets {
+		respondent := sheet.AnswerID.Hex()
+		if sheet.StudentID != "" {
+			respondent = sheet.StudentID
+		}
+		for _, answer := range sheet.Answers {
+			question, ok := fileQuestions[answer.QuestionID]
+			if !ok || answer.Content == "" {
+				continue
+			}
+			dir := sanitizeZipName(strconv.Itoa(question.SerialNum) + "_" + question.Subject)
+			urls := strings.Split(answer.Content, "┋")
+			scanStatus := make(map[string]string)
+			if question.QuestionType == 6 {
+				if scanStatus, err = GetUploadScanStatus(urls); err != nil {
+					return err
+				}
+			}
+			for _, url := range urls {
+				scan := ""
+				if question.QuestionType == 6 {
+					scan = ScanStatusLabel(scanStatus[url])
+				}
+				status := "正常"
+				zipPath := ""
+				key, ok := uploadKey(url)
+				if !ok {
+					status = "外部链接"
+				} else {
+					name := uniqueZipName(used, dir, sanitizeZipName(respondent), path.Ext(key))
+					err := addFileToZip(zw, key, name)
+					switch {
+					case errors.Is(err, fs.ErrNotExist):
+						status = "文件缺失"
+					case err != nil:
+						return err
+					default:
+						zipPath = name
+					}
+				}
+				manifest = append(manifest, []string{sheet.AnswerID.Hex(), sheet.StudentID, sheet.Time,
+					strconv.Itoa(question.SerialNum), question.Subject, url, zipPath, status, scan})
+			}
+		}
+	}
+
+	// 写入清单
+	mw, err := zw.Create("manifest.csv")
+	if err != nil {
+		return err
+	}
+	// 写入 BOM 以便 Excel 正确识别 UTF-8
+	if _, err := mw.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(mw)
+	if err := cw.WriteAll(manifest); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func addFileToZip(zw *zip.Writer, key string, name string) error {
+	file, err := storage.Store.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func(file io.ReadCloser) {
+		err := file.Close()
+		if err != nil {
+			zap.L().Error("Failed to close file", zap.Error(err))
+		}
+	}(file)
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	}
+	fw, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, file)
+	return err
+}
@@ -0,0 +1,20 @@
+package service
+
+import "testing"
+
+func TestUniqueZipName(t *testing.T) {
+	used := make(map[string]bool)
+	got := []string{
+		uniqueZipName(used, "1_附件", "abc", ".pdf"),
+		uniqueZipName(used, "1_附件", "abc", ".pdf"),
+		uniqueZipName(used, "1_附件", "abc_2", ".pdf"),
+		uniqueZipName(used, "1_附件", "abc", ".pdf"),
+		uniqueZipName(used, "2_照片", "abc", ".pdf"),
+	}
+	want := []string{"1_附件/abc.pdf", "1_附件/abc_2.pdf", "1_附件/abc_2_2.pdf", "1_附件/abc_3.pdf", "2_照片/abc.pdf"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("生成的文件名为 %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // 注册解码器
+	"image/jpeg"
+	"image/png"
+	"io"
+	"path"
+	"strings"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/pkg/storage"
+	"QA-System/internal/pkg/webp"
+	"go.uber.org/zap"
+	_ "golang.org/x/image/bmp" // 注册解码器
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+)
+
+// ErrImageTooLarge 图片像素数超出限制, 避免解码时占用过多内存
+var ErrImageTooLarge = errors.New("图片尺寸超出限制")
+
+// imageOptions 图片处理配置
+type imageOptions struct {
+	MaxSize   int    // 长边最大像素, 超出时等比缩小
+	ThumbSize int    // 缩略图长边像素
+	MaxPixels int    // 允许解码的最大像素数
+	Quality   int    // JPEG 质量, WebP 为无损编码, 不受影响
+	Format    string // 输出格式
+}
+
+func getImageOptions() imageOptions {
+	opts := imageOptions{MaxSize: 2048, ThumbSize: 320, MaxPixels: 40_000_000, Quality: 85, Format: "jpeg"}
+	if global.Config.IsSet("image.max-size") {
+		opts.MaxSize = global.Config.GetInt("image.max-size")
+	}
+	if global.Config.IsSet("image.thumb-size") {
+		opts.ThumbSize = global.Config.GetInt("image.thumb-size")
+	}
+	if global.Config.IsSet("image.max-pixels") {
+		opts.MaxPixels = global.Config.GetInt("image.max-pixels")
+	}
+	if global.Config.IsSet("image.quality") {
+		opts.Quality = global.Config.GetInt("image.quality")
+	}
+	if global.Config.IsSet("image.format") {
+		opts.Format = strings.ToLower(global.Config.GetString("image.format"))
+	}
+	return opts
+}
+
+// imageEncoder 图片编码器
+type imageEncoder struct {
+	Ext         string
+	ContentType string
+	Opaque      bool // 不支持透明度, 编码前需铺白色背景
+	Encode      func(w io.Writer, img image.Image, quality int) error
+}
+
+// imageEncoders 可用的输出格式
+var imageEncoders = map[string]imageEncoder{
+	"jpeg": {Ext: ".jpg", ContentType: "image/jpeg", Opaque: true,
+		Encode: func(w io.Writer, img image.Image, quality int) error {
+			return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+		}},
+	"png": {Ext: ".png", ContentType: "image/png",
+		Encode: func(w io.Writer, img image.Image, _ int) error {
+			return png.Encode(w, img)
+		}},
+	"webp": {Ext: ".webp", ContentType: "image/webp",
+		Encode: func(w io.Writer, img image.Image, _ int) error {
+			return webp.Encode(w, img)
+		}},
+}
+
+// CheckImageConfig 启动时检查图片处理配置, 输出格式不可用时返回错误
+func CheckImageConfig() error {
+	format := getImageOptions().Format
+	if _, ok := imageEncoders[format]; !ok {
+		return fmt.Errorf("不支持的图片输出格式 %s, 可选 jpeg | png | webp", format)
+	}
+	return nil
+}
+
+// getImageEncoder 获取配置的输出格式, 启动时已检查, 不可用时按 JPEG 处理
+func getImageEncoder(format string) imageEncoder {
+	if enc, ok := imageEncoders[format]; ok {
+		return enc
+	}
+	return imageEncoders["jpeg"]
+}
+
+// ProcessedImage 处理后的图片与缩略图
+type ProcessedImage struct {
+	Image       []byte
+	Thumb       []byte
+	Ext         string
+	ContentType string
+}
+
+// ProcessImage 处理上传的图片: 按 EXIF 方向旋转, 限制尺寸, 生成缩略图并重新编码
+// 重新编码后不保留 EXIF 等元数据, 避免泄露拍摄位置
+func ProcessImage(reader io.Reader) (*ProcessedImage, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	opts := getImageOptions()
+	img, err := decodeImage(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	enc := getImageEncoder(opts.Format)
+	orientation := exifOrientation(data)
+
+	full := orient(resizeImage(img, opts.MaxSize, enc.Opaque), orientation)
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, full, opts.Quality); err != nil {
+		return nil, err
+	}
+	result := &ProcessedImage{Image: buf.Bytes(), Ext: enc.Ext, ContentType: enc.ContentType}
+
+	var thumb bytes.Buffer
+	if err := enc.Encode(&thumb, resizeImage(full, opts.ThumbSize, enc.Opaque), opts.Quality); err != nil {
+		return nil, err
+	}
+	result.Thumb = thumb.Bytes()
+	return result, nil
+}
+
+// decodeImage 先读取尺寸再解码, 拒绝像素数超出限制的图片
+func decodeImage(data []byte, opts imageOptions) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if opts.MaxPixels > 0 && cfg.Width*cfg.Height > opts.MaxPixels {
+		return nil, ErrImageTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// SavedUpload 保存后的上传文件
+type SavedUpload struct {
+	URL         string
+	Size        int64
+	ContentType string
+	ScanStatus  string // 安全扫描状态, 图片会重新编码, 不进行扫描
+	ScanResult  string
+}
+
+// SaveImage 处理并保存图片与缩略图
+func SaveImage(reader io.Reader, name string) (*SavedUpload, error) {
+	processed, err := ProcessImage(reader)
+	if err != nil {
+		return nil, err
+	}
+	name += processed.Ext
+	err = storage.Store.Put(ctx, thumbKey(StaticDir+"/"+name), bytes.NewReader(processed.Thumb),
+		int64(len(processed.Thumb)), processed.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	size := int64(len(processed.Image))
+	url, err := SaveUpload(StaticDir, name, bytes.NewReader(processed.Image), size, processed.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedUpload{URL: url, Size: size, ContentType: processed.ContentType}, nil
+}
+
+// thumbSuffix 缩略图文件名后缀
+const thumbSuffix = "_thumb"
+
+// thumbKey 获取图片缩略图的 key
+func thumbKey(key string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + thumbSuffix + ext
+}
+
+func isThumbKey(key string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(key, path.Ext(key)), thumbSuffix)
+}
+
+// ThumbURL 获取图片缩略图地址, 不是上传到本系统的图片时返回原地址
+func ThumbURL(url string) string {
+	key, ok := uploadKey(url)
+	if !ok || !strings.HasPrefix(key, StaticDir+"/") || isThumbKey(key) {
+		return url
+	}
+	return GetConfigUrl() + "/public/" + thumbKey(key)
+}
+
+// GenerateMissingThumbs 为缺少缩略图的历史图片生成缩略图, 返回生成的数量
+func GenerateMissingThumbs() (int, error) {
+	objects, err := storage.Store.List(ctx, StaticDir+"/")
+	if err != nil {
+		return 0, err
+	}
+	exists := make(map[string]bool, len(objects))
+	for _, object := range objects {
+		exists[object.Key] = true
+	}
+	opts := getImageOptions()
+	count := 0
+	for _, object := range objects {
+		if isThumbKey(object.Key) || exists[thumbKey(object.Key)] {
+			continue
+		}
+		if err := generateThumb(object.Key, opts); err != nil {
+			zap.L().Warn("Failed to generate thumbnail", zap.String("key", object.Key), zap.Error(err))
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// generateThumb 按原图格式生成缩略图
+func generateThumb(key string, opts imageOptions) error {
+	file, err := storage.Store.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(file)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+	img, err := decodeImage(data, opts)
+	if err != nil {
+		return err
+	}
+	enc := imageEncoders["jpeg"]
+	for _, e := range imageEncoders {
+		if e.Ext == path.Ext(key) {
+			enc = e
+		}
+	}
+	var buf bytes.Buffer
+	thumb := orient(resizeImage(img, opts.ThumbSize, enc.Opaque), exifOrientation(data))
+	if err := enc.Encode(&buf, thumb, opts.Quality); err != nil {
+		return err
+	}
+	return storage.Store.Put(ctx, thumbKey(key), &buf, int64(buf.Len()), enc.ContentType)
+}
+
+// fitSize 等比缩放到长边不超过 limit
+func fitSize(w, h, limit int) (int, int) {
+	if limit <= 0 || (w <= limit && h <= limit) {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, h*limit/w)
+	}
+	return max(1, w*limit/h), limit
+}
+
+// resizeImage 缩放图片并转换为 RGBA, opaque 为 true 时铺白色背景
+func resizeImage(src image.Image, limit int, opaque bool) *image.RGBA {
+	b := src.Bounds()
+	w, h := fitSize(b.Dx(), b.Dy(), limit)
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opaque {
+		draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	}
+	if w == b.Dx() && h == b.Dy() {
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	} else {
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	}
+	return dst
+}
+
+// orient 按 EXIF 方向旋转或翻转图片
+func orient(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2: // 水平翻转
+				sx, sy = w-1-x, y
+			case 3: // 旋转 180°
+				sx, sy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				sx, sy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				sx, sy = y, x
+			case 6: // 顺时针旋转 90°
+				sx, sy = y, h-1-x
+			case 7: // 沿右上-左下对角线翻转
+				sx, sy = w-1-y, h-1-x
+			case 8: // 逆时针旋转 90°
+				sx, sy = w-1-y, x
+			}
+			si := sy*src.Stride + sx*4
+			di := y*dst.Stride + x*4
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// exifOrientation 读取 JPEG 的 EXIF 方向, 不存在或无法解析时返回 1
+func exifOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		switch {
+		case marker == 0xFF: // 填充字节
+			i++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7): // 无长度的标记
+			i += 2
+			continue
+		case marker == 0xDA || marker == 0xD9: // 图像数据开始, EXIF 只会出现在之前
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + size
+	}
+	return 1
+}
+
+// tiffOrientation 从 EXIF 的 TIFF 结构中读取 IFD0 的 Orientation 标签
+func tiffOrientation(b []byte) int {
+	if len(b) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(b[4:]))
+	if offset < 8 || offset+2 > len(b) {
+		return 1
+	}
+	n := int(order.Uint16(b[offset:]))
+	for j := 0; j < n; j++ {
+		entry := offset + 2 + j*12
+		if entry+12 > len(b) {
+			return 1
+		}
+		if order.Uint16(b[entry:]) != 0x0112 {
+			continue
+		}
+		// Orientation 为 SHORT 类型, 值位于条目的最后 4 字节中
+		if order.Uint16(b[entry+2:]) != 3 {
+			return 1
+		}
+		if v := int(order.Uint16(b[entry+8:])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 1
+	}
+	return 1
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	global "QA-System/internal/global/config"
+	"golang.org/x/image/webp"
+)
+
+func TestProcessImageWebP(t *testing.T) {
+	global.Config.Set("image.format", "webp")
+	t.Cleanup(func() { global.Config.Set("image.format", "jpeg") })
+	if err := CheckImageConfig(); err != nil {
+		t.Fatal(err)
+	}
+	src := image.NewNRGBA(image.Rect(0, 0, 640, 480))
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 640; x++ {
+			src.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: uint8(255 - y/2)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	processed, err := ProcessImage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processed.Ext != ".webp" || processed.ContentType != "image/webp" {
+		t.Fatalf("输出格式为 %s %s", processed.Ext, processed.ContentType)
+	}
+	for _, data := range [][]byte{processed.Image, processed.Thumb} {
+		img, err := webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, a := img.At(0, img.Bounds().Dy()-1).RGBA(); a == 0xffff {
+			t.Error("WebP 输出应保留透明度")
+		}
+	}
+	thumb, _ := webp.DecodeConfig(bytes.NewReader(processed.Thumb))
+	if thumb.Width != 320 || thumb.Height != 240 {
+		t.Fatalf("缩略图尺寸为 %dx%d", thumb.Width, thumb.Height)
+	}
+}
+
+func TestCheckImageConfig(t *testing.T) {
+	global.Config.Set("image.format", "gif")
+	t.Cleanup(func() { global.Config.Set("image.format", "jpeg") })
+	if err := CheckImageConfig(); err == nil {
+		t.Fatal("不支持的输出格式应返回错误")
+	}
+}
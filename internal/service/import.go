@@ -0,0 +1,455 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ImportError 导入时的错误定位信息
+type ImportError struct {
+	Location string `json:"location"` // 出错位置, 如 "第3行第5列" 或 "C5"
+	Message  string `json:"message"`  // 错误信息
+}
+
+// 题目表的列名
+const (
+	columnSubject     = "题目"
+	columnDescription = "描述"
+	columnType        = "题型"
+	columnOptions     = "选项"
+	columnRequired    = "必填"
+	columnUnique      = "唯一"
+	columnOtherOption = "其他选项"
+	columnReg         = "正则"
+	columnMinimum     = "最少选项数"
+	columnMaximum     = "最多选项数"
+)
+
+// questionTypeNames 题型名称与题型编号的对应关系
+var questionTypeNames = map[string]int{
+	"单选": 1,
+	"多选": 2,
+	"填空": 3,
+	"简答": 4,
+	"图片": 5,
+	"文件": 6,
+}
+
+// ParseQuestionSheet 从 Excel 题目表中解析问题列表, 表格第一行为表头, 之后每行一道题
+func ParseQuestionSheet(r io.Reader) ([]dao.QuestionList, []ImportError, error) {
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func(f *excelize.File) {
+		err := f.Close()
+		if err != nil {
+			zap.L().Error("Failed to close excel file", zap.Error(err))
+		}
+	}(f)
+
+	sheet := f.GetSheetName(0)
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, []ImportError{{Location: sheet, Message: "工作表为空"}}, nil
+	}
+
+	// 根据表头定位各列
+	columns := make(map[string]int)
+	for i, title := range rows[0] {
+		columns[strings.TrimSpace(title)] = i
+	}
+	importErrors := make([]ImportError, 0)
+	for _, required := range []string{columnSubject, columnType} {
+		if _, ok := columns[required]; !ok {
+			importErrors = append(importErrors, ImportError{Location: "第1行", Message: "缺少\"" + required + "\"列"})
+		}
+	}
+	if len(importErrors) > 0 {
+		return nil, importErrors, nil
+	}
+
+	questions := make([]dao.QuestionList, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		cell := func(column string) (string, string) {
+			index, ok := columns[column]
+			if !ok {
+				return "", ""
+			}
+			axis, err := excelize.CoordinatesToCellName(index+1, rowNum)
+			if err != nil {
+				axis = fmt.Sprintf("第%d行", rowNum)
+			}
+			if index >= len(row) {
+				return "", axis
+			}
+			return strings.TrimSpace(row[index]), axis
+		}
+		addError := func(axis string, msg string) {
+			importErrors = append(importErrors, ImportError{Location: axis, Message: msg})
+		}
+
+		// 跳过空行
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+
+		var question dao.QuestionList
+		question.SerialNum = len(questions) + 1
+		question.Subject, _ = cell(columnSubject)
+		question.Description, _ = cell(columnDescription)
+		question.QuestionSetting.Reg, _ = cell(columnReg)
+
+		value, axis := cell(columnType)
+		questionType, err := parseQuestionType(value)
+		if err != nil {
+			addError(axis, err.Error())
+		}
+		question.QuestionSetting.QuestionType = questionType
+
+		for _, item := range []struct {
+			column string
+			target *bool
+		}{
+			{columnRequired, &question.QuestionSetting.Required},
+			{columnUnique, &question.QuestionSetting.Unique},
+			{columnOtherOption, &question.QuestionSetting.OtherOption},
+		} {
+			value, axis := cell(item.column)
+			b, err := parseBool(value)
+			if err != nil {
+				addError(axis, err.Error())
+			}
+			*item.target = b
+		}
+		for _, item := range []struct {
+			column string
+			target *uint
+		}{
+			{columnMinimum, &question.QuestionSetting.MinimumOption},
+			{columnMaximum, &question.QuestionSetting.MaximumOption},
+		} {
+			value, axis := cell(item.column)
+			if value == "" {
+				continue
+			}
+			n, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				addError(axis, "\""+value+"\"不是有效的数字")
+			}
+			*item.target = uint(n)
+		}
+
+		value, axis = cell(columnOptions)
+		for _, content := range splitOptions(value) {
+			question.Options = append(question.Options, dao.Option{
+				SerialNum: len(question.Options) + 1,
+				Content:   content,
+			})
+		}
+		if (questionType == 1 || questionType == 2) && len(question.Options) == 0 {
+			addError(axis, "选择题至少需要一个选项")
+		}
+		questions = append(questions, question)
+	}
+	return questions, importErrors, nil
+}
+
+func parseQuestionType(value string) (int, error) {
+	if t, ok := questionTypeNames[value]; ok {
+		return t, nil
+	}
+	t, err := strconv.Atoi(value)
+	if err != nil || t < 1 || t > 6 {
+		return 0, errors.New("题型\"" + value + "\"无效, 应为单选、多选、填空、简答、图片、文件或 1-6")
+	}
+	return t, nil
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "是", "true", "1", "y", "yes":
+		return true, nil
+	case "否", "false", "0", "n", "no", "":
+		return false, nil
+	default:
+		return false, errors.New("\"" + value + "\"应为是或否")
+	}
+}
+
+// splitOptions 按换行或竖线拆分选项
+func splitOptions(value string) []string {
+	options := make([]string, 0)
+	for _, line := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == '\n' || r == '|' || r == '┋'
+	}) {
+		if line = strings.TrimSpace(line); line != "" {
+			options = append(options, line)
+		}
+	}
+	return options
+}
+
+// 答卷表中的固定列
+const (
+	columnSerial    = "序号"
+	columnTime      = "提交时间"
+	columnStudentID = "学号"
+)
+
+// readTable 读取 CSV 或 Excel 文件中的全部行
+func readTable(r io.Reader, ext string) ([][]string, error) {
+	if ext == ".csv" {
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		// 去除 Excel 导出 CSV 时携带的 BOM
+		if len(rows) > 0 && len(rows[0]) > 0 {
+			rows[0][0] = strings.TrimPrefix(rows[0][0], "\xEF\xBB\xBF")
+		}
+		return rows, nil
+	}
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *excelize.File) {
+		err := f.Close()
+		if err != nil {
+			zap.L().Error("Failed to close excel file", zap.Error(err))
+		}
+	}(f)
+	return f.GetRows(f.GetSheetName(0))
+}
+
+// ImportAnswerSheets 从 CSV 或 Excel 文件导入历史答卷, 表头与导出文件一致, 即问题标题
+// 任意一行校验失败时不导入任何答卷, 返回成功导入的答卷数量
+// 导入中途失败后重新导入同一文件, 只会写入尚未导入的答卷
+func ImportAnswerSheets(survey *model.Survey, r io.Reader, ext string) (int, []ImportError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	fileSum := sha256.Sum256(data)
+	rows, err := readTable(bytes.NewReader(data), ext)
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(rows) < 2 {
+		return 0, []ImportError{{Location: "文件", Message: "没有可导入的答卷"}}, nil
+	}
+	questions, err := d.GetQuestionsBySurveyID(ctx, survey.ID)
+	if err != nil {
+		return 0, nil, err
+	}
+	questionMap := make(map[string]model.Question)
+	optionsMap := make(map[int]map[string]bool)
+	for _, question := range questions {
+		questionMap[question.Subject] = question
+		options, err := d.GetOptionsByQuestionID(ctx, question.ID)
+		if err != nil {
+			return 0, nil, err
+		}
+		optionsMap[question.ID] = make(map[string]bool)
+		for _, option := range options {
+			optionsMap[question.ID][option.Content] = true
+		}
+	}
+
+	// 解析表头
+	importErrors := make([]ImportError, 0)
+	columnQuestions := make(map[int]model.Question)
+	timeColumn, studentColumn := -1, -1
+	seen := make(map[int]bool)
+	for i, title := range rows[0] {
+		title = strings.TrimSpace(title)
+		axis := cellName(i, 1)
+		switch title {
+		case columnSerial, "":
+			continue
+		case columnTime:
+			timeColumn = i
+			continue
+		case columnStudentID:
+			studentColumn = i
+			continue
+		}
+		question, ok := questionMap[title]
+		if !ok {
+			importErrors = append(importErrors, ImportError{Location: axis, Message: "问卷中不存在问题\"" + title + "\""})
+			continue
+		}
+		columnQuestions[i] = question
+		seen[question.ID] = true
+	}
+	for _, question := range questions {
+		if question.Required && !seen[question.ID] {
+			importErrors = append(importErrors, ImportError{Location: "第1行",
+				Message: "缺少必填问题\"" + question.Subject + "\"列"})
+		}
+	}
+	if len(importErrors) > 0 {
+		return 0, importErrors, nil
+	}
+
+	// 逐行校验
+	sheets := make([]dao.AnswerSheet, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		cellValue := func(column int) string {
+			if column < 0 || column >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[column])
+		}
+		sheet := dao.AnswerSheet{
+			SurveyID:  survey.ID,
+			AnswerID:  importedAnswerID(survey.ID, fileSum, rowNum),
+			Time:      time.Now().Format("2006-01-02 15:04:05"),
+			Unique:    true,
+			StudentID: cellValue(studentColumn),
+			Imported:  true,
+		}
+		if value := cellValue(timeColumn); value != "" {
+			if _, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local); err != nil {
+				importErrors = append(importErrors, ImportError{Location: cellName(timeColumn, rowNum),
+					Message: "提交时间格式应为 2006-01-02 15:04:05"})
+			}
+			sheet.Time = value
+		}
+		for column, question := range columnQuestions {
+			value := cellValue(column)
+			if msg := checkImportedAnswer(survey, question, optionsMap[question.ID], value); msg != "" {
+				importErrors = append(importErrors, ImportError{Location: cellName(column, rowNum), Message: msg})
+				continue
+			}
+			sheet.Answers = append(sheet.Answers, dao.Answer{
+				QuestionID: question.ID,
+				SerialNum:  question.SerialNum,
+				Subject:    question.Subject,
+				Content:    value,
+			})
+		}
+		sort.Slice(sheet.Answers, func(i, j int) bool {
+			return sheet.Answers[i].SerialNum < sheet.Answers[j].SerialNum
+		})
+		sheets = append(sheets, sheet)
+	}
+	if len(importErrors) > 0 {
+		sort.SliceStable(importErrors, func(i, j int) bool {
+			return compareCellName(importErrors[i].Location, importErrors[j].Location)
+		})
+		return 0, importErrors, nil
+	}
+
+	// 写入答卷
+	qids := make([]int, 0)
+	for _, question := range questions {
+		if question.QuestionType == 3 && question.Unique {
+			qids = append(qids, question.ID)
+		}
+	}
+	inserted, err := d.InsertAnswerSheets(ctx, sheets, qids)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := d.IncreaseSurveyNumBy(ctx, survey.ID, inserted); err != nil {
+		return 0, nil, err
+	}
+	return inserted, nil, nil
+}
+
+// importedAnswerID 由问卷、文件内容与行号生成导入答卷的ID, 同一文件重复导入时ID相同
+func importedAnswerID(surveyID int, fileSum [sha256.Size]byte, rowNum int) primitive.ObjectID {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(surveyID)))
+	h.Write(fileSum[:])
+	h.Write([]byte(strconv.Itoa(rowNum)))
+	var id primitive.ObjectID
+	copy(id[:], h.Sum(nil))
+	return id
+}
+
+// checkImportedAnswer 按照题型校验导入的答案, 返回错误信息
+func checkImportedAnswer(survey *model.Survey, question model.Question, options map[string]bool, value string) string {
+	if value == "" {
+		if question.Required {
+			return "必填问题\"" + question.Subject + "\"未填写"
+		}
+		return ""
+	}
+	multiple := (question.QuestionType == 2 && survey.Type == 0) || (question.QuestionType == 1 && survey.Type == 1)
+	switch {
+	case question.QuestionType == 1 || question.QuestionType == 2:
+		answers := strings.Split(value, "┋")
+		if !multiple && len(answers) > 1 {
+			return "单选题只能选择一个选项"
+		}
+		if multiple {
+			length := uint(len(answers))
+			if question.MinimumOption != 0 && length < question.MinimumOption {
+				return fmt.Sprintf("至少需要选择%d项", question.MinimumOption)
+			}
+			if question.MaximumOption != 0 && length > question.MaximumOption {
+				return fmt.Sprintf("最多只能选择%d项", question.MaximumOption)
+			}
+		}
+		for _, answer := range answers {
+			if !options[answer] && !question.OtherOption {
+				return "选项\"" + answer + "\"不存在"
+			}
+		}
+	case question.Reg != "":
+		reg, err := regexp.Compile(question.Reg)
+		if err == nil && !reg.MatchString(value) {
+			return "\"" + value + "\"不符合格式要求"
+		}
+	}
+	return ""
+}
+
+func cellName(column int, row int) string {
+	name, err := excelize.CoordinatesToCellName(column+1, row)
+	if err != nil {
+		return fmt.Sprintf("第%d行", row)
+	}
+	return name
+}
+
+// compareCellName 按行、列顺序比较单元格位置
+func compareCellName(a, b string) bool {
+	ac, ar, aErr := excelize.CellNameToCoordinates(a)
+	bc, br, bErr := excelize.CellNameToCoordinates(b)
+	if aErr != nil || bErr != nil {
+		return a < b
+	}
+	if ar != br {
+		return ar < br
+	}
+	return ac < bc
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestImportedAnswerID(t *testing.T) {
+	sum := sha256.Sum256([]byte("问题1\n答案1\n"))
+	id := importedAnswerID(1, sum, 2)
+	if importedAnswerID(1, sum, 2) != id {
+		t.Fatal("重复导入同一文件时答卷ID应相同")
+	}
+	other := sha256.Sum256([]byte("问题1\n答案2\n"))
+	for _, got := range []any{importedAnswerID(1, sum, 3), importedAnswerID(2, sum, 2), importedAnswerID(1, other, 2)} {
+		if got == id {
+			t.Fatal("不同问卷、文件或行的答卷ID不应相同")
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// CreateInvitation 创建邀请码, 返回的明文邀请码只在此时可见
+func CreateInvitation(creator int, username string, adminType int, ttl time.Duration) (string,
+	*model.Invitation, error) {
+	code, err := utils.RandomPassword(16)
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	invitation := &model.Invitation{
+		CodeHash:  hashSecret(code),
+		Username:  username,
+		AdminType: adminType,
+		CreatedBy: creator,
+		CreatedAt: now,
+		ExpireAt:  now.Add(ttl),
+	}
+	if err := d.CreateInvitation(ctx, invitation); err != nil {
+		return "", nil, err
+	}
+	return code, invitation, nil
+}
+
+// GetInvitationList 分页获取邀请码
+func GetInvitationList(pageNum, pageSize int) ([]model.Invitation, *int64, error) {
+	return d.GetInvitationList(ctx, pageNum, pageSize)
+}
+
+// RevokeInvitation 作废邀请码
+func RevokeInvitation(id int) error {
+	return d.RevokeInvitation(ctx, id)
+}
+
+// RegisterByInvitation 使用邀请码注册管理员
+func RegisterByInvitation(code string, username string, password string, ip string) error {
+	invitation, err := d.GetInvitationByCodeHash(ctx, hashSecret(code))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return dao.ErrInvitationUnavailable
+	} else if err != nil {
+		return err
+	}
+	if invitation.UsedBy != 0 || invitation.Revoked || time.Now().After(invitation.ExpireAt) {
+		return dao.ErrInvitationUnavailable
+	}
+	if invitation.Username != "" && invitation.Username != username {
+		return dao.ErrInvitationUnavailable
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	user := &model.User{
+		Username:  username,
+		Password:  hash,
+		AdminType: invitation.AdminType,
+	}
+	if err := d.CreateUserByInvitation(ctx, invitation.ID, user, ip); err != nil {
+		return err
+	}
+	zap.L().Info("Admin registered by invitation", zap.Int("invitation", invitation.ID),
+		zap.Int("created_by", invitation.CreatedBy), zap.String("username", username), zap.String("ip", ip))
+	return nil
+}
+
+// CheckRegisterKey 校验旧版共享注册密钥, 仅在配置开启时可用
+func CheckRegisterKey(key string) bool {
+	if !global.Config.GetBool("register.key") || key == "" {
+		return false
+	}
+	adminKey := GetConfigKey()
+	return adminKey != "" && subtle.ConstantTimeCompare([]byte(adminKey), []byte(key)) == 1
+}
+
+// GetInvitationTTL 获取邀请码默认有效期
+func GetInvitationTTL() time.Duration {
+	ttl := 72
+	if global.Config.IsSet("register.invitation-ttl") {
+		ttl = global.Config.GetInt("register.invitation-ttl")
+	}
+	return time.Duration(ttl) * time.Hour
+}
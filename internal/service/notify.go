@@ -0,0 +1,454 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	mailer "QA-System/internal/pkg/mail"
+	"QA-System/internal/pkg/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 邮件模板名称, 对应 templates 目录下的同名 .tmpl 文件
+const (
+	MailSubmission = "submission" // 新答卷通知
+	MailCopy       = "copy"       // 答卷副本
+	MailDigest     = "digest"     // 每日汇总
+)
+
+// ErrMailDisabled 未配置 SMTP 服务
+var ErrMailDisabled = errors.New("未配置邮件服务")
+
+//go:embed templates/*.tmpl
+var mailTemplates embed.FS
+
+var mailFuncs = template.FuncMap{
+	// answer 将多选与多文件答案的分隔符替换为换行
+	"answer": func(content string) string {
+		return strings.ReplaceAll(content, "┋", "\n")
+	},
+}
+
+// SheetMailData 答卷通知与答卷副本的模板数据
+type SheetMailData struct {
+	Survey    *model.Survey
+	AnswerID  string
+	Time      string
+	StudentID string
+	Answers   []SheetAnswer
+}
+
+// DigestMailData 每日汇总的模板数据
+type DigestMailData struct {
+	Survey    *model.Survey
+	Since     time.Time // 统计开始时间
+	Until     time.Time // 统计结束时间
+	NewCount  int       // 期间新增答卷数
+	Total     int       // 累计答卷数
+	Questions []DigestQuestion
+}
+
+// DigestQuestion 选择题的选项统计
+type DigestQuestion struct {
+	SerialNum int
+	Subject   string
+	Options   []DigestOption
+}
+
+// DigestOption 选项及其被选次数
+type DigestOption struct {
+	Content string
+	Count   int
+}
+
+// MailEnabled 是否已配置邮件服务
+func MailEnabled() bool {
+	return mailer.Sender != nil
+}
+
+// loadMailTemplate 加载邮件模板, notify.template-dir 中存在同名文件时优先使用
+func loadMailTemplate(name string) (*template.Template, error) {
+	file := name + ".tmpl"
+	if dir := global.Config.GetString("notify.template-dir"); dir != "" {
+		path := filepath.Join(dir, file)
+		if _, err := os.Stat(path); err == nil {
+			return template.New(file).Funcs(mailFuncs).ParseFiles(path)
+		}
+	}
+	return template.New(file).Funcs(mailFuncs).ParseFS(mailTemplates, "templates/"+file)
+}
+
+// renderMail 渲染模板中的 subject 与 body
+func renderMail(name string, data any) (*mailer.Message, error) {
+	tmpl, err := loadMailTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return nil, err
+	}
+	return &mailer.Message{Subject: subject.String(), Body: body.String()}, nil
+}
+
+// sendMail 发送邮件
+func sendMail(to string, msg *mailer.Message) error {
+	if mailer.Sender == nil {
+		return ErrMailDisabled
+	}
+	msg.To = []string{to}
+	return mailer.Sender.Send(ctx, msg)
+}
+
+// SendTestMail 发送测试邮件, 用于检查 SMTP 配置
+func SendTestMail(to string) error {
+	return sendMail(to, &mailer.Message{
+		Subject: "QA-System 测试邮件",
+		Body:    "收到这封邮件说明 SMTP 配置正确。\n",
+	})
+}
+
+// CheckEmail 检查邮箱地址格式, 只接受不带名称的地址
+func CheckEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// GetNotification 获取用户对问卷的邮件通知订阅, 未订阅时返回 gorm.ErrRecordNotFound
+func GetNotification(sid int, uid int) (*model.Notification, error) {
+	return d.GetNotification(ctx, sid, uid)
+}
+
+// SaveNotification 订阅或修改问卷的邮件通知, mode 为0时取消订阅
+func SaveNotification(sid int, uid int, email string, mode int) (*model.Notification, error) {
+	if mode == 0 {
+		return nil, d.DeleteNotification(ctx, sid, uid)
+	}
+	notification, err := d.GetNotification(ctx, sid, uid)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		notification = &model.Notification{SurveyID: sid, UserID: uid}
+	} else if err != nil {
+		return nil, err
+	}
+	// 开启每日汇总时从当前开始统计, 避免立即发送一封包含全部历史答卷的汇总
+	if mode == model.NotifyDigest && (notification.Mode != model.NotifyDigest || notification.LastDigestAt == nil) {
+		now := time.Now()
+		notification.LastDigestAt = &now
+	}
+	notification.Email = email
+	notification.Mode = mode
+	return notification, d.SaveNotification(ctx, notification)
+}
+
+// GetSheetNotifications 获取需要为每份答卷发送邮件的订阅
+func GetSheetNotifications(sid int) ([]model.Notification, error) {
+	return d.GetNotificationsByMode(ctx, sid, model.NotifyEach)
+}
+
+// notificationSurvey 获取订阅对应的问卷, 订阅者已无查看权限或账号停用时返回 nil
+func notificationSurvey(notification *model.Notification) (*model.Survey, error) {
+	survey, err := d.GetSurveyByID(ctx, notification.SurveyID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	user, err := d.GetUserByID(ctx, notification.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return nil, nil
+	}
+	ok, err := HasSurveyPermission(user, survey, ActionView)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return survey, nil
+}
+
+// sheetMailData 生成答卷的模板数据
+func sheetMailData(survey *model.Survey, sheet *dao.AnswerSheet) (*SheetMailData, error) {
+	answers, err := sheetAnswers(sheet)
+	if err != nil {
+		return nil, err
+	}
+	return &SheetMailData{
+		Survey:    survey,
+		AnswerID:  sheet.AnswerID.Hex(),
+		Time:      sheet.Time,
+		StudentID: sheet.StudentID,
+		Answers:   answers,
+	}, nil
+}
+
+// getAnswerSheet 根据答卷ID获取答卷
+func getAnswerSheet(answerID string) (*dao.AnswerSheet, error) {
+	objectID, err := primitive.ObjectIDFromHex(answerID)
+	if err != nil {
+		return nil, err
+	}
+	sheet, err := d.GetAnswerSheetByAnswerID(ctx, objectID)
+	return &sheet, err
+}
+
+// SendSheetNotification 向订阅者发送新答卷通知
+func SendSheetNotification(answerID string, notificationID int) error {
+	notification, err := d.GetNotificationByID(ctx, notificationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if notification.Mode != model.NotifyEach {
+		return nil
+	}
+	survey, err := notificationSurvey(notification)
+	if err != nil || survey == nil {
+		return err
+	}
+	sheet, err := getAnswerSheet(answerID)
+	if err != nil {
+		return err
+	}
+	data, err := sheetMailData(survey, sheet)
+	if err != nil {
+		return err
+	}
+	msg, err := renderMail(MailSubmission, data)
+	if err != nil {
+		return err
+	}
+	return sendMail(notification.Email, msg)
+}
+
+// SheetCopyAddress 获取答卷人在邮箱题中填写的地址, 问卷没有邮箱题或未填写时返回空
+func SheetCopyAddress(sheet *dao.AnswerSheet) (string, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, sheet.SurveyID)
+	if err != nil {
+		return "", err
+	}
+	copyQuestions := make(map[int]bool)
+	for _, question := range questions {
+		if question.SendCopy {
+			copyQuestions[question.ID] = true
+		}
+	}
+	for _, answer := range sheet.Answers {
+		if copyQuestions[answer.QuestionID] && CheckEmail(answer.Content) {
+			return answer.Content, nil
+		}
+	}
+	return "", nil
+}
+
+func copyLimitKey(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(email)))
+	return "notify:copy:" + hex.EncodeToString(sum[:])
+}
+
+// CheckCopyLimit 统计发往同一地址的答卷副本数量, 超出限制时返回 false
+// 答卷人可以填写任意地址, 限制发送次数以免被用来向他人滥发邮件
+func CheckCopyLimit(email string) (bool, error) {
+	limit, window := int64(3), 24*time.Hour
+	if global.Config.IsSet("notify.copy-limit") {
+		limit = global.Config.GetInt64("notify.copy-limit")
+	}
+	if global.Config.IsSet("notify.copy-window") {
+		window = time.Duration(global.Config.GetInt("notify.copy-window")) * time.Second
+	}
+	if limit <= 0 {
+		return true, nil
+	}
+	key := copyLimitKey(email)
+	count, err := redis.RedisClient.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	if count == 1 {
+		if err := redis.RedisClient.Expire(ctx, key, window).Err(); err != nil {
+			return false, err
+		}
+	}
+	return count <= limit, nil
+}
+
+// SendSheetCopy 向答卷人发送答卷副本
+func SendSheetCopy(answerID string) error {
+	sheet, err := getAnswerSheet(answerID)
+	if err != nil {
+		return err
+	}
+	survey, err := d.GetSurveyByID(ctx, sheet.SurveyID)
+	if err != nil {
+		return err
+	}
+	email, err := SheetCopyAddress(sheet)
+	if err != nil || email == "" {
+		return err
+	}
+	data, err := sheetMailData(survey, sheet)
+	if err != nil {
+		return err
+	}
+	// 副本只包含答案, 不包含学号
+	data.StudentID = ""
+	msg, err := renderMail(MailCopy, data)
+	if err != nil {
+		return err
+	}
+	return sendMail(email, msg)
+}
+
+// GetDigestHour 获取每日汇总的发送时间(本地时间的小时)
+func GetDigestHour() int {
+	if global.Config.IsSet("notify.digest-hour") {
+		return global.Config.GetInt("notify.digest-hour")
+	}
+	return 8
+}
+
+// SendDigests 发送每日汇总, 由周期任务每小时调用
+// 当天到达发送时间后, 为当天尚未发送的订阅发送, 发送失败的订阅在下一次调用时重试
+func SendDigests() error {
+	if !MailEnabled() {
+		return nil
+	}
+	now := time.Now()
+	mark := time.Date(now.Year(), now.Month(), now.Day(), GetDigestHour(), 0, 0, 0, time.Local)
+	if now.Before(mark) {
+		return nil
+	}
+	notifications, err := d.GetNotificationsByMode(ctx, 0, model.NotifyDigest)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range notifications {
+		notification := &notifications[i]
+		if notification.LastDigestAt != nil && !notification.LastDigestAt.Before(mark) {
+			continue
+		}
+		if err := sendDigest(notification, now); err != nil {
+			zap.L().Error("Failed to send digest", zap.Int("notification", notification.ID), zap.Error(err))
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendDigest 发送一个订阅的每日汇总, 期间没有新答卷时不发送
+func sendDigest(notification *model.Notification, now time.Time) error {
+	survey, err := notificationSurvey(notification)
+	if err != nil {
+		return err
+	}
+	if survey == nil {
+		return d.UpdateNotificationDigestAt(ctx, notification.ID, now)
+	}
+	since := now.Add(-24 * time.Hour)
+	if notification.LastDigestAt != nil {
+		since = *notification.LastDigestAt
+	}
+	sheets, err := GetSurveyAnswersBySurveyID(survey.ID)
+	if err != nil {
+		return err
+	}
+	data := &DigestMailData{Survey: survey, Since: since, Until: now, Total: len(sheets)}
+	for _, sheet := range sheets {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", sheet.Time, time.Local)
+		if err == nil && t.After(since) {
+			data.NewCount++
+		}
+	}
+	if data.NewCount == 0 {
+		return d.UpdateNotificationDigestAt(ctx, notification.ID, now)
+	}
+	if data.Questions, err = digestQuestions(survey.ID, sheets); err != nil {
+		return err
+	}
+	msg, err := renderMail(MailDigest, data)
+	if err != nil {
+		return err
+	}
+	if err := sendMail(notification.Email, msg); err != nil {
+		return err
+	}
+	return d.UpdateNotificationDigestAt(ctx, notification.ID, now)
+}
+
+// digestQuestions 统计单选题与多选题各选项的累计被选次数, 不在选项中的答案计入"其他"
+func digestQuestions(sid int, sheets []dao.AnswerSheet) ([]DigestQuestion, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]DigestQuestion, 0)
+	optionsMap := make(map[int][]model.Option)
+	counts := make(map[int]map[string]int)
+	for _, question := range questions {
+		if question.QuestionType != 1 && question.QuestionType != 2 {
+			continue
+		}
+		options, err := d.GetOptionsByQuestionID(ctx, question.ID)
+		if err != nil {
+			return nil, err
+		}
+		optionsMap[question.ID] = options
+		counts[question.ID] = make(map[string]int, len(options))
+		for _, option := range options {
+			counts[question.ID][option.Content] = 0
+		}
+	}
+	const other = "其他"
+	for _, sheet := range sheets {
+		for _, answer := range sheet.Answers {
+			questionCounts, ok := counts[answer.QuestionID]
+			if !ok || answer.Content == "" {
+				continue
+			}
+			for _, content := range strings.Split(answer.Content, "┋") {
+				if _, ok := questionCounts[content]; ok {
+					questionCounts[content]++
+				} else {
+					questionCounts[other]++
+				}
+			}
+		}
+	}
+	for _, question := range questions {
+		questionCounts, ok := counts[question.ID]
+		if !ok {
+			continue
+		}
+		item := DigestQuestion{SerialNum: question.SerialNum, Subject: question.Subject}
+		for _, option := range optionsMap[question.ID] {
+			item.Options = append(item.Options, DigestOption{Content: option.Content,
+				Count: questionCounts[option.Content]})
+		}
+		if n := questionCounts[other]; n > 0 {
+			item.Options = append(item.Options, DigestOption{Content: other, Count: n})
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
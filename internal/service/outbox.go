@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"QA-System/internal/dao"
+	"QA-System/internal/model"
+	"go.uber.org/zap"
+)
+
+// 发件箱事件类型, 均为提交事务后才能执行的 MongoDB、Redis 与文件操作
+const (
+	OutboxRemoveUploads      = "uploads.remove"       // 删除上传文件
+	OutboxClearQuestionCache = "cache.questions"      // 清除问题与选项缓存
+	OutboxDeleteAnswerSheets = "answer_sheets.delete" // 删除问卷全部答卷
+	OutboxDeleteRecordSheets = "record_sheets.delete" // 删除问卷统一验证记录
+)
+
+// outboxMaxAttempts 发件箱事件最多执行次数, 超过后需人工处理
+const outboxMaxAttempts = 10
+
+type removeUploadsPayload struct {
+	URLs []string `json:"urls"`
+}
+
+type surveyPayload struct {
+	SurveyID int `json:"survey_id"`
+}
+
+// outbox 收集事务中产生的副作用
+type outbox struct {
+	events []model.OutboxEvent
+}
+
+// add 添加事件, 在事务提交时一并写入
+func (o *outbox) add(eventType string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	o.events = append(o.events, model.OutboxEvent{Type: eventType, Payload: string(data)})
+	return nil
+}
+
+// withOutbox 在 MySQL 事务中执行 fn, 事务提交后再执行 fn 添加的副作用
+// 副作用执行失败不影响本次操作结果, 由周期任务重试
+func withOutbox(fn func(tx *dao.Dao, o *outbox) error) error {
+	o := &outbox{}
+	err := d.Transaction(ctx, func(tx *dao.Dao) error {
+		if err := fn(tx, o); err != nil {
+			return err
+		}
+		return tx.CreateOutboxEvents(ctx, o.events)
+	})
+	if err != nil {
+		return err
+	}
+	for i := range o.events {
+		dispatchOutboxEvent(&o.events[i])
+	}
+	return nil
+}
+
+// dispatchOutboxEvent 执行事件并记录结果
+func dispatchOutboxEvent(event *model.OutboxEvent) {
+	if err := runOutboxEvent(event); err != nil {
+		zap.L().Error("Failed to run outbox event", zap.Int("id", event.ID), zap.String("type", event.Type),
+			zap.Error(err))
+		if err := d.MarkOutboxEventFailed(ctx, event.ID, err.Error()); err != nil {
+			zap.L().Error("Failed to mark outbox event", zap.Int("id", event.ID), zap.Error(err))
+		}
+		return
+	}
+	if err := d.MarkOutboxEventDone(ctx, event.ID); err != nil {
+		zap.L().Error("Failed to mark outbox event", zap.Int("id", event.ID), zap.Error(err))
+	}
+}
+
+// runOutboxEvent 执行事件, 所有事件都需要可以重复执行
+func runOutboxEvent(event *model.OutboxEvent) error {
+	switch event.Type {
+	case OutboxRemoveUploads:
+		var p removeUploadsPayload
+		if err := json.Unmarshal([]byte(event.Payload), &p); err != nil {
+			return err
+		}
+		return removeUploads(p.URLs)
+	case OutboxClearQuestionCache:
+		if err := dao.DeleteAllQuestionCache(ctx); err != nil {
+			return err
+		}
+		return dao.DeleteAllOptionCache(ctx)
+	case OutboxDeleteAnswerSheets:
+		var p surveyPayload
+		if err := json.Unmarshal([]byte(event.Payload), &p); err != nil {
+			return err
+		}
+		return d.DeleteAnswerSheetBySurveyID(ctx, p.SurveyID)
+	case OutboxDeleteRecordSheets:
+		var p surveyPayload
+		if err := json.Unmarshal([]byte(event.Payload), &p); err != nil {
+			return err
+		}
+		return d.DeleteRecordSheets(ctx, p.SurveyID)
+	default:
+		return fmt.Errorf("未知的发件箱事件类型 %s", event.Type)
+	}
+}
+
+// ProcessOutbox 重试未执行成功的发件箱事件
+// 只处理创建超过一分钟的事件, 避免与提交后的立即执行重复
+func ProcessOutbox() error {
+	events, err := d.GetPendingOutboxEvents(ctx, time.Now().Add(-time.Minute), outboxMaxAttempts, 100)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		dispatchOutboxEvent(&events[i])
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+
+	"QA-System/internal/model"
+	"gorm.io/gorm"
+)
+
+// SurveyAction 对问卷的操作类型
+type SurveyAction int
+
+// 问卷操作类型
+const (
+	ActionView    SurveyAction = iota + 1 // 查看问卷、统计与答卷
+	ActionExport                          // 导出答卷与上传文件
+	ActionEdit                            // 修改问卷内容与状态
+	ActionDelete                          // 删除问卷与答卷
+	ActionGrant                           // 管理协作者权限
+	ActionWebhook                         // 管理 Webhook
+)
+
+// actionRoles 执行各操作所需的最低角色
+var actionRoles = map[SurveyAction]int{
+	ActionView:    model.RoleViewer,
+	ActionExport:  model.RoleAnalyst,
+	ActionEdit:    model.RoleEditor,
+	ActionDelete:  model.RoleOwner,
+	ActionGrant:   model.RoleOwner,
+	ActionWebhook: model.RoleOwner,
+}
+
+// GetSurveyRole 获取用户在问卷中的角色, 无权限时返回 0
+func GetSurveyRole(user *model.User, survey *model.Survey) (int, error) {
+	// 超级管理员与问卷创建者视为所有者
+	if user.AdminType == 2 || (user.AdminType == 1 && survey.UserID == user.ID) {
+		return model.RoleOwner, nil
+	}
+	manage, err := d.GetManageByUIDAndSID(ctx, user.ID, survey.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if manage.Role == 0 {
+		return model.RoleEditor, nil
+	}
+	return manage.Role, nil
+}
+
+// HasSurveyPermission 判断用户能否对问卷执行指定操作
+func HasSurveyPermission(user *model.User, survey *model.Survey, action SurveyAction) (bool, error) {
+	required, ok := actionRoles[action]
+	if !ok {
+		return false, errors.New("未知的问卷操作")
+	}
+	role, err := GetSurveyRole(user, survey)
+	if err != nil {
+		return false, err
+	}
+	return role >= required, nil
+}
+
+// GetSurveyManagers 获取问卷的协作者列表
+func GetSurveyManagers(sid int) ([]model.Manage, error) {
+	return d.GetManageBySurveyID(ctx, sid)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// GetRecycleRetention 获取回收站保留时间
+func GetRecycleRetention() time.Duration {
+	days := 30
+	if global.Config.IsSet("recycle.retention") {
+		days = global.Config.GetInt("recycle.retention")
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// DeleteSurvey 将问卷移入回收站, 问题、答卷与上传文件在保留期过后才会清除
+func DeleteSurvey(id int) error {
+	return d.DeleteSurvey(ctx, id)
+}
+
+// RestoreSurvey 从回收站恢复问卷
+func RestoreSurvey(id int) error {
+	return d.RestoreSurvey(ctx, id)
+}
+
+// GetDeletedSurveyByID 获取回收站中的问卷
+func GetDeletedSurveyByID(id int) (*model.Survey, error) {
+	return d.GetDeletedSurveyByID(ctx, id)
+}
+
+// GetDeletedSurveys 获取回收站中的问卷, uid 为 0 时获取全部
+func GetDeletedSurveys(uid int) ([]model.Survey, error) {
+	return d.GetDeletedSurveys(ctx, uid, time.Time{})
+}
+
+// DeleteAnswerSheetByAnswerID 将答卷移入回收站
+func DeleteAnswerSheetByAnswerID(answerID primitive.ObjectID) error {
+	return d.SoftDeleteAnswerSheet(ctx, answerID)
+}
+
+// RestoreAnswerSheet 从回收站恢复答卷
+func RestoreAnswerSheet(answerID primitive.ObjectID) error {
+	return d.RestoreAnswerSheet(ctx, answerID)
+}
+
+// GetDeletedAnswerSheetByAnswerID 获取回收站中的答卷
+func GetDeletedAnswerSheetByAnswerID(answerID primitive.ObjectID) (dao.AnswerSheet, error) {
+	return d.GetDeletedAnswerSheetByAnswerID(ctx, answerID)
+}
+
+// GetDeletedAnswerSheets 获取问卷在回收站中的答卷
+func GetDeletedAnswerSheets(sid int) ([]dao.AnswerSheet, error) {
+	return d.GetDeletedAnswerSheets(ctx, sid, time.Time{})
+}
+
+// PurgeSurvey 彻底删除问卷及其问题、答卷、统一验证记录与上传文件
+func PurgeSurvey(id int) error {
+	answerSheets, err := d.GetAllAnswerSheetsBySurveyID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return withOutbox(func(tx *dao.Dao, o *outbox) error {
+		questions, err := tx.GetQuestionsBySurveyID(ctx, id)
+		if err != nil {
+			return err
+		}
+		urls, err := getOldImgs(tx, questions)
+		if err != nil {
+			return err
+		}
+		for _, answerSheet := range answerSheets {
+			urls = append(urls, answerSheetUploads(questions, answerSheet)...)
+		}
+		for _, question := range questions {
+			if err := tx.DeleteOption(ctx, question.ID); err != nil {
+				return err
+			}
+		}
+		if err := tx.DeleteQuestionBySurveyID(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.PurgeSurvey(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.DeleteManageBySurveyID(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.DeleteWebhooksBySurveyID(ctx, id); err != nil {
+			return err
+		}
+		if err := tx.DeleteNotificationsBySurveyID(ctx, id); err != nil {
+			return err
+		}
+		// MongoDB 数据与文件在提交后删除
+		if err := o.add(OutboxDeleteAnswerSheets, surveyPayload{SurveyID: id}); err != nil {
+			return err
+		}
+		if err := o.add(OutboxDeleteRecordSheets, surveyPayload{SurveyID: id}); err != nil {
+			return err
+		}
+		if err := o.add(OutboxClearQuestionCache, nil); err != nil {
+			return err
+		}
+		return o.add(OutboxRemoveUploads, removeUploadsPayload{URLs: urls})
+	})
+}
+
+// purgeAnswerSheet 彻底删除答卷及其上传文件
+func purgeAnswerSheet(answerSheet dao.AnswerSheet) error {
+	questions, err := d.GetQuestionsBySurveyID(ctx, answerSheet.SurveyID)
+	if err != nil {
+		return err
+	}
+	// 先删除文件, 失败时保留答卷以便下次清理时重试
+	if err := removeUploads(answerSheetUploads(questions, answerSheet)); err != nil {
+		return err
+	}
+	return d.DeleteAnswerSheetByAnswerID(ctx, answerSheet.AnswerID)
+}
+
+// answerSheetUploads 获取答卷中上传的图片与文件地址
+func answerSheetUploads(questions []model.Question, answerSheet dao.AnswerSheet) []string {
+	types := make(map[int]int, len(questions))
+	for _, question := range questions {
+		types[question.ID] = question.QuestionType
+	}
+	urls := make([]string, 0)
+	for _, answer := range answerSheet.Answers {
+		if t := types[answer.QuestionID]; (t == 5 || t == 6) && answer.Content != "" {
+			urls = append(urls, strings.Split(answer.Content, "┋")...)
+		}
+	}
+	return urls
+}
+
+// removeUploads 删除上传的文件及其缩略图与登记记录, 文件不存在时视为已删除, 可重复执行
+// 删除失败的文件保留登记记录并返回错误, 由调用方重试
+func removeUploads(urls []string) error {
+	keys := uploadKeys(urls)
+	removed := make([]string, 0, len(keys))
+	var errs []error
+	for _, key := range keys {
+		removeKeys := []string{key}
+		if strings.HasPrefix(key, StaticDir+"/") {
+			removeKeys = append(removeKeys, thumbKey(key))
+		}
+		ok := true
+		for _, key := range removeKeys {
+			if err := storage.Store.Delete(ctx, key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("删除文件 %s 失败: %w", key, err))
+				ok = false
+			}
+		}
+		if ok {
+			removed = append(removed, key)
+		}
+	}
+	if err := d.DeleteUploadsByKeys(ctx, removed); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// PurgeRecycleBin 清除超过保留时间的问卷与答卷
+func PurgeRecycleBin() error {
+	before := time.Now().Add(-GetRecycleRetention())
+	surveys, err := d.GetDeletedSurveys(ctx, 0, before)
+	if err != nil {
+		return err
+	}
+	for _, survey := range surveys {
+		if err := PurgeSurvey(survey.ID); err != nil {
+			return err
+		}
+		zap.L().Info("Survey purged", zap.Int("survey_id", survey.ID), zap.Time("deleted_at", survey.DeletedAt.Time))
+	}
+	answerSheets, err := d.GetDeletedAnswerSheets(ctx, 0, before)
+	if err != nil {
+		return err
+	}
+	for _, answerSheet := range answerSheets {
+		if err := purgeAnswerSheet(answerSheet); err != nil {
+			return err
+		}
+	}
+	if len(answerSheets) > 0 {
+		zap.L().Info("Answer sheets purged", zap.Int("num", len(answerSheets)))
+	}
+	return nil
+}
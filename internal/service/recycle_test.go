@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	r "QA-System/internal/pkg/redis"
+	"QA-System/internal/pkg/storage"
+	"github.com/redis/go-redis/v9"
+)
+
+// useUploadURL 使上传地址不依赖 Redis 缓存, 直接使用配置的 url.host
+func useUploadURL(t *testing.T) string {
+	old, oldHost := r.RedisClient, global.Config.GetString("url.host")
+	r.RedisClient = redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: time.Second})
+	global.Config.Set("url.host", "http://qa.test")
+	t.Cleanup(func() {
+		_ = r.RedisClient.Close()
+		r.RedisClient = old
+		global.Config.Set("url.host", oldHost)
+	})
+	return "http://qa.test/public/"
+}
+
+// failingStore 删除指定文件时返回错误
+type failingStore struct {
+	storage.Storage
+	fail map[string]bool
+}
+
+func (s failingStore) Delete(ctx context.Context, key string) error {
+	if s.fail[key] {
+		return errors.New("storage unavailable")
+	}
+	return s.Storage.Delete(ctx, key)
+}
+
+func TestRemoveUploadsRetry(t *testing.T) {
+	db := useFakeDB(t)
+	base := useUploadURL(t)
+	dir := t.TempDir()
+	local := storage.NewLocal(dir)
+	for _, key := range []string{"file/a.pdf", "file/b.pdf"} {
+		if err := os.MkdirAll(filepath.Join(dir, "file"), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, key), []byte(key), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldStore := storage.Store
+	storage.Store = failingStore{Storage: local, fail: map[string]bool{"file/b.pdf": true}}
+	t.Cleanup(func() { storage.Store = oldStore })
+
+	event := &model.OutboxEvent{Type: OutboxRemoveUploads,
+		Payload: `{"urls":["` + base + `file/a.pdf","` + base + `file/b.pdf","` + base + `file/missing.pdf"]}`}
+	if err := runOutboxEvent(event); err == nil {
+		t.Fatal("文件删除失败时事件应返回错误以便重试")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file/a.pdf")); !os.IsNotExist(err) {
+		t.Fatal("删除成功的文件仍然存在")
+	}
+	execs := db.Execs("DELETE FROM `uploads`")
+	if len(execs) != 1 || containsArg(execs[0].Args, "file/b.pdf") || !containsArg(execs[0].Args, "file/a.pdf") {
+		t.Fatalf("删除失败的文件不应删除登记记录: %v", execs)
+	}
+
+	// 重试时已删除与不存在的文件视为成功
+	storage.Store = local
+	if err := runOutboxEvent(event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "file/b.pdf")); !os.IsNotExist(err) {
+		t.Fatal("重试后文件仍然存在")
+	}
+}
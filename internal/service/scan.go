@@ -0,0 +1,128 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/scanner"
+	"QA-System/internal/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// QuarantineDir 检出威胁的文件的隔离目录, 不提供下载
+const QuarantineDir = "quarantine"
+
+var (
+	// ErrInfected 文件检出威胁
+	ErrInfected = errors.New("文件未通过安全扫描")
+	// ErrScanUnavailable 扫描服务不可用且未配置放行
+	ErrScanUnavailable = errors.New("文件安全扫描暂不可用")
+)
+
+// ScanUpload 扫描答卷人上传的文件, 扫描后将读取位置恢复到开头
+// 检出威胁时将文件隔离并登记后返回 ErrInfected, 结果写入 saved 以便登记
+func ScanUpload(r io.ReadSeeker, question *model.Question, uploader string, name string,
+	saved *SavedUpload) error {
+	if scanner.Engine == nil {
+		saved.ScanStatus = model.ScanSkipped
+		return nil
+	}
+	result, err := scanner.Engine.Scan(ctx, r)
+	if _, seekErr := r.Seek(0, io.SeekStart); seekErr != nil {
+		return seekErr
+	}
+	if err != nil {
+		if !scanner.FailOpen() {
+			zap.L().Error("Failed to scan upload", zap.Error(err))
+			return ErrScanUnavailable
+		}
+		zap.L().Warn("Failed to scan upload, accepted by fail-open", zap.Error(err))
+		saved.ScanStatus, saved.ScanResult = model.ScanFailed, truncate(err.Error(), 255)
+		return nil
+	}
+	if !result.Infected {
+		saved.ScanStatus = model.ScanClean
+		return nil
+	}
+
+	// 隔离文件并登记, 供管理员排查
+	key := QuarantineDir + "/" + name
+	zap.L().Warn("Infected upload quarantined", zap.String("key", key), zap.String("signature", result.Signature),
+		zap.Int("question_id", question.ID), zap.String("uploader", uploader))
+	if err := storage.Store.Put(ctx, key, r, saved.Size, saved.ContentType); err != nil {
+		return err
+	}
+	now := time.Now()
+	err = d.CreateUpload(ctx, &model.Upload{
+		Key:         key,
+		SurveyID:    question.SurveyID,
+		QuestionID:  question.ID,
+		Uploader:    uploader,
+		Size:        saved.Size,
+		ContentType: saved.ContentType,
+		Status:      model.UploadInfected,
+		ScanStatus:  model.ScanInfected,
+		ScanResult:  truncate(result.Signature, 255),
+		ScannedAt:   &now,
+	})
+	if err != nil {
+		return err
+	}
+	return ErrInfected
+}
+
+// GetUploadScanStatus 获取上传文件的扫描状态, 外部链接或升级前上传的文件为空
+func GetUploadScanStatus(urls []string) (map[string]string, error) {
+	status := make(map[string]string, len(urls))
+	keys := make(map[string]string, len(urls))
+	for _, url := range urls {
+		if key, ok := uploadKey(url); ok {
+			keys[key] = url
+			status[url] = ""
+		}
+	}
+	if len(keys) == 0 {
+		return status, nil
+	}
+	uploads, err := d.GetUploadsByKeys(ctx, uploadKeys(urls))
+	if err != nil {
+		return nil, err
+	}
+	for _, upload := range uploads {
+		status[keys[upload.Key]] = upload.ScanStatus
+	}
+	return status, nil
+}
+
+// GetUploadScanStatusByKey 根据存储 key 获取上传文件的扫描状态
+func GetUploadScanStatusByKey(key string) (string, error) {
+	uploads, err := d.GetUploadsByKeys(ctx, []string{key})
+	if err != nil || len(uploads) == 0 {
+		return "", err
+	}
+	return uploads[0].ScanStatus, nil
+}
+
+// ScanStatusLabel 扫描状态说明
+func ScanStatusLabel(status string) string {
+	switch status {
+	case model.ScanClean:
+		return "已通过"
+	case model.ScanInfected:
+		return "检出威胁"
+	case model.ScanFailed:
+		return "扫描失败"
+	default:
+		return "未扫描"
+	}
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return strings.TrimSpace(string(r[:n]))
+	}
+	return s
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/scanner"
+	"QA-System/internal/pkg/storage"
+)
+
+// stubScanner 返回固定的扫描结果, 并读完全部内容以检查读取位置是否恢复
+type stubScanner struct {
+	result *scanner.Result
+	err    error
+}
+
+func (s stubScanner) Scan(_ context.Context, r io.Reader) (*scanner.Result, error) {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	return s.result, s.err
+}
+
+// useScanner 替换扫描服务与存储后端, 测试结束后恢复, 返回存储根目录
+func useScanner(t *testing.T, engine scanner.Scanner) string {
+	dir := t.TempDir()
+	oldEngine, oldStore := scanner.Engine, storage.Store
+	scanner.Engine, storage.Store = engine, storage.NewLocal(dir)
+	t.Cleanup(func() { scanner.Engine, storage.Store = oldEngine, oldStore })
+	return dir
+}
+
+func scanTestUpload(t *testing.T, content string) (*strings.Reader, *model.Question, *SavedUpload) {
+	t.Helper()
+	question := &model.Question{ID: 7, SurveyID: 3}
+	saved := &SavedUpload{Size: int64(len(content)), ContentType: "application/pdf"}
+	return strings.NewReader(content), question, saved
+}
+
+func TestScanUploadClean(t *testing.T) {
+	db := useFakeDB(t)
+	useScanner(t, stubScanner{result: &scanner.Result{}})
+	r, question, saved := scanTestUpload(t, "report")
+	if err := ScanUpload(r, question, "202300000001", "a.pdf", saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.ScanStatus != model.ScanClean {
+		t.Fatalf("扫描状态为 %q", saved.ScanStatus)
+	}
+	if r.Len() != len("report") {
+		t.Fatal("扫描后读取位置未恢复到开头")
+	}
+	if execs := db.Execs("INSERT"); len(execs) != 0 {
+		t.Fatalf("未检出威胁时不应登记: %v", execs)
+	}
+}
+
+func TestScanUploadInfected(t *testing.T) {
+	db := useFakeDB(t)
+	dir := useScanner(t, stubScanner{result: &scanner.Result{Infected: true, Signature: "Eicar-Signature"}})
+	r, question, saved := scanTestUpload(t, "infected")
+	err := ScanUpload(r, question, testUploader, "a.pdf", saved)
+	if !errors.Is(err, ErrInfected) {
+		t.Fatalf("应返回 ErrInfected, 实际为 %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, QuarantineDir, "a.pdf"))
+	if err != nil || string(data) != "infected" {
+		t.Fatalf("隔离文件内容为 %q, %v", data, err)
+	}
+	execs := db.Execs("INSERT INTO `uploads`")
+	if len(execs) != 1 {
+		t.Fatalf("应登记 1 条上传记录, 实际为 %v", db.execs)
+	}
+	values := make([]any, 0, len(execs[0].Args))
+	for _, arg := range execs[0].Args {
+		values = append(values, arg.Value)
+	}
+	for _, want := range []any{QuarantineDir + "/a.pdf", testUploader, int64(model.UploadInfected),
+		model.ScanInfected, "Eicar-Signature"} {
+		if !containsValue(values, want) {
+			t.Errorf("登记的上传记录缺少 %v: %v", want, values)
+		}
+	}
+}
+
+func TestScanUploadUnavailable(t *testing.T) {
+	useFakeDB(t)
+	useScanner(t, stubScanner{err: errors.New("connection refused")})
+	r, question, saved := scanTestUpload(t, "report")
+	if err := ScanUpload(r, question, testUploader, "a.pdf", saved); !errors.Is(err, ErrScanUnavailable) {
+		t.Fatalf("应返回 ErrScanUnavailable, 实际为 %v", err)
+	}
+
+	global.Config.Set("scan.fail-open", true)
+	t.Cleanup(func() { global.Config.Set("scan.fail-open", false) })
+	r, question, saved = scanTestUpload(t, "report")
+	if err := ScanUpload(r, question, testUploader, "a.pdf", saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.ScanStatus != model.ScanFailed || saved.ScanResult != "connection refused" {
+		t.Fatalf("放行后的扫描结果为 %q %q", saved.ScanStatus, saved.ScanResult)
+	}
+}
+
+func containsValue(values []any, want any) bool {
+	for _, v := range values {
+		if v == want {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	"QA-System/internal/model"
+	"QA-System/internal/pkg/redis"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -12,16 +13,35 @@ import (
 func SetUserSession(c *gin.Context, user *model.User) error {
 	webSession := sessions.Default(c)
 	webSession.Options(sessions.Options{
-		MaxAge:   3600 * 24 * 7,
+		MaxAge:   int(sessionMaxAge.Seconds()),
 		Path:     "/",
 		HttpOnly: true,
 	})
+	mustEnroll, err := IsTwoFactorRequired(user)
+	if err != nil {
+		return err
+	}
+	// 已登录时沿用原会话, 否则登记新会话
+	sid, ok := webSession.Get("sid").(string)
+	if !ok || webSession.Get("id") != user.ID {
+		sid, err = createAdminSession(c, user.ID)
+		if err != nil {
+			return err
+		}
+	}
 	webSession.Set("id", user.ID)
+	webSession.Set("sid", sid)
+	webSession.Set("must_change_password", user.MustChangePassword)
+	webSession.Set("must_enroll_2fa", mustEnroll && !user.TOTPEnabled)
 	return webSession.Save()
 }
 
 // GetUserSession 获取用户会话
 func GetUserSession(c *gin.Context) (*model.User, error) {
+	// 使用 API 令牌访问时没有会话
+	if user, ok := getAPITokenUser(c); ok {
+		return user, nil
+	}
 	webSession := sessions.Default(c)
 	id := webSession.Get("id")
 	if id == nil {
@@ -31,8 +51,22 @@ func GetUserSession(c *gin.Context) (*model.User, error) {
 	if !ok {
 		return nil, errors.New("")
 	}
+	// 会话被注销后立即失效
+	sid, _ := webSession.Get("sid").(string)
+	adminSession, err := touchAdminSession(c, uid, sid)
+	if err != nil {
+		return nil, err
+	}
+	if adminSession == nil {
+		err = ClearUserSession(c)
+		if err != nil {
+			return nil, err
+		}
+		return nil, errors.New("")
+	}
 	user, err := GetAdminByID(uid)
-	if user == nil || err != nil {
+	// 账号被删除或停用后立即失效
+	if user == nil || err != nil || user.Disabled {
 		err = ClearUserSession(c)
 		if err != nil {
 			return nil, err
@@ -59,13 +93,42 @@ func UpdateUserSession(c *gin.Context) (*model.User, error) {
 func CheckUserSession(c *gin.Context) bool {
 	webSession := sessions.Default(c)
 	id := webSession.Get("id")
-	return id != nil
+	sid, ok := webSession.Get("sid").(string)
+	if id == nil || !ok {
+		return false
+	}
+	n, err := redis.RedisClient.Exists(ctx, adminSessionKey(sid)).Result()
+	return err == nil && n == 1
+}
+
+// CurrentSessionID 获取当前请求的会话ID
+func CurrentSessionID(c *gin.Context) string {
+	webSession := sessions.Default(c)
+	sid, _ := webSession.Get("sid").(string)
+	return sid
+}
+
+// CheckPasswordChangeRequired 检查用户是否需要先修改密码
+func CheckPasswordChangeRequired(c *gin.Context) bool {
+	webSession := sessions.Default(c)
+	mustChange, ok := webSession.Get("must_change_password").(bool)
+	return ok && mustChange
+}
+
+// CheckTwoFactorEnrollRequired 检查用户是否需要先开启两步验证
+func CheckTwoFactorEnrollRequired(c *gin.Context) bool {
+	webSession := sessions.Default(c)
+	mustEnroll, ok := webSession.Get("must_enroll_2fa").(bool)
+	return ok && mustEnroll
 }
 
 // ClearUserSession 清除用户会话
 func ClearUserSession(c *gin.Context) error {
 	webSession := sessions.Default(c)
 	webSession.Delete("id")
+	webSession.Delete("sid")
+	webSession.Delete("must_change_password")
+	webSession.Delete("must_enroll_2fa")
 	err := webSession.Save()
 	if err != nil {
 		return err
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/redis"
+	"QA-System/internal/pkg/utils"
+	redisPkg "github.com/redis/go-redis/v9"
+)
+
+// SettingRequireSuperAdmin2FA 是否要求超级管理员开启两步验证
+const SettingRequireSuperAdmin2FA = "require_2fa_super_admin"
+
+// recoveryCodeNum 每次生成的恢复码数量
+const recoveryCodeNum = 10
+
+// ErrTOTPNotPending 未开始绑定两步验证或绑定已过期
+var ErrTOTPNotPending = errors.New("两步验证绑定已过期")
+
+func totpPendingKey(uid int) string {
+	return "totp:pending:" + strconv.Itoa(uid)
+}
+
+func totpUsedKey(uid int, step int64) string {
+	return "totp:used:" + strconv.Itoa(uid) + ":" + strconv.FormatInt(step, 10)
+}
+
+// IsTwoFactorRequired 判断用户是否被策略要求开启两步验证
+func IsTwoFactorRequired(user *model.User) (bool, error) {
+	if user.AdminType != 2 {
+		return false, nil
+	}
+	return GetTwoFactorPolicy()
+}
+
+// GetTwoFactorPolicy 获取是否要求超级管理员开启两步验证
+func GetTwoFactorPolicy() (bool, error) {
+	value, err := d.GetSetting(ctx, SettingRequireSuperAdmin2FA)
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// SetTwoFactorPolicy 设置是否要求超级管理员开启两步验证
+func SetTwoFactorPolicy(required bool) error {
+	return d.SetSetting(ctx, SettingRequireSuperAdmin2FA, strconv.FormatBool(required))
+}
+
+// BeginTOTPEnrollment 生成待绑定的密钥, 需在十分钟内验证后才会生效
+func BeginTOTPEnrollment(user *model.User) (string, string, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := redis.RedisClient.Set(ctx, totpPendingKey(user.ID), secret, 10*time.Minute).Err(); err != nil {
+		return "", "", err
+	}
+	issuer := global.Config.GetString("totp.issuer")
+	if issuer == "" {
+		issuer = "QA-System"
+	}
+	return secret, utils.TOTPURI(issuer, user.Username, secret), nil
+}
+
+// EnableTOTP 验证待绑定的密钥并开启两步验证, 返回恢复码明文
+func EnableTOTP(user *model.User, code string) ([]string, bool, error) {
+	secret, err := redis.RedisClient.Get(ctx, totpPendingKey(user.ID)).Result()
+	if errors.Is(err, redisPkg.Nil) {
+		return nil, false, ErrTOTPNotPending
+	} else if err != nil {
+		return nil, false, err
+	}
+	step, ok := utils.VerifyTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, false, nil
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := d.EnableUserTOTP(ctx, user.ID, secret, hashes); err != nil {
+		return nil, false, err
+	}
+	if err := redis.RedisClient.Del(ctx, totpPendingKey(user.ID)).Err(); err != nil {
+		return nil, false, err
+	}
+	if _, err := markTOTPStepUsed(user.ID, step); err != nil {
+		return nil, false, err
+	}
+	return codes, true, nil
+}
+
+// DisableTOTP 关闭两步验证
+func DisableTOTP(uid int) error {
+	return d.DisableUserTOTP(ctx, uid)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码
+func RegenerateRecoveryCodes(uid int) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.ReplaceRecoveryCodes(ctx, uid, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// CountRecoveryCodes 统计剩余可用的恢复码数量
+func CountRecoveryCodes(uid int) (int64, error) {
+	return d.CountRecoveryCodes(ctx, uid)
+}
+
+// VerifySecondFactor 校验验证码或恢复码, 同一验证码只能使用一次
+func VerifySecondFactor(user *model.User, code string, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return d.UseRecoveryCode(ctx, user.ID, hashSecret(normalizeRecoveryCode(recoveryCode)))
+	}
+	step, ok := utils.VerifyTOTP(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return markTOTPStepUsed(user.ID, step)
+}
+
+// markTOTPStepUsed 记录已使用的时间步, 已使用过时返回 false
+func markTOTPStepUsed(uid int, step int64) (bool, error) {
+	return redis.RedisClient.SetNX(ctx, totpUsedKey(uid, step), 1, 2*time.Minute).Result()
+}
+
+// newRecoveryCodes 生成恢复码及其摘要
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeNum)
+	hashes := make([]string, 0, recoveryCodeNum)
+	for i := 0; i < recoveryCodeNum; i++ {
+		raw, err := utils.RandomPassword(10)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(raw[:5] + "-" + raw[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashSecret(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode 忽略恢复码的大小写、空格与连字符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/storage"
+	"github.com/dustin/go-humanize"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// 上传文件的默认大小限制, 问题设置的限制不能超过该值
+const (
+	MaxImageSize int64 = 10 * humanize.MiByte
+	MaxFileSize  int64 = 50 * humanize.MiByte
+)
+
+var (
+	// ErrFileType 文件类型不被允许
+	ErrFileType = errors.New("文件类型不允许上传")
+	// ErrUploadQuota 答卷人上传的总大小超出限制
+	ErrUploadQuota = errors.New("上传文件总大小超出限制")
+	// ErrFileCount 答案中的文件数量超出限制
+	ErrFileCount = errors.New("文件数量超出限制")
+	// ErrUploadMismatch 答案中的文件不是为该问题上传的
+	ErrUploadMismatch = errors.New("文件不属于该问题")
+	// ErrUploadToken 答卷人提供的上传凭证格式错误
+	ErrUploadToken = errors.New("上传凭证无效")
+)
+
+// uploadTokenLength 上传凭证的长度, 为 16 字节随机数的十六进制
+const uploadTokenLength = 32
+
+// NewUploadToken 生成答卷人的上传凭证
+// 无需统一验证的问卷以上传凭证区分答卷人, 首次上传时签发, 之后的上传与提交需携带同一凭证
+func NewUploadToken() (string, error) {
+	b := make([]byte, uploadTokenLength/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// UploadTokenUploader 将上传凭证转换为上传者标识, 只登记凭证的摘要; 凭证格式错误时返回 ErrUploadToken
+func UploadTokenUploader(token string) (string, error) {
+	if len(token) != uploadTokenLength {
+		return "", ErrUploadToken
+	}
+	if _, err := hex.DecodeString(token); err != nil {
+		return "", ErrUploadToken
+	}
+	return "token:" + hashSecret(token)[:32], nil
+}
+
+// deniedFileTypes 浏览器会执行其中脚本的类型, 由本站地址下载时存在 XSS 风险, 任何问题都不允许上传
+var deniedFileTypes = []string{
+	"text/html", "application/xhtml+xml", "image/svg+xml",
+	"text/javascript", "application/javascript", "application/xml", "text/xml",
+}
+
+// DetectFileType 根据文件内容检测类型, 检测后将读取位置恢复到开头
+func DetectFileType(r io.ReadSeeker) (*mimetype.MIME, error) {
+	mtype, err := mimetype.DetectReader(r)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return mtype, nil
+}
+
+// CheckFileType 检查文件类型是否允许上传到该问题, 检测到的类型或其父类型匹配即可
+func CheckFileType(question *model.Question, mtype *mimetype.MIME) error {
+	for m := mtype; m != nil; m = m.Parent() {
+		for _, denied := range deniedFileTypes {
+			if m.Is(denied) {
+				return ErrFileType
+			}
+		}
+	}
+	if strings.TrimSpace(question.FileTypes) == "" {
+		return nil
+	}
+	for m := mtype; m != nil; m = m.Parent() {
+		base, _, _ := strings.Cut(m.String(), ";")
+		for _, allowed := range strings.Split(question.FileTypes, ",") {
+			allowed = strings.ToLower(strings.TrimSpace(allowed))
+			if allowed == "" {
+				continue
+			}
+			if prefix, ok := strings.CutSuffix(allowed, "/*"); ok {
+				if strings.HasPrefix(base, prefix+"/") {
+					return nil
+				}
+			} else if m.Is(allowed) {
+				return nil
+			}
+		}
+	}
+	return ErrFileType
+}
+
+// GetUploadSizeLimit 获取问题的单个文件大小限制, 不超过默认限制
+func GetUploadSizeLimit(question *model.Question, defaultLimit int64) int64 {
+	if question.MaxFileSize > 0 && question.MaxFileSize < defaultLimit {
+		return question.MaxFileSize
+	}
+	return defaultLimit
+}
+
+// CheckUploadQuota 检查答卷人再上传 size 字节后是否超出该问题的配额
+func CheckUploadQuota(question *model.Question, uploader string, size int64) error {
+	if question.UploadQuota <= 0 {
+		return nil
+	}
+	used, err := d.SumUploadSize(ctx, question.ID, uploader)
+	if err != nil {
+		return err
+	}
+	if used+size > question.UploadQuota {
+		return ErrUploadQuota
+	}
+	return nil
+}
+
+// RecordUpload 登记上传的文件, 管理员上传的问卷图片 surveyID 与 questionID 为0
+func RecordUpload(surveyID int, questionID int, uploader string, saved *SavedUpload) error {
+	key, ok := uploadKey(saved.URL)
+	if !ok {
+		return errors.New("上传文件地址无效")
+	}
+	upload := &model.Upload{
+		Key:         key,
+		SurveyID:    surveyID,
+		QuestionID:  questionID,
+		Uploader:    uploader,
+		Size:        saved.Size,
+		ContentType: saved.ContentType,
+		Status:      model.UploadPending,
+		ScanStatus:  saved.ScanStatus,
+		ScanResult:  saved.ScanResult,
+	}
+	if saved.ScanStatus == model.ScanClean || saved.ScanStatus == model.ScanFailed {
+		now := time.Now()
+		upload.ScannedAt = &now
+	}
+	return d.CreateUpload(ctx, upload)
+}
+
+// uploadKeys 将访问地址转换为存储 key, 忽略外部链接
+func uploadKeys(urls []string) []string {
+	keys := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if key, ok := uploadKey(url); ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// attachUploads 将问卷或答卷引用的文件标记为已关联
+func attachUploads(tx *dao.Dao, urls []string, surveyID int) error {
+	return tx.AttachUploads(ctx, uploadKeys(urls), surveyID)
+}
+
+// claimUploads 将答卷引用的文件关联到问卷, 文件须由 uploader 上传且仍待关联, 否则不关联任何文件
+func claimUploads(keys []string, surveyID int, uploader string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return d.Transaction(ctx, func(tx *dao.Dao) error {
+		claimed, err := tx.ClaimUploads(ctx, keys, surveyID, uploader)
+		if err != nil {
+			return err
+		}
+		// 部分文件已被其他答卷关联或不属于该上传者时回滚
+		if claimed != int64(len(keys)) {
+			return ErrUploadMismatch
+		}
+		return nil
+	})
+}
+
+// CheckAnswerUploads 检查图片题与文件题答案的文件数量, 以及文件是否由 uploader 为该问题上传
+func CheckAnswerUploads(question *model.Question, answer string, uploader string) error {
+	if answer == "" {
+		return nil
+	}
+	urls := strings.Split(answer, "┋")
+	if question.MaxFileCount > 0 && uint(len(urls)) > question.MaxFileCount {
+		return ErrFileCount
+	}
+	keys := make([]string, 0, len(urls))
+	seen := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		key, ok := uploadKey(url)
+		if !ok || seen[key] {
+			return ErrUploadMismatch
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	uploads, err := d.GetUploadsByKeys(ctx, keys)
+	if err != nil {
+		return err
+	}
+	// 已关联的文件属于其他答卷, 其他答卷人上传的文件也不能引用
+	pending := make(map[string]bool, len(uploads))
+	for _, upload := range uploads {
+		pending[upload.Key] = upload.QuestionID == question.ID && upload.Status == model.UploadPending &&
+			upload.Uploader == uploader
+	}
+	for _, key := range keys {
+		if !pending[key] {
+			return ErrUploadMismatch
+		}
+	}
+	return nil
+}
+
+// GetUploadPendingTTL 获取上传文件等待关联的时长, 超过后由定时任务清理
+func GetUploadPendingTTL() time.Duration {
+	hours := 24
+	if global.Config.IsSet("upload.pending-ttl") {
+		hours = global.Config.GetInt("upload.pending-ttl")
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// CollectPendingUploads 清理上传超过 minAge 仍未关联的文件, dryRun 为 true 时只返回不删除
+func CollectPendingUploads(minAge time.Duration, dryRun bool) ([]model.Upload, error) {
+	uploads, err := d.GetPendingUploads(ctx, time.Now().Add(-minAge))
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return uploads, nil
+	}
+	for i, upload := range uploads {
+		keys := []string{upload.Key}
+		if strings.HasPrefix(upload.Key, StaticDir+"/") {
+			keys = append(keys, thumbKey(upload.Key))
+		}
+		for _, key := range keys {
+			if err := storage.Store.Delete(ctx, key); err != nil {
+				return uploads[:i], err
+			}
+		}
+		if err := d.DeleteUploadsByKeys(ctx, []string{upload.Key}); err != nil {
+			return uploads[:i], err
+		}
+	}
+	return uploads, nil
+}
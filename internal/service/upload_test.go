@@ -0,0 +1,90 @@
+package service
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// testUploader 无需统一验证的问卷中答卷人的上传者标识
+const testUploader = "token:0123456789abcdef0123456789abcdef"
+
+func TestClaimUploads(t *testing.T) {
+	db := useFakeDB(t)
+	db.rowsAffected = func(string, []driver.NamedValue) int64 { return 2 }
+	keys := []string{"file/a.pdf", "file/b.pdf"}
+	if err := claimUploads(keys, 3, testUploader); err != nil {
+		t.Fatal(err)
+	}
+	execs := db.Execs("UPDATE `uploads`")
+	if len(execs) != 1 {
+		t.Fatalf("应执行 1 条更新, 实际为 %v", db.execs)
+	}
+	if !containsArg(execs[0].Args, testUploader) {
+		t.Fatalf("关联时未按上传者过滤: %s %v", execs[0].Query, execs[0].Args)
+	}
+	if db.commits != 1 || db.rollbacks != 0 {
+		t.Fatalf("事务提交 %d 次, 回滚 %d 次", db.commits, db.rollbacks)
+	}
+}
+
+func TestClaimUploadsPartial(t *testing.T) {
+	db := useFakeDB(t)
+	// 其中一个文件已被并发提交的答卷关联, 或由其他答卷人上传
+	db.rowsAffected = func(string, []driver.NamedValue) int64 { return 1 }
+	err := claimUploads([]string{"file/a.pdf", "file/b.pdf"}, 3, testUploader)
+	if !errors.Is(err, ErrUploadMismatch) {
+		t.Fatalf("应返回 ErrUploadMismatch, 实际为 %v", err)
+	}
+	if db.commits != 0 || db.rollbacks != 1 {
+		t.Fatalf("部分关联时应回滚, 提交 %d 次, 回滚 %d 次", db.commits, db.rollbacks)
+	}
+}
+
+func TestClaimUploadsEmpty(t *testing.T) {
+	db := useFakeDB(t)
+	if err := claimUploads(nil, 3, testUploader); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.execs) != 0 {
+		t.Fatalf("没有文件时不应写入: %v", db.execs)
+	}
+}
+
+func TestUploadToken(t *testing.T) {
+	token, err := NewUploadToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewUploadToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploader, err := UploadTokenUploader(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := UploadTokenUploader(token); again != uploader {
+		t.Fatal("同一凭证的上传者标识应相同")
+	}
+	if otherUploader, _ := UploadTokenUploader(other); otherUploader == uploader {
+		t.Fatal("不同凭证的上传者标识不应相同")
+	}
+	if len(uploader) > 64 || uploader[len("token:"):] == token {
+		t.Fatalf("上传者标识应为凭证的摘要且不超过 64 字符: %s", uploader)
+	}
+	for _, invalid := range []string{"", "abc", token + "0", "zz" + token[2:]} {
+		if _, err := UploadTokenUploader(invalid); !errors.Is(err, ErrUploadToken) {
+			t.Errorf("UploadTokenUploader(%q) 应返回 ErrUploadToken, 实际为 %v", invalid, err)
+		}
+	}
+}
+
+func containsArg(args []driver.NamedValue, want any) bool {
+	for _, arg := range args {
+		if arg.Value == want {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,7 @@
 package service
 
 import (
-	"bytes"
-	"image"
-	_ "image/gif" // 注册解码器
-	"image/jpeg"
-	_ "image/png" // 注册解码器
-	"io"
-	"os"
-	"path/filepath"
+	"strings"
 	"time"
 
 	"QA-System/internal/dao"
@@ -17,9 +10,6 @@ import (
 	"github.com/zjutjh/WeJH-SDK/oauth"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
-	_ "golang.org/x/image/bmp" // 注册解码器
-	_ "golang.org/x/image/tiff"
-	_ "golang.org/x/image/webp"
 )
 
 // GetSurveyByID 根据ID获取问卷
@@ -49,33 +39,49 @@ func GetQuestionByID(id int) (*model.Question, error) {
 	return question, err
 }
 
-// SubmitSurvey 提交问卷
-func SubmitSurvey(sid int, data []dao.QuestionsList, t string) error {
+// SubmitSurvey 提交问卷, uploader 为提交者上传文件时记录的上传者
+func SubmitSurvey(sid int, data []dao.QuestionsList, t string, stuId string, uploader string) (
+	*dao.AnswerSheet, error) {
 	var answerSheet dao.AnswerSheet
 	answerSheet.SurveyID = sid
 	answerSheet.Time = t
+	answerSheet.StudentID = stuId
 	answerSheet.Unique = true
 	answerSheet.AnswerID = primitive.NewObjectID()
 	qids := make([]int, 0)
+	urls := make([]string, 0)
 	for _, q := range data {
 		var answer dao.Answer
 		question, err := d.GetQuestionByID(ctx, q.QuestionID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if question.QuestionType == 3 && question.Unique {
 			qids = append(qids, q.QuestionID)
 		}
+		if (question.QuestionType == 5 || question.QuestionType == 6) && q.Answer != "" {
+			urls = append(urls, strings.Split(q.Answer, "┋")...)
+		}
 		answer.QuestionID = q.QuestionID
 		answer.Content = q.Answer
 		answerSheet.Answers = append(answerSheet.Answers, answer)
 	}
+	// 先关联文件再保存答卷, 避免已保存的答卷引用的文件被当作未关联文件清理
+	if err := claimUploads(uploadKeys(urls), sid, uploader); err != nil {
+		return nil, err
+	}
 	err := d.SaveAnswerSheet(ctx, answerSheet, qids)
 	if err != nil {
-		return err
+		// 保存失败时恢复为待关联, 答卷人可以使用同样的文件重新提交
+		if detachErr := d.DetachUploads(ctx, uploadKeys(urls)); detachErr != nil {
+			zap.L().Error("Failed to detach uploads", zap.Int("survey", sid), zap.Error(detachErr))
+		}
+		return nil, err
+	}
+	if err := d.IncreaseSurveyNum(ctx, sid); err != nil {
+		return nil, err
 	}
-	err = d.IncreaseSurveyNum(ctx, sid)
-	return err
+	return &answerSheet, nil
 }
 
 // CreateOauthRecord 创建一条统一验证记录
@@ -92,46 +98,6 @@ func CreateOauthRecord(userInfo oauth.UserInfo, t time.Time, sid int) error {
 	return d.SaveRecordSheet(ctx, sheet, sid)
 }
 
-// ConvertToJPEG 将图片转换为 JPEG 格式
-func ConvertToJPEG(reader io.Reader) (io.Reader, error) {
-	img, _, err := image.Decode(reader)
-	if err != nil {
-		return nil, err
-	}
-
-	var buf bytes.Buffer
-	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100})
-	if err != nil {
-		return nil, err
-	}
-	return bytes.NewReader(buf.Bytes()), nil
-}
-
-// SaveFile 保存文件
-func SaveFile(reader io.Reader, path string) error {
-	dst := filepath.Clean(path)
-	err := os.MkdirAll(filepath.Dir(dst), 0750)
-	if err != nil {
-		return err
-	}
-
-	// 创建文件
-	outFile, err := os.Create(dst)
-	if err != nil {
-		return err
-	}
-	defer func(outFile *os.File) {
-		err := outFile.Close()
-		if err != nil {
-			zap.L().Error("Failed to close file", zap.Error(err))
-		}
-	}(outFile)
-
-	// 写入文件
-	_, err = io.Copy(outFile, reader)
-	return err
-}
-
 // UpdateVoteLimit 更新投票限制
 func UpdateVoteLimit(c *gin.Context, stuId string, surveyID int, isNew bool, durationType string) error {
 	if isNew {
@@ -24,7 +24,7 @@ func FetchHandleOfPost(form map[string]any, webUrl string) (*UserCenterResponse,
 		SetHeader("Content-Type", "application/json").
 		SetBody(form).
 		SetResult(&rc).
-		Post(userCenterApi.UserCenterHost + webUrl)
+		Post(userCenterApi.UserCenterHost() + webUrl)
 
 	// 检查请求错误
 	if err != nil || resp.IsError() {
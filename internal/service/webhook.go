@@ -0,0 +1,462 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"QA-System/internal/dao"
+	global "QA-System/internal/global/config"
+	"QA-System/internal/model"
+	"QA-System/internal/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// Webhook 事件类型
+const (
+	EventSheetSubmitted  = "sheet.submitted"  // 提交答卷
+	EventSheetDeleted    = "sheet.deleted"    // 删除答卷
+	EventSurveyPublished = "survey.published" // 发布问卷
+	EventSurveyClosed    = "survey.closed"    // 截止问卷
+	EventQuotaReached    = "quota.reached"    // 答卷数达到设定值
+)
+
+// WebhookEvents 可订阅的全部事件
+var WebhookEvents = []string{
+	EventSheetSubmitted, EventSheetDeleted, EventSurveyPublished, EventSurveyClosed, EventQuotaReached,
+}
+
+// webhookSecretPrefix 签名密钥前缀, 便于识别泄露的密钥
+const webhookSecretPrefix = "whsec_"
+
+// webhookResponseLimit 投递记录保存的响应内容长度
+const webhookResponseLimit = 1024
+
+var (
+	// ErrWebhookURL 推送地址无效
+	ErrWebhookURL = errors.New("推送地址必须为 http 或 https 地址")
+	// ErrWebhookEvent 订阅了未知的事件
+	ErrWebhookEvent = errors.New("未知的 Webhook 事件")
+	// ErrWebhookAddress 推送地址解析到内网地址
+	ErrWebhookAddress = errors.New("不允许推送到内网地址")
+	// ErrWebhookGone Webhook 已删除或停用, 投递不再重试
+	ErrWebhookGone = errors.New("Webhook 已删除或停用")
+	// ErrWebhookRejected 接收方拒绝请求, 重试也不会成功
+	ErrWebhookRejected = errors.New("接收方拒绝了推送")
+)
+
+// webhookEnvelope 推送的请求体
+type webhookEnvelope struct {
+	Event     string    `json:"event"`
+	SurveyID  int       `json:"survey_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// SheetAnswer 推送中的单个答案
+type SheetAnswer struct {
+	QuestionID   int    `json:"question_id"`
+	SerialNum    int    `json:"serial_num"`
+	Subject      string `json:"subject"`
+	QuestionType int    `json:"question_type"`
+	Answer       string `json:"answer"`
+}
+
+var (
+	webhookClientOnce sync.Once
+	webhookClient     *http.Client
+)
+
+// getWebhookClient 获取推送使用的 HTTP 客户端, 不跟随重定向, 默认拒绝连接内网地址
+func getWebhookClient() *http.Client {
+	webhookClientOnce.Do(func() {
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		if !global.Config.GetBool("webhook.allow-private") {
+			dialer.Control = checkWebhookAddress
+		}
+		webhookClient = &http.Client{
+			Timeout:   GetWebhookTimeout(),
+			Transport: &http.Transport{DialContext: dialer.DialContext, MaxIdleConnsPerHost: 4},
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	})
+	return webhookClient
+}
+
+// checkWebhookAddress 在建立连接时检查解析后的地址, 避免通过域名解析绕过检查
+func checkWebhookAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return ErrWebhookAddress
+	}
+	return nil
+}
+
+// GetWebhookTimeout 获取单次推送的超时时间
+func GetWebhookTimeout() time.Duration {
+	seconds := 10
+	if global.Config.IsSet("webhook.timeout") {
+		seconds = global.Config.GetInt("webhook.timeout")
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// GetWebhookMaxRetry 获取推送失败后的最大重试次数
+func GetWebhookMaxRetry() int {
+	if global.Config.IsSet("webhook.max-retry") {
+		return global.Config.GetInt("webhook.max-retry")
+	}
+	return 8
+}
+
+// CheckWebhookURL 检查推送地址格式
+func CheckWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrWebhookURL
+	}
+	return nil
+}
+
+// CheckWebhookEvents 检查订阅的事件是否都受支持
+func CheckWebhookEvents(events []string) error {
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			return fmt.Errorf("%w %s", ErrWebhookEvent, event)
+		}
+	}
+	return nil
+}
+
+// CreateWebhook 创建 Webhook, 返回的签名密钥只在此时可见
+func CreateWebhook(sid int, uid int, rawURL string, events []string, quota int) (*model.Webhook, string, error) {
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	webhook := &model.Webhook{
+		SurveyID: sid,
+		UserID:   uid,
+		URL:      rawURL,
+		Secret:   secret,
+		Events:   strings.Join(events, ","),
+		Quota:    quota,
+		Enabled:  true,
+	}
+	if err := d.CreateWebhook(ctx, webhook); err != nil {
+		return nil, "", err
+	}
+	return webhook, secret, nil
+}
+
+// newWebhookSecret 生成签名密钥
+func newWebhookSecret() (string, error) {
+	raw, err := utils.RandomPassword(40)
+	if err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + raw, nil
+}
+
+// GetWebhooks 获取问卷的全部 Webhook
+func GetWebhooks(sid int) ([]model.Webhook, error) {
+	return d.GetWebhooksBySurveyID(ctx, sid)
+}
+
+// GetWebhookByID 根据id获取 Webhook
+func GetWebhookByID(id int) (*model.Webhook, error) {
+	return d.GetWebhookByID(ctx, id)
+}
+
+// UpdateWebhook 更新 Webhook, rotateSecret 为 true 时重新生成并返回签名密钥
+// 修改答卷数设定值后会重新推送 quota.reached
+func UpdateWebhook(webhook *model.Webhook, rawURL string, events []string, quota int, enabled bool,
+	rotateSecret bool) (string, error) {
+	var secret string
+	if rotateSecret {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return "", err
+		}
+		webhook.Secret = secret
+	}
+	if quota != webhook.Quota {
+		webhook.QuotaNotified = false
+	}
+	webhook.URL = rawURL
+	webhook.Events = strings.Join(events, ",")
+	webhook.Quota = quota
+	webhook.Enabled = enabled
+	return secret, d.UpdateWebhook(ctx, webhook)
+}
+
+// DeleteWebhook 删除 Webhook 及其投递记录
+func DeleteWebhook(id int) error {
+	return d.DeleteWebhook(ctx, id)
+}
+
+// subscribed 判断 Webhook 是否订阅了该事件
+func subscribed(webhook *model.Webhook, event string) bool {
+	return webhook.Enabled && slices.Contains(strings.Split(webhook.Events, ","), event)
+}
+
+// newWebhookDelivery 生成一条待投递记录
+func newWebhookDelivery(webhook *model.Webhook, event string, data any) (model.WebhookDelivery, error) {
+	payload, err := json.Marshal(webhookEnvelope{
+		Event:     event,
+		SurveyID:  webhook.SurveyID,
+		CreatedAt: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		return model.WebhookDelivery{}, err
+	}
+	return model.WebhookDelivery{
+		WebhookID: webhook.ID,
+		SurveyID:  webhook.SurveyID,
+		Event:     event,
+		Payload:   string(payload),
+		Status:    model.DeliveryPending,
+	}, nil
+}
+
+// CreateWebhookDeliveries 为订阅了事件的 Webhook 创建投递记录, 由调用方投递到任务队列
+func CreateWebhookDeliveries(sid int, event string, data any) ([]model.WebhookDelivery, error) {
+	webhooks, err := d.GetWebhooksBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]model.WebhookDelivery, 0)
+	for i := range webhooks {
+		if !subscribed(&webhooks[i], event) {
+			continue
+		}
+		delivery, err := newWebhookDelivery(&webhooks[i], event, data)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := d.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// CreateQuotaDeliveries 答卷数达到设定值时创建 quota.reached 投递记录, 每个 Webhook 只推送一次
+func CreateQuotaDeliveries(sid int) ([]model.WebhookDelivery, error) {
+	webhooks, err := d.GetWebhooksBySurveyID(ctx, sid)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]model.WebhookDelivery, 0)
+	var survey *model.Survey
+	for i := range webhooks {
+		webhook := &webhooks[i]
+		if webhook.Quota <= 0 || webhook.QuotaNotified || !subscribed(webhook, EventQuotaReached) {
+			continue
+		}
+		if survey == nil {
+			if survey, err = d.GetSurveyByID(ctx, sid); err != nil {
+				return nil, err
+			}
+		}
+		if survey.Num < webhook.Quota {
+			continue
+		}
+		ok, err := d.MarkWebhookQuotaNotified(ctx, webhook.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		delivery, err := newWebhookDelivery(webhook, EventQuotaReached, map[string]any{
+			"title": survey.Title,
+			"num":   survey.Num,
+			"quota": webhook.Quota,
+		})
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := d.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// sheetAnswers 为答卷中的答案附带题目信息
+func sheetAnswers(sheet *dao.AnswerSheet) ([]SheetAnswer, error) {
+	questions, err := d.GetQuestionsBySurveyID(ctx, sheet.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	questionMap := make(map[int]model.Question, len(questions))
+	for _, question := range questions {
+		questionMap[question.ID] = question
+	}
+	answers := make([]SheetAnswer, 0, len(sheet.Answers))
+	for _, answer := range sheet.Answers {
+		question := questionMap[answer.QuestionID]
+		answers = append(answers, SheetAnswer{
+			QuestionID:   answer.QuestionID,
+			SerialNum:    question.SerialNum,
+			Subject:      question.Subject,
+			QuestionType: question.QuestionType,
+			Answer:       answer.Content,
+		})
+	}
+	return answers, nil
+}
+
+// SheetEventData 生成答卷事件的推送数据, 答案附带题目以便接收方直接使用
+func SheetEventData(sheet *dao.AnswerSheet) (any, error) {
+	answers, err := sheetAnswers(sheet)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"answer_id":  sheet.AnswerID.Hex(),
+		"time":       sheet.Time,
+		"student_id": sheet.StudentID,
+		"answers":    answers,
+	}, nil
+}
+
+// SurveyEventData 生成问卷事件的推送数据
+func SurveyEventData(survey *model.Survey, status int) any {
+	return map[string]any{
+		"title":  survey.Title,
+		"status": status,
+		"num":    survey.Num,
+	}
+}
+
+// GetWebhookDeliveries 分页获取 Webhook 的投递记录
+func GetWebhookDeliveries(webhookID int, pageNum, pageSize int) ([]model.WebhookDelivery, *int64, error) {
+	return d.GetWebhookDeliveries(ctx, webhookID, pageNum, pageSize)
+}
+
+// GetWebhookDeliveryByID 根据id获取投递记录
+func GetWebhookDeliveryByID(id int) (*model.WebhookDelivery, error) {
+	return d.GetWebhookDeliveryByID(ctx, id)
+}
+
+// RedeliverWebhook 以原请求体创建一条新的投递记录, 原记录保留
+func RedeliverWebhook(delivery *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	deliveries := []model.WebhookDelivery{{
+		WebhookID: delivery.WebhookID,
+		SurveyID:  delivery.SurveyID,
+		Event:     delivery.Event,
+		Payload:   delivery.Payload,
+		Status:    model.DeliveryPending,
+	}}
+	if err := d.CreateWebhookDeliveries(ctx, deliveries); err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// SignWebhook 计算签名, 签名内容为 "时间戳.请求体"
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// DeliverWebhook 投递一次并记录结果, 返回错误时由任务队列重试
+// Webhook 已删除或停用以及接收方返回 4xx 时返回的错误包含 ErrWebhookGone 或 ErrWebhookRejected, 不应重试
+func DeliverWebhook(id int) error {
+	delivery, err := d.GetWebhookDeliveryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	webhook, err := d.GetWebhookByID(ctx, delivery.WebhookID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err != nil || !webhook.Enabled {
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = ErrWebhookGone.Error()
+		if err := d.UpdateWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+		return ErrWebhookGone
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.DeliveredAt = &now
+	delivery.ResponseCode = 0
+	delivery.ResponseBody = ""
+	delivery.Error = ""
+	deliverErr := postWebhook(webhook, delivery)
+	if deliverErr != nil {
+		delivery.Status = model.DeliveryFailed
+		delivery.Error = truncate(deliverErr.Error(), 512)
+	} else {
+		delivery.Status = model.DeliverySuccess
+	}
+	if err := d.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		return err
+	}
+	return deliverErr
+}
+
+// postWebhook 发送请求并将响应写入投递记录
+func postWebhook(webhook *model.Webhook, delivery *model.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "QA-System-Webhook")
+	req.Header.Set("X-QA-Event", delivery.Event)
+	req.Header.Set("X-QA-Delivery", strconv.Itoa(delivery.ID))
+	req.Header.Set("X-QA-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-QA-Signature", SignWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := getWebhookClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	content, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	delivery.ResponseCode = resp.StatusCode
+	delivery.ResponseBody = truncate(string(content), webhookResponseLimit)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	// 超时与限流可能在重试后恢复, 其余 4xx 与重定向不再重试
+	if resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests &&
+		resp.StatusCode < 500 {
+		return fmt.Errorf("%w: HTTP %d", ErrWebhookRejected, resp.StatusCode)
+	}
+	return fmt.Errorf("HTTP %d", resp.StatusCode)
+}